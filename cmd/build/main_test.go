@@ -1,11 +1,27 @@
 package main_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 )
 
@@ -28,6 +44,36 @@ var _ = Describe("Main", func() {
 		Entry("When the image.json and metadata.json files exist", 0, func() []string {
 			return []string{"./testdata/ccsrc/validimage", "./testdata/ccmetadata/validmetadata", tempDir}
 		}),
+		Entry("When the image.json file contains valid resources", 0, func() []string {
+			return []string{"./testdata/ccsrc/withresources", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains invalid resources", 1, func() []string {
+			return []string{"./testdata/ccsrc/invalidresources", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains a valid image pull secret", 0, func() []string {
+			return []string{"./testdata/ccsrc/withimagepullsecret", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains an invalid image pull secret", 1, func() []string {
+			return []string{"./testdata/ccsrc/invalidimagepullsecret", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains an invalid digest", 1, func() []string {
+			return []string{"./testdata/ccsrc/invaliddigest", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains a tag and image tag resolution is not enabled", 1, func() []string {
+			return []string{"./testdata/ccsrc/withimagetag", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains valid probes", 0, func() []string {
+			return []string{"./testdata/ccsrc/withprobes", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains invalid probes", 1, func() []string {
+			return []string{"./testdata/ccsrc/invalidprobes", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file contains valid chaincode configuration", 0, func() []string {
+			return []string{"./testdata/ccsrc/withconfig", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
+		Entry("When the image.json file overrides a reserved environment variable", 1, func() []string {
+			return []string{"./testdata/ccsrc/reservedenv", "./testdata/ccmetadata/validmetadata", tempDir}
+		}),
 		Entry("When the image.json file does not exist", 1, func() []string {
 			return []string{"CHAINCODE_SOURCE_DIR", "./testdata/ccmetadata/validmetadata", "BUILD_OUTPUT_DIR"}
 		}),
@@ -59,6 +105,27 @@ var _ = Describe("Main", func() {
 		}),
 	)
 
+	DescribeTable("Running the build command with an image policy produces the correct result",
+		func(env []string, expectedErrorCode int, expectedMessage string) {
+			args := []string{"./testdata/ccsrc/validimage", "./testdata/ccmetadata/validmetadata", tempDir}
+			command := exec.Command(buildCmdPath, args...)
+			command.Env = append(os.Environ(), env...)
+
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(expectedErrorCode))
+
+			if expectedMessage != "" {
+				Eventually(session.Err).Should(gbytes.Say(expectedMessage))
+			}
+		},
+		Entry("When the chaincode image is allowed", []string{"FABRIC_K8S_BUILDER_IMAGE_ALLOWLIST=docker.io,ghcr.io/hyperledger/*"}, 0, ""),
+		Entry("When the chaincode image is not allowed", []string{"FABRIC_K8S_BUILDER_IMAGE_ALLOWLIST=docker.io"}, 1, `build \[\d+\]: Error building chaincode: chaincode image ghcr\.io/hyperledger/asset-transfer-basic is not allowed by any image allowlist rule`),
+		Entry("When the chaincode image is denied", []string{"FABRIC_K8S_BUILDER_IMAGE_DENYLIST=regex:ghcr\\.io/.*"}, 1, `build \[\d+\]: Error building chaincode: chaincode image ghcr\.io/hyperledger/asset-transfer-basic is not allowed by image denylist rule 'regex:ghcr\\\.io/\.\*'`),
+		Entry("When an image policy rule is invalid", []string{"FABRIC_K8S_BUILDER_IMAGE_ALLOWLIST=ghcr.io/["}, 1, `build \[\d+\]: The FABRIC_K8S_BUILDER_IMAGE_ALLOWLIST and FABRIC_K8S_BUILDER_IMAGE_DENYLIST environment variables must be comma separated lists of glob patterns, or regular expressions with a 'regex:' prefix: invalid image allowlist: rule 'ghcr\.io/\[' is not a valid glob pattern`),
+	)
+
 	It("should copy chaincode metadata to the build output directory", func() {
 		args := []string{"./testdata/ccsrc/withmetadata", "./testdata/ccmetadata/validmetadata", tempDir}
 		command := exec.Command(buildCmdPath, args...)
@@ -70,4 +137,159 @@ var _ = Describe("Main", func() {
 		indexPath := filepath.Join(tempDir, "META-INF", "test", "test.txt")
 		Expect(indexPath).To(BeARegularFile())
 	})
+
+	It("should record the chaincode package ID in the build output directory when chaincode is run as a service", func() {
+		buildOutputDir := filepath.Join(tempDir, "fabric-basic-cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b2847146102", "bld")
+		Expect(os.MkdirAll(buildOutputDir, 0o750)).To(Succeed())
+
+		args := []string{"./testdata/ccsrc/validimage", "./testdata/ccmetadata/validmetadata", buildOutputDir}
+		command := exec.Command(buildCmdPath, args...)
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_RUN_MODE=service")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(filepath.Join(buildOutputDir, "build.json")).To(BeARegularFile())
+		Expect(os.ReadFile(filepath.Join(buildOutputDir, "build.json"))).To(MatchJSON(`{"package_id":"basic:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b"}`))
+	})
+
+	It("should fail when chaincode is run as a service and the chaincode package ID is not available", func() {
+		args := []string{"./testdata/ccsrc/validimage", "./testdata/ccmetadata/validmetadata", tempDir}
+		command := exec.Command(buildCmdPath, args...)
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_RUN_MODE=service")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(session.Err).Should(gbytes.Say(`build \[\d+\]: Error building chaincode: chaincode label basic cannot be run as a service, since the chaincode package ID is not available to the k8s builder: unable to find package ID for chaincode label basic`))
+		Expect(filepath.Join(tempDir, "build.json")).NotTo(BeAnExistingFile())
+	})
+
+	It("should not record the chaincode package ID when chaincode is run as a job", func() {
+		buildOutputDir := filepath.Join(tempDir, "fabric-basic-cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b2847146102", "bld")
+		Expect(os.MkdirAll(buildOutputDir, 0o750)).To(Succeed())
+
+		args := []string{"./testdata/ccsrc/validimage", "./testdata/ccmetadata/validmetadata", buildOutputDir}
+		command := exec.Command(buildCmdPath, args...)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(filepath.Join(buildOutputDir, "build.json")).NotTo(BeAnExistingFile())
+	})
+
+	Describe("Verifying the chaincode image digest", func() {
+		var (
+			chaincodeSourceDir string
+			imageName          string
+			imageDigest        string
+		)
+
+		writeImageJSON := func(name, digest string) {
+			imageJSON := fmt.Sprintf(`{"name": %q, "digest": %q}`, name, digest)
+			Expect(os.WriteFile(filepath.Join(chaincodeSourceDir, "image.json"), []byte(imageJSON), 0o600)).To(Succeed())
+		}
+
+		runBuild := func(env ...string) *gexec.Session {
+			args := []string{chaincodeSourceDir, "./testdata/ccmetadata/validmetadata", tempDir}
+			command := exec.Command(buildCmdPath, args...)
+			command.Env = append(os.Environ(), env...)
+
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			return session
+		}
+
+		BeforeEach(func() {
+			chaincodeSourceDir = GinkgoT().TempDir()
+
+			server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+			DeferCleanup(server.Close)
+
+			imageName = strings.TrimPrefix(server.URL, "http://") + "/hyperledger/asset-transfer-basic"
+
+			image, err := random.Image(1024, 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			ref, err := name.ParseReference(imageName + ":latest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, image)).To(Succeed())
+
+			digest, err := image.Digest()
+			Expect(err).NotTo(HaveOccurred())
+
+			imageDigest = digest.String()
+		})
+
+		It("should succeed when the chaincode image exists in the registry", func() {
+			writeImageJSON(imageName, imageDigest)
+
+			session := runBuild("FABRIC_K8S_BUILDER_VERIFY_IMAGE_DIGEST=true")
+			Eventually(session).Should(gexec.Exit(0))
+		})
+
+		It("should fail when the chaincode image does not exist in the registry", func() {
+			writeImageJSON(imageName, "sha256:b35962f000d26ad046d4102f22d70a1351692fc69a9ddead89dfa13aefb942a7")
+
+			session := runBuild("FABRIC_K8S_BUILDER_VERIFY_IMAGE_DIGEST=true")
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(session.Err).Should(gbytes.Say(`build \[\d+\]: Error building chaincode: chaincode image .+/hyperledger/asset-transfer-basic@sha256:b35962f000d26ad046d4102f22d70a1351692fc69a9ddead89dfa13aefb942a7 does not exist in registry`))
+		})
+
+		It("should not check the registry by default", func() {
+			writeImageJSON(imageName, "sha256:b35962f000d26ad046d4102f22d70a1351692fc69a9ddead89dfa13aefb942a7")
+
+			session := runBuild()
+			Eventually(session).Should(gexec.Exit(0))
+		})
+
+		It("should fail when the chaincode image is not signed and there is a signature policy", func() {
+			writeImageJSON(imageName, imageDigest)
+
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			policyDir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(policyDir, "release.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(policyDir, "policy.yaml"), []byte("policies:\n  - name: release\n    images: ['*/hyperledger/*']\n    keys: [release.pub]\n"), 0o600)).To(Succeed())
+
+			session := runBuild("FABRIC_K8S_BUILDER_SIGNATURE_POLICY=" + filepath.Join(policyDir, "policy.yaml"))
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(session.Err).Should(gbytes.Say(`build \[\d+\]: Error building chaincode: signature verification failed for chaincode image .+ using signature policy release: no signatures found`))
+		})
+
+		It("should fail when FABRIC_K8S_BUILDER_VERIFY_IMAGE_DIGEST is not a boolean", func() {
+			writeImageJSON(imageName, imageDigest)
+
+			session := runBuild("FABRIC_K8S_BUILDER_VERIFY_IMAGE_DIGEST=always")
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(session.Err).Should(gbytes.Say(`build \[\d+\]: The FABRIC_K8S_BUILDER_VERIFY_IMAGE_DIGEST environment variable must be a valid boolean value, e\.g\. true or false`))
+		})
+
+		It("should write the resolved image digest when image tag resolution is enabled", func() {
+			imageJSON := fmt.Sprintf(`{"name": %q, "tag": "latest"}`, imageName)
+			Expect(os.WriteFile(filepath.Join(chaincodeSourceDir, "image.json"), []byte(imageJSON), 0o600)).To(Succeed())
+
+			session := runBuild("FABRIC_K8S_BUILDER_RESOLVE_IMAGE_TAGS=true", "FABRIC_K8S_BUILDER_VERIFY_IMAGE_DIGEST=true")
+			Eventually(session).Should(gexec.Exit(0))
+			Eventually(session.Err).Should(gbytes.Say(`build \[\d+\]: Resolved chaincode image .+/hyperledger/asset-transfer-basic:latest to digest ` + imageDigest))
+
+			Expect(filepath.Join(tempDir, "image.json")).To(BeAnExistingFile())
+			output, err := os.ReadFile(filepath.Join(tempDir, "image.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(ContainSubstring(`"digest": "` + imageDigest + `"`))
+		})
+
+		It("should fail when the chaincode image tag does not exist in the registry", func() {
+			imageJSON := fmt.Sprintf(`{"name": %q, "tag": "v9.9.9"}`, imageName)
+			Expect(os.WriteFile(filepath.Join(chaincodeSourceDir, "image.json"), []byte(imageJSON), 0o600)).To(Succeed())
+
+			session := runBuild("FABRIC_K8S_BUILDER_RESOLVE_IMAGE_TAGS=true")
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(session.Err).Should(gbytes.Say(`build \[\d+\]: Error building chaincode: chaincode image .+/hyperledger/asset-transfer-basic:v9\.9\.9 does not exist in registry`))
+		})
+	})
 })
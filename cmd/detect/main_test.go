@@ -1,6 +1,9 @@
 package main_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"os/exec"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -68,4 +71,75 @@ var _ = Describe("Main", func() {
 
 		Eventually(session.Err).ShouldNot(gbytes.Say(`detect \[\d+\]:`))
 	})
+
+	It("Logs JSON when the FABRIC_K8S_BUILDER_LOG_FORMAT is json", func() {
+		command := exec.Command(detectCmdPath, "CHAINCODE_SOURCE_DIR", "./testdata/validtype")
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_LOG_FORMAT=json")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Err.Contents()).To(MatchRegexp(`\{"time":"[^"]+","level":"INFO","msg":"Detected k8s chaincode: basic","command":"[^"]*detect","pid":\d+\}`))
+	})
+
+	It("Logs logfmt when the FABRIC_K8S_BUILDER_LOG_FORMAT is logfmt", func() {
+		command := exec.Command(detectCmdPath, "CHAINCODE_SOURCE_DIR", "./testdata/validtype")
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_LOG_FORMAT=logfmt")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Err.Contents()).To(MatchRegexp(`time=\S+ level=INFO msg="Detected k8s chaincode: basic" command=\S*detect pid=\d+`))
+	})
+
+	It("Logs an error when the FABRIC_K8S_BUILDER_LOG_FORMAT is not valid", func() {
+		command := exec.Command(detectCmdPath, "CHAINCODE_SOURCE_DIR", "./testdata/validtype")
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_LOG_FORMAT=xml")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(session.Err).Should(gbytes.Say(`detect \[\d+\]: The FABRIC_K8S_BUILDER_LOG_FORMAT environment variable must be 'text', 'json', or 'logfmt'`))
+	})
+
+	It("Does not log info messages when the FABRIC_K8S_BUILDER_LOG_LEVEL is error", func() {
+		command := exec.Command(detectCmdPath, "CHAINCODE_SOURCE_DIR", "./testdata/validtype")
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_LOG_LEVEL=error")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Err.Contents()).To(BeEmpty())
+	})
+
+	It("Logs an error when the FABRIC_K8S_BUILDER_LOG_LEVEL is not valid", func() {
+		command := exec.Command(detectCmdPath, "CHAINCODE_SOURCE_DIR", "./testdata/validtype")
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_LOG_LEVEL=verbose")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(session.Err).Should(gbytes.Say(`detect \[\d+\]: The FABRIC_K8S_BUILDER_LOG_LEVEL environment variable must be 'error', 'warn', 'info', 'debug', or 'trace'`))
+	})
+
+	It("Exports a span when an OTLP endpoint is configured", func() {
+		requests := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case requests <- r.URL.Path:
+			default:
+			}
+
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+
+		command := exec.Command(detectCmdPath, "CHAINCODE_SOURCE_DIR", "./testdata/validtype")
+		command.Env = append(os.Environ(), "OTEL_EXPORTER_OTLP_ENDPOINT="+server.URL)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0))
+		Eventually(requests).Should(Receive(Equal("/v1/traces")))
+	})
 })
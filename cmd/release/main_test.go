@@ -1,11 +1,13 @@
 package main_test
 
 import (
+	"os"
 	"os/exec"
 	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 )
 
@@ -39,6 +41,91 @@ var _ = Describe("Main", func() {
 		}),
 	)
 
+	It("should return an error if the FABRIC_K8S_BUILDER_RUN_MODE environment variable is invalid", func() {
+		args := []string{"./testdata/buildwithoutindexes", tempDir}
+		command := exec.Command(releaseCmdPath, args...)
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_RUN_MODE=daemon")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(
+			session.Err,
+		).Should(gbytes.Say(`release \[\d+\]: The FABRIC_K8S_BUILDER_RUN_MODE environment variable must be either 'job' or 'service'`))
+	})
+
+	It("should return an error if the CORE_PEER_ID environment variable is not set in service mode", func() {
+		args := []string{"./testdata/buildwithoutindexes", tempDir}
+		command := exec.Command(releaseCmdPath, args...)
+		command.Env = append(os.Environ(), "FABRIC_K8S_BUILDER_RUN_MODE=service")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(
+			session.Err,
+		).Should(gbytes.Say(`release \[\d+\]: Expected CORE_PEER_ID environment variable`))
+	})
+
+	It("should return an error if the CORE_PEER_TLS_ENABLED environment variable is not set in service mode", func() {
+		args := []string{"./testdata/buildwithoutindexes", tempDir}
+		command := exec.Command(releaseCmdPath, args...)
+		command.Env = append(os.Environ(),
+			"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+			"FABRIC_K8S_BUILDER_RUN_MODE=service",
+		)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(
+			session.Err,
+		).Should(gbytes.Say(`release \[\d+\]: Expected CORE_PEER_TLS_ENABLED environment variable in service run mode`))
+	})
+
+	DescribeTable("Running the release command produces the correct error when the peer uses TLS in service mode",
+		func(tlsEnabledValue string) {
+			args := []string{"./testdata/buildwithoutindexes", tempDir}
+			command := exec.Command(releaseCmdPath, args...)
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+				"CORE_PEER_TLS_ENABLED="+tlsEnabledValue,
+				"FABRIC_K8S_BUILDER_RUN_MODE=service",
+			)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(`release \[\d+\]: The CORE_PEER_TLS_ENABLED environment variable must be 'false' in service run mode, since the connection to the chaincode service does not use TLS`))
+		},
+		Entry("When the CORE_PEER_TLS_ENABLED is true", "true"),
+		Entry("When the CORE_PEER_TLS_ENABLED is not a boolean", "yes please"),
+	)
+
+	DescribeTable("Running the release command produces the correct error for invalid FABRIC_K8S_BUILDER_SERVICE_REPLICAS environment variable values",
+		func(serviceReplicasValue string) {
+			args := []string{"./testdata/buildwithoutindexes", tempDir}
+			command := exec.Command(releaseCmdPath, args...)
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+				"CORE_PEER_TLS_ENABLED=false",
+				"FABRIC_K8S_BUILDER_RUN_MODE=service",
+				"FABRIC_K8S_BUILDER_SERVICE_REPLICAS="+serviceReplicasValue,
+			)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(`release \[\d+\]: The FABRIC_K8S_BUILDER_SERVICE_REPLICAS environment variable must be a positive integer`))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_SERVICE_REPLICAS is zero", "0"),
+		Entry("When the FABRIC_K8S_BUILDER_SERVICE_REPLICAS is not a number", "three"),
+	)
+
 	It("should only copy .json CouchDB index definitions to the release output directory", func() {
 		args := []string{"./testdata/buildwithindexes", tempDir}
 		command := exec.Command(releaseCmdPath, args...)
@@ -109,4 +109,281 @@ var _ = Describe("Main", func() {
 		Entry("When the FABRIC_K8S_BUILDER_START_TIMEOUT is missing a duration unit", "3", `run \[\d+\]: The FABRIC_K8S_BUILDER_START_TIMEOUT environment variable must be a valid Go duration string, e\.g\. 3m40s: time: missing unit in duration "3"`),
 		Entry("When the FABRIC_K8S_BUILDER_START_TIMEOUT is not a valid duration string", "three minutes", `run \[\d+\]: The FABRIC_K8S_BUILDER_START_TIMEOUT environment variable must be a valid Go duration string, e\.g\. 3m40s: time: invalid duration "three minutes"`),
 	)
+
+	DescribeTable("Running the run command produces the correct error for invalid FABRIC_K8S_BUILDER_SHUTDOWN_GRACE_PERIOD environment variable values",
+		func(shutdownGracePeriodValue, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+				"FABRIC_K8S_BUILDER_SHUTDOWN_GRACE_PERIOD="+shutdownGracePeriodValue,
+			)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_SHUTDOWN_GRACE_PERIOD is missing a duration unit", "4", `run \[\d+\]: The FABRIC_K8S_BUILDER_SHUTDOWN_GRACE_PERIOD environment variable must be a positive Go duration string, e\.g\. 4s`),
+		Entry("When the FABRIC_K8S_BUILDER_SHUTDOWN_GRACE_PERIOD is zero", "0s", `run \[\d+\]: The FABRIC_K8S_BUILDER_SHUTDOWN_GRACE_PERIOD environment variable must be a positive Go duration string, e\.g\. 4s`),
+	)
+
+	DescribeTable("Running the run command produces the correct error for invalid chaincode retry policy environment variable values",
+		func(envVars []string, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+			)
+			command.Env = append(command.Env, envVars...)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_RESTART_POLICY is not supported", []string{"FABRIC_K8S_BUILDER_RESTART_POLICY=Always"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_RESTART_POLICY environment variable must be either 'Never' or 'OnFailure'`),
+		Entry("When the FABRIC_K8S_BUILDER_BACKOFF_LIMIT is negative", []string{"FABRIC_K8S_BUILDER_BACKOFF_LIMIT=-1"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_BACKOFF_LIMIT environment variable must be zero or a positive integer`),
+		Entry("When the FABRIC_K8S_BUILDER_IGNORE_POD_DISRUPTIONS is not a boolean", []string{"FABRIC_K8S_BUILDER_IGNORE_POD_DISRUPTIONS=yes please"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_IGNORE_POD_DISRUPTIONS environment variable must be a valid boolean value, e\.g\. true or false`),
+		Entry("When the FABRIC_K8S_BUILDER_FAIL_JOB_EXIT_CODES contains zero", []string{"FABRIC_K8S_BUILDER_FAIL_JOB_EXIT_CODES=1,0"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_FAIL_JOB_EXIT_CODES environment variable must be a comma separated list of non-zero exit codes, e\.g\. 1,42`),
+		Entry("When pod failure policy rules are used with the OnFailure restart policy", []string{"FABRIC_K8S_BUILDER_RESTART_POLICY=OnFailure", "FABRIC_K8S_BUILDER_IGNORE_POD_DISRUPTIONS=true"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_IGNORE_POD_DISRUPTIONS and FABRIC_K8S_BUILDER_FAIL_JOB_EXIT_CODES environment variables can only be used with the 'Never' restart policy`),
+	)
+
+	DescribeTable("Running the run command produces the correct error for invalid credential store environment variable values",
+		func(envVars []string, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+			)
+			command.Env = append(command.Env, envVars...)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_CREDENTIAL_STORE is not supported", []string{"FABRIC_K8S_BUILDER_CREDENTIAL_STORE=aws"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_CREDENTIAL_STORE environment variable must be either 'kubernetes' or 'vault'`),
+		Entry("When the FABRIC_K8S_BUILDER_VAULT_ADDR is not set", []string{"FABRIC_K8S_BUILDER_CREDENTIAL_STORE=vault"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_VAULT_ADDR environment variable must be a valid http or https URL, e\.g\. https://vault:8200`),
+		Entry("When the FABRIC_K8S_BUILDER_VAULT_ROLE is not set", []string{"FABRIC_K8S_BUILDER_CREDENTIAL_STORE=vault", "FABRIC_K8S_BUILDER_VAULT_ADDR=https://vault:8200", "FABRIC_K8S_BUILDER_VAULT_TOKEN_FILE=/var/run/secrets/vault/token"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_VAULT_TOKEN_FILE, FABRIC_K8S_BUILDER_VAULT_KV_MOUNT, and FABRIC_K8S_BUILDER_VAULT_ROLE environment variables must be set when the FABRIC_K8S_BUILDER_CREDENTIAL_STORE environment variable is 'vault'`),
+	)
+
+	DescribeTable("Running the run command produces the correct error for invalid chaincode pod environment variable values",
+		func(envVars []string, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+			)
+			command.Env = append(command.Env, envVars...)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_ALLOW_RUN_AS_ROOT is not a boolean", []string{"FABRIC_K8S_BUILDER_ALLOW_RUN_AS_ROOT=root"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_ALLOW_RUN_AS_ROOT environment variable must be a valid boolean value, e\.g\. true or false`),
+		Entry("When the FABRIC_K8S_BUILDER_RUN_AS_USER is not a number", []string{"FABRIC_K8S_BUILDER_RUN_AS_USER=fabric"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_RUN_AS_USER environment variable must be zero or a positive integer`),
+		Entry("When the FABRIC_K8S_BUILDER_RUN_AS_USER is root without FABRIC_K8S_BUILDER_ALLOW_RUN_AS_ROOT", []string{"FABRIC_K8S_BUILDER_RUN_AS_USER=0"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_RUN_AS_USER environment variable cannot be 0 unless FABRIC_K8S_BUILDER_ALLOW_RUN_AS_ROOT is true`),
+		Entry("When the FABRIC_K8S_BUILDER_ALLOW_PRIVILEGE_ESCALATION is not a boolean", []string{"FABRIC_K8S_BUILDER_ALLOW_PRIVILEGE_ESCALATION=maybe"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_ALLOW_PRIVILEGE_ESCALATION environment variable must be a valid boolean value, e\.g\. true or false`),
+		Entry("When the FABRIC_K8S_BUILDER_ADD_CAPABILITIES contains ALL", []string{"FABRIC_K8S_BUILDER_ADD_CAPABILITIES=NET_BIND_SERVICE,ALL"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_ADD_CAPABILITIES environment variable must be a comma separated list of capabilities, e\.g\. NET_BIND_SERVICE`),
+		Entry("When the FABRIC_K8S_BUILDER_ADD_CAPABILITIES contains an invalid capability", []string{"FABRIC_K8S_BUILDER_ADD_CAPABILITIES=net-admin"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_ADD_CAPABILITIES environment variable must be a comma separated list of capabilities, e\.g\. NET_BIND_SERVICE`),
+		Entry("When the FABRIC_K8S_BUILDER_SECCOMP_PROFILE is not supported", []string{"FABRIC_K8S_BUILDER_SECCOMP_PROFILE=Localhost"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_SECCOMP_PROFILE environment variable must be either 'RuntimeDefault' or 'Unconfined'`),
+		Entry("When the FABRIC_K8S_BUILDER_WRITABLE_ROOT_FILESYSTEM is not a boolean", []string{"FABRIC_K8S_BUILDER_WRITABLE_ROOT_FILESYSTEM=rw"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_WRITABLE_ROOT_FILESYSTEM environment variable must be a valid boolean value, e\.g\. true or false`),
+		Entry("When the FABRIC_K8S_BUILDER_READINESS_PROBE type is not supported", []string{"FABRIC_K8S_BUILDER_READINESS_PROBE=http:8080"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_READINESS_PROBE environment variable must be a valid probe, e\.g\. exec:/bin/healthcheck, tcp:9999, or grpc:9999: unsupported probe type 'http'`),
+		Entry("When the FABRIC_K8S_BUILDER_LIVENESS_PROBE port is invalid", []string{"FABRIC_K8S_BUILDER_LIVENESS_PROBE=tcp:chaincode"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_LIVENESS_PROBE environment variable must be a valid probe, e\.g\. exec:/bin/healthcheck, tcp:9999, or grpc:9999: invalid probe port 'chaincode'`),
+		Entry("When the FABRIC_K8S_BUILDER_CHAINCODE_ENV overrides a reserved variable", []string{"FABRIC_K8S_BUILDER_CHAINCODE_ENV=LOG_LEVEL=debug,CORE_PEER_ADDRESS=peer1:7052"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_CHAINCODE_ENV environment variable must be a comma separated list of NAME=value pairs, e\.g\. LOG_LEVEL=debug: environment variable CORE_PEER_ADDRESS is reserved for the k8s builder`),
+		Entry("When the FABRIC_K8S_BUILDER_CHAINCODE_ENV_FROM type is not supported", []string{"FABRIC_K8S_BUILDER_CHAINCODE_ENV_FROM=vault:chaincode"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_CHAINCODE_ENV_FROM environment variable must be a comma separated list of ConfigMaps and Secrets, e\.g\. configmap:chaincode-env,secret:chaincode-credentials: unsupported config source type 'vault'`),
+		Entry("When the FABRIC_K8S_BUILDER_CHAINCODE_CONFIG_FILES mount path is relative", []string{"FABRIC_K8S_BUILDER_CHAINCODE_CONFIG_FILES=configmap:chaincode-config:config"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_CHAINCODE_CONFIG_FILES environment variable must be a comma separated list of ConfigMaps and Secrets with mount paths, e\.g\. configmap:chaincode-config:/etc/chaincode: invalid config file: mount path 'config' must be an absolute path`),
+		Entry("When the FABRIC_K8S_BUILDER_CHAINCODE_CONFIG_ALLOWLIST type is missing", []string{"FABRIC_K8S_BUILDER_CHAINCODE_CONFIG_ALLOWLIST=fabcar-*"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_CHAINCODE_CONFIG_ALLOWLIST environment variable must be a comma separated list of ConfigMap and Secret name patterns, e\.g\. configmap:fabcar-\*,secret:fabcar-\*: invalid config source pattern 'fabcar-\*'`),
+		Entry("When the FABRIC_K8S_BUILDER_NODE_SELECTOR is not a valid label selector", []string{"FABRIC_K8S_BUILDER_NODE_SELECTOR=disktype in ssd"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_NODE_SELECTOR environment variable must be a valid node label selector, e\.g\. disktype=ssd: invalid node selector 'disktype in ssd'`),
+		Entry("When the FABRIC_K8S_BUILDER_TOLERATIONS effect is not supported", []string{"FABRIC_K8S_BUILDER_TOLERATIONS=dedicated=chaincode:NoRun"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_TOLERATIONS environment variable must be a comma separated list of taints, e\.g\. dedicated=chaincode:NoSchedule: toleration 'dedicated=chaincode:NoRun' has an unsupported effect 'NoRun'`),
+		Entry("When the FABRIC_K8S_BUILDER_TOPOLOGY_SPREAD action is not supported", []string{"FABRIC_K8S_BUILDER_TOPOLOGY_SPREAD=kubernetes.io/hostname:Never"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_TOPOLOGY_SPREAD environment variable must be a comma separated list of topology keys, e\.g\. topology\.kubernetes\.io/zone,kubernetes\.io/hostname:DoNotSchedule: invalid topology spread 'kubernetes\.io/hostname:Never': unsupported action 'Never'`),
+		Entry("When the FABRIC_K8S_BUILDER_POD_ANTI_AFFINITY type is not supported", []string{"FABRIC_K8S_BUILDER_POD_ANTI_AFFINITY=always"}, `run \[\d+\]: The FABRIC_K8S_BUILDER_POD_ANTI_AFFINITY environment variable must be preferred or required, with an optional topology key, e\.g\. required:kubernetes\.io/hostname: unsupported pod anti-affinity 'always'`),
+	)
+
+	DescribeTable("Running the run command logs debug messages for FABRIC_K8S_BUILDER_DEBUG and FABRIC_K8S_BUILDER_LOG_LEVEL environment variable values",
+		func(envVar, expectedMessage string) {
+			command := exec.Command(runCmdPath)
+			command.Env = append(os.Environ(), envVar)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(session.Err).Should(gbytes.Say(expectedMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_DEBUG is true", "FABRIC_K8S_BUILDER_DEBUG=true", `run \[\d+\] DEBUG: FABRIC_K8S_BUILDER_DEBUG=true`),
+		Entry("When the FABRIC_K8S_BUILDER_DEBUG is 1", "FABRIC_K8S_BUILDER_DEBUG=1", `run \[\d+\] DEBUG: FABRIC_K8S_BUILDER_DEBUG=1`),
+		Entry("When the FABRIC_K8S_BUILDER_LOG_LEVEL is trace", "FABRIC_K8S_BUILDER_LOG_LEVEL=trace", `run \[\d+\] DEBUG: FABRIC_K8S_BUILDER_LOG_LEVEL=trace`),
+		Entry("When the FABRIC_K8S_BUILDER_DEBUG is not a boolean", "FABRIC_K8S_BUILDER_DEBUG=yes", `run \[\d+\] WARN: Ignoring the FABRIC_K8S_BUILDER_DEBUG environment variable, which must be a valid boolean value, e\.g\. true or false`),
+	)
+
+	It("should return an error if FABRIC_K8S_BUILDER_VERIFY_SIGNATURES_ON_RUN is set without a signature policy", func() {
+		args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+		command := exec.Command(runCmdPath, args...)
+
+		command.Env = append(os.Environ(),
+			"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+			"FABRIC_K8S_BUILDER_VERIFY_SIGNATURES_ON_RUN=true",
+		)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(
+			session.Err,
+		).Should(gbytes.Say(`run \[\d+\]: The FABRIC_K8S_BUILDER_VERIFY_SIGNATURES_ON_RUN environment variable requires the FABRIC_K8S_BUILDER_SIGNATURE_POLICY environment variable to be set`))
+	})
+
+	It("should return an error for an invalid FABRIC_K8S_BUILDER_EXISTING_JOB_POLICY environment variable value", func() {
+		args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+		command := exec.Command(runCmdPath, args...)
+
+		command.Env = append(os.Environ(),
+			"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+			"FABRIC_K8S_BUILDER_EXISTING_JOB_POLICY=reuse",
+		)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(
+			session.Err,
+		).Should(gbytes.Say(`run \[\d+\]: The FABRIC_K8S_BUILDER_EXISTING_JOB_POLICY environment variable must be 'adopt', 'replace', or 'ignore'`))
+	})
+
+	It("should return an error if chaincode.json contains some, but not all, of the peer TLS certificates", func() {
+		args := []string{"./testdata/validimage", "./testdata/partialtlschaincode"}
+		command := exec.Command(runCmdPath, args...)
+
+		command.Env = append(os.Environ(),
+			"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+		)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(
+			session.Err,
+		).Should(gbytes.Say(`run \[\d+\]: Error running chaincode: testdata/partialtlschaincode/chaincode\.json file must contain all of 'client_cert', 'client_key', and 'root_cert' for a TLS peer connection, or none of them for a plain text peer connection: missing 'client_key'`))
+	})
+
+	DescribeTable("Running the run command produces the correct error for invalid FABRIC_K8S_BUILDER_IMAGE_PULL_SECRETS environment variable values",
+		func(imagePullSecrets, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+				"FABRIC_K8S_BUILDER_IMAGE_PULL_SECRETS="+imagePullSecrets,
+			)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_IMAGE_PULL_SECRETS contains an invalid secret name", "registry-a,Registry_B", `run \[\d+\]: The FABRIC_K8S_BUILDER_IMAGE_PULL_SECRETS environment variable must be a comma separated list of Kubernetes secret names, e\.g\. registry-a,registry-b: a lowercase RFC 1123 subdomain must consist of`),
+		Entry("When the FABRIC_K8S_BUILDER_IMAGE_PULL_SECRETS contains an empty secret name", "registry-a,,registry-b", `run \[\d+\]: The FABRIC_K8S_BUILDER_IMAGE_PULL_SECRETS environment variable must be a comma separated list of Kubernetes secret names`),
+	)
+
+	DescribeTable("Running the run command produces the correct error for invalid chaincode resource environment variable values",
+		func(resourceVariable, resourceValue, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+				resourceVariable+"="+resourceValue,
+			)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_CPU_REQUEST is not a valid quantity", "FABRIC_K8S_BUILDER_CPU_REQUEST", "one cpu", `run \[\d+\]: The FABRIC_K8S_BUILDER_CPU_REQUEST environment variable must be a valid, non-negative Kubernetes quantity, e\.g\. 500m or 128Mi: quantities must match the regular expression`),
+		Entry("When the FABRIC_K8S_BUILDER_CPU_LIMIT is not a valid quantity", "FABRIC_K8S_BUILDER_CPU_LIMIT", "1 core", `run \[\d+\]: The FABRIC_K8S_BUILDER_CPU_LIMIT environment variable must be a valid, non-negative Kubernetes quantity, e\.g\. 500m or 128Mi: quantities must match the regular expression`),
+		Entry("When the FABRIC_K8S_BUILDER_MEMORY_REQUEST is not a valid quantity", "FABRIC_K8S_BUILDER_MEMORY_REQUEST", "128MB!", `run \[\d+\]: The FABRIC_K8S_BUILDER_MEMORY_REQUEST environment variable must be a valid, non-negative Kubernetes quantity, e\.g\. 500m or 128Mi: quantities must match the regular expression`),
+		Entry("When the FABRIC_K8S_BUILDER_MEMORY_LIMIT is not a valid quantity", "FABRIC_K8S_BUILDER_MEMORY_LIMIT", "lots", `run \[\d+\]: The FABRIC_K8S_BUILDER_MEMORY_LIMIT environment variable must be a valid, non-negative Kubernetes quantity, e\.g\. 500m or 128Mi: quantities must match the regular expression`),
+		Entry("When the FABRIC_K8S_BUILDER_CPU_REQUEST is negative", "FABRIC_K8S_BUILDER_CPU_REQUEST", "-500m", `run \[\d+\]: The FABRIC_K8S_BUILDER_CPU_REQUEST environment variable must be a valid, non-negative Kubernetes quantity, e\.g\. 500m or 128Mi: quantity must not be negative`),
+		Entry("When the FABRIC_K8S_BUILDER_MEMORY_LIMIT is negative", "FABRIC_K8S_BUILDER_MEMORY_LIMIT", "-1", `run \[\d+\]: The FABRIC_K8S_BUILDER_MEMORY_LIMIT environment variable must be a valid, non-negative Kubernetes quantity, e\.g\. 500m or 128Mi: quantity must not be negative`),
+	)
+
+	DescribeTable("Running the run command produces the correct error for invalid chaincode log environment variable values",
+		func(logVariable, logValue, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+				logVariable+"="+logValue,
+			)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_STREAM_LOGS is not a boolean", "FABRIC_K8S_BUILDER_STREAM_LOGS", "sometimes", `run \[\d+\]: The FABRIC_K8S_BUILDER_STREAM_LOGS environment variable must be a valid boolean value, e\.g\. true or false`),
+		Entry("When the FABRIC_K8S_BUILDER_LOG_TAIL_LINES is negative", "FABRIC_K8S_BUILDER_LOG_TAIL_LINES", "-1", `run \[\d+\]: The FABRIC_K8S_BUILDER_LOG_TAIL_LINES environment variable must be zero or a positive integer`),
+		Entry("When the FABRIC_K8S_BUILDER_LOG_TAIL_LINES is not a number", "FABRIC_K8S_BUILDER_LOG_TAIL_LINES", "twenty", `run \[\d+\]: The FABRIC_K8S_BUILDER_LOG_TAIL_LINES environment variable must be zero or a positive integer`),
+	)
+
+	DescribeTable("Running the run command produces the correct error for invalid FABRIC_K8S_BUILDER_METRICS_PUSHGATEWAY_URL environment variable values",
+		func(pushgatewayURL, expectedErrorMessage string) {
+			args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+			command := exec.Command(runCmdPath, args...)
+
+			command.Env = append(os.Environ(),
+				"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+				"FABRIC_K8S_BUILDER_METRICS_PUSHGATEWAY_URL="+pushgatewayURL,
+			)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(
+				session.Err,
+			).Should(gbytes.Say(expectedErrorMessage))
+		},
+		Entry("When the FABRIC_K8S_BUILDER_METRICS_PUSHGATEWAY_URL has no scheme", "pushgateway:9091", `run \[\d+\]: The FABRIC_K8S_BUILDER_METRICS_PUSHGATEWAY_URL environment variable must be a valid http or https URL, e\.g\. http://pushgateway:9091`),
+		Entry("When the FABRIC_K8S_BUILDER_METRICS_PUSHGATEWAY_URL has an unsupported scheme", "ftp://pushgateway:9091", `run \[\d+\]: The FABRIC_K8S_BUILDER_METRICS_PUSHGATEWAY_URL environment variable must be a valid http or https URL, e\.g\. http://pushgateway:9091`),
+	)
+
+	It("should return an error if a chaincode resource request is greater than the limit", func() {
+		args := []string{"BUILD_OUTPUT_DIR", "RUN_METADATA_DIR"}
+		command := exec.Command(runCmdPath, args...)
+
+		command.Env = append(os.Environ(),
+			"CORE_PEER_ID=core-peer-id-abcdefghijklmnopqrstuvwxyz-0123456789",
+			"FABRIC_K8S_BUILDER_MEMORY_REQUEST=1Gi",
+			"FABRIC_K8S_BUILDER_MEMORY_LIMIT=512Mi",
+		)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(1))
+		Eventually(
+			session.Err,
+		).Should(gbytes.Say(`run \[\d+\]: The memory request 1Gi must be less than or equal to the memory limit 512Mi`))
+	})
 })
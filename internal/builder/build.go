@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
@@ -15,6 +16,11 @@ type Build struct {
 	ChaincodeSourceDirectory   string
 	ChaincodeMetadataDirectory string
 	BuildOutputDirectory       string
+	VerifyImageDigest          bool
+	SignaturePolicyPath        string
+	ImagePolicy                *util.ImagePolicy
+	ResolveImageTags           bool
+	RunMode                    string
 }
 
 func (b *Build) Run(ctx context.Context) error {
@@ -26,6 +32,8 @@ func (b *Build) Run(ctx context.Context) error {
 		return err
 	}
 
+	tracing.SetAttributes(ctx, tracing.PackageLabelKey.String(metadata.Label))
+
 	if errs := validation.IsDNS1035Label(metadata.Label); len(errs) != 0 {
 		return fmt.Errorf(
 			"chaincode label '%s' must be a valid RFC1035 label: %v",
@@ -34,15 +42,77 @@ func (b *Build) Run(ctx context.Context) error {
 		)
 	}
 
-	err = util.CopyImageJSON(logger, b.ChaincodeSourceDirectory, b.BuildOutputDirectory)
+	if b.ResolveImageTags {
+		err = util.ResolveImageJSON(ctx, logger, b.ChaincodeSourceDirectory, b.BuildOutputDirectory, b.ImagePolicy)
+	} else {
+		err = util.CopyImageJSON(logger, b.ChaincodeSourceDirectory, b.BuildOutputDirectory, b.ImagePolicy)
+	}
+
 	if err != nil {
 		return err
 	}
 
+	if b.VerifyImageDigest || b.SignaturePolicyPath != "" {
+		err = b.verifyImage(ctx, logger, metadata.Label)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = util.CopyMetadataDir(logger, b.ChaincodeSourceDirectory, b.BuildOutputDirectory)
 	if err != nil {
 		return err
 	}
 
+	if b.RunMode == util.RunModeService {
+		return b.writeBuildJSON(logger, metadata.Label)
+	}
+
+	return nil
+}
+
+// writeBuildJSON records the chaincode package ID in the build output for
+// chaincode which is run as a service. Fabric does not pass the package ID to
+// the build or release commands, so it is found using the name of the build
+// context directory created by the peer. The build fails if the package ID
+// cannot be found, rather than the release failing later.
+func (b *Build) writeBuildJSON(logger *log.CmdLogger, label string) error {
+	chaincodeID, err := util.GetChaincodeIDFromBuildContext(b.BuildOutputDirectory, label)
+	if err != nil {
+		return fmt.Errorf(
+			"chaincode label %s cannot be run as a service, since the chaincode package ID is not available to the k8s builder: %w",
+			label,
+			err,
+		)
+	}
+
+	return util.WriteBuildJSON(logger, b.BuildOutputDirectory, chaincodeID)
+}
+
+// verifyImage checks the chaincode image in the registry, to catch problems
+// when chaincode is installed rather than when it is started.
+func (b *Build) verifyImage(ctx context.Context, logger *log.CmdLogger, label string) error {
+	imageData, err := util.ReadImageJSON(logger, b.BuildOutputDirectory, b.ImagePolicy)
+	if err != nil {
+		return err
+	}
+
+	if b.VerifyImageDigest {
+		if err := util.VerifyImageDigest(ctx, logger, imageData); err != nil {
+			return err
+		}
+	}
+
+	if b.SignaturePolicyPath != "" {
+		policy, err := util.ReadSignaturePolicy(logger, b.SignaturePolicyPath)
+		if err != nil {
+			return err
+		}
+
+		if err := util.VerifyImageSignature(ctx, logger, policy, imageData, label); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
 )
 
@@ -27,6 +28,8 @@ func (d *Detect) Run(ctx context.Context) error {
 		return err
 	}
 
+	tracing.SetAttributes(ctx, tracing.PackageLabelKey.String(metadata.Label))
+
 	if strings.ToLower(metadata.Type) == "k8s" {
 		logger.Printf("Detected k8s chaincode: %s", metadata.Label)
 
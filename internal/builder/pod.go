@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package builder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// PodSettings configures chaincode pods, whether chaincode is run as a job or
+// as a service.
+type PodSettings struct {
+	ImagePullSecrets         []string
+	ChaincodeSecurityPolicy  util.SecurityPolicy
+	ChaincodeProbes          util.ChaincodeProbes
+	ChaincodeConfig          util.ChaincodeConfig
+	ChaincodeConfigAllowlist []util.ConfigSource
+	SchedulingPolicy         util.SchedulingPolicy
+}
+
+// getPodOptions returns the chaincode pod options, using any settings in the
+// image.json file in preference to the builder defaults.
+func (s *PodSettings) getPodOptions(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	secretsClient v1.SecretInterface,
+	namespace string,
+	chaincodeData *util.ChaincodeJSON,
+	imageData *util.ImageJSON,
+) (util.PodOptions, error) {
+	imagePullSecrets, err := util.GetImagePullSecrets(ctx, logger, secretsClient, namespace, s.ImagePullSecrets, imageData)
+	if err != nil {
+		return util.PodOptions{}, fmt.Errorf("invalid image pull secrets for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	probes, err := util.GetChaincodeProbes(s.ChaincodeProbes, imageData)
+	if err != nil {
+		return util.PodOptions{}, fmt.Errorf("invalid probes for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	config, err := util.GetChaincodeConfig(s.ChaincodeConfig, s.ChaincodeConfigAllowlist, imageData)
+	if err != nil {
+		return util.PodOptions{}, fmt.Errorf("invalid configuration for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	return util.PodOptions{
+		ImagePullSecrets: imagePullSecrets,
+		SecurityPolicy:   s.ChaincodeSecurityPolicy,
+		Probes:           probes,
+		Config:           config,
+		SchedulingPolicy: s.SchedulingPolicy,
+	}, nil
+}
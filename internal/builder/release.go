@@ -4,14 +4,33 @@ package builder
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 type Release struct {
+	PodSettings
+
 	BuildOutputDirectory   string
 	ReleaseOutputDirectory string
+	RunMode                string
+	PeerID                 string
+	KubeconfigPath         string
+	KubeNamespace          string
+	KubeNodeRole           string
+	KubeServiceAccount     string
+	KubeNamePrefix         string
+	KubeJobTemplatePath    string
+	SignaturePolicyPath    string
+	ImagePolicy            *util.ImagePolicy
+	ChaincodeResources     apiv1.ResourceRequirements
+	ServiceReplicas        int32
 }
 
 func (r *Release) Run(ctx context.Context) error {
@@ -27,5 +46,152 @@ func (r *Release) Run(ctx context.Context) error {
 		return err
 	}
 
+	if r.RunMode == util.RunModeService {
+		return r.releaseChaincodeService(ctx, logger)
+	}
+
 	return nil
 }
+
+// releaseChaincodeService runs the chaincode as a service and, if the chaincode
+// server is required, release is responsible for providing a connection.json
+// file in the chaincode/server/ directory under RELEASE_OUTPUT_DIR.
+func (r *Release) releaseChaincodeService(ctx context.Context, logger *log.CmdLogger) error {
+	imageData, err := util.ReadImageJSON(logger, r.BuildOutputDirectory, r.ImagePolicy)
+	if err != nil {
+		return err
+	}
+
+	buildData, err := util.ReadBuildJSON(logger, r.BuildOutputDirectory)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to find chaincode package ID, chaincode must be built by the k8s builder with %s=%s to run as a service: %w",
+			util.RunModeVariable,
+			util.RunModeService,
+			err,
+		)
+	}
+
+	chaincodeID := buildData.PackageID
+
+	chaincodeData := &util.ChaincodeJSON{ChaincodeID: chaincodeID}
+
+	logger = logger.With(
+		log.ChaincodeIDKey, chaincodeID,
+		log.PackageLabelKey, util.NewChaincodePackageID(chaincodeID).Label,
+	)
+	tracing.SetAttributes(ctx,
+		tracing.PeerIDKey.String(r.PeerID),
+		tracing.ChaincodeIDKey.String(chaincodeID),
+		tracing.PackageLabelKey.String(util.NewChaincodePackageID(chaincodeID).Label),
+	)
+
+	err = verifyImageSignature(ctx, logger, r.SignaturePolicyPath, imageData, chaincodeID)
+	if err != nil {
+		return err
+	}
+
+	kubeObjectName := util.GetValidRfc1035LabelName(r.KubeNamePrefix, r.PeerID, chaincodeData, 0)
+
+	clientset, err := util.GetKubeClientset(logger, r.KubeconfigPath)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to connect kubernetes client for chaincode ID %s: %w",
+			chaincodeData.ChaincodeID,
+			err,
+		)
+	}
+
+	deploymentsClient := clientset.AppsV1().Deployments(r.KubeNamespace)
+
+	deployment, err := r.applyChaincodeDeployment(ctx, logger, clientset, kubeObjectName, chaincodeData, imageData)
+	if err != nil {
+		return err
+	}
+
+	servicesClient := clientset.CoreV1().Services(r.KubeNamespace)
+
+	service, err := util.ApplyChaincodeService(
+		ctx,
+		logger,
+		servicesClient,
+		kubeObjectName,
+		r.KubeNamespace,
+		r.PeerID,
+		chaincodeData,
+	)
+	if err != nil {
+		// The peer cannot connect to the chaincode without the service
+		if deleteErr := util.DeleteChaincodeDeployment(ctx, logger, deploymentsClient, deployment); deleteErr != nil {
+			logger.Warnf("Unable to delete chaincode deployment %s/%s: %v", deployment.Namespace, deployment.Name, deleteErr)
+		}
+
+		return err
+	}
+
+	chaincodeServerAddress := util.GetChaincodeServerAddress(service.Name, service.Namespace)
+
+	err = util.WriteConnectionJSON(logger, r.ReleaseOutputDirectory, chaincodeServerAddress)
+	if err != nil {
+		return err
+	}
+
+	logger.Printf(
+		"Running chaincode ID %s as a service with kubernetes deployment %s/%s at %s",
+		chaincodeData.ChaincodeID,
+		deployment.Namespace,
+		deployment.Name,
+		chaincodeServerAddress,
+	)
+
+	return nil
+}
+
+// applyChaincodeDeployment creates or updates the chaincode deployment.
+func (r *Release) applyChaincodeDeployment(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	clientset *kubernetes.Clientset,
+	kubeObjectName string,
+	chaincodeData *util.ChaincodeJSON,
+	imageData *util.ImageJSON,
+) (*appsv1.Deployment, error) {
+	resources, err := util.GetChaincodeResources(r.ChaincodeResources, imageData)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"invalid resource requirements for chaincode ID %s: %w",
+			chaincodeData.ChaincodeID,
+			err,
+		)
+	}
+
+	podOptions, err := r.getPodOptions(ctx, logger, clientset.CoreV1().Secrets(r.KubeNamespace), r.KubeNamespace, chaincodeData, imageData)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobTemplate []byte
+	if r.KubeJobTemplatePath != "" {
+		jobTemplate, err = util.ReadJobTemplate(logger, r.KubeJobTemplatePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return util.ApplyChaincodeDeployment(
+		ctx,
+		logger,
+		clientset.AppsV1().Deployments(r.KubeNamespace),
+		kubeObjectName,
+		r.KubeNamespace,
+		r.KubeServiceAccount,
+		r.KubeNodeRole,
+		r.PeerID,
+		chaincodeData,
+		imageData,
+		resources,
+		podOptions,
+		r.ServiceReplicas,
+		jobTemplate,
+	)
+}
@@ -8,10 +8,20 @@ import (
 	"time"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/metrics"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	typedBatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 type Run struct {
+	PodSettings
+
 	BuildOutputDirectory  string
 	RunMetadataDirectory  string
 	PeerID                string
@@ -20,14 +30,26 @@ type Run struct {
 	KubeNodeRole          string
 	KubeServiceAccount    string
 	KubeNamePrefix        string
+	KubeJobTemplatePath   string
+	SignaturePolicyPath   string
+	ImagePolicy           *util.ImagePolicy
+	ExistingJobPolicy     string
+	VaultConfig           *util.VaultConfig
 	ChaincodeStartTimeout time.Duration
+	ChaincodeResources    apiv1.ResourceRequirements
+	ChaincodeRetryPolicy  util.RetryPolicy
+	StreamChaincodeLogs   bool
+	ChaincodeLogTailLines int64
+	ShutdownGracePeriod   time.Duration
+	MetricsPushgatewayURL string
+	MetricsTextfileDir    string
 }
 
 func (r *Run) Run(ctx context.Context) error {
 	logger := log.New(ctx)
 	logger.Debugln("Running chaincode...")
 
-	imageData, err := util.ReadImageJSON(logger, r.BuildOutputDirectory)
+	imageData, err := util.ReadImageJSON(logger, r.BuildOutputDirectory, r.ImagePolicy)
 	if err != nil {
 		return err
 	}
@@ -37,62 +59,473 @@ func (r *Run) Run(ctx context.Context) error {
 		return err
 	}
 
-	kubeObjectName := util.GetValidRfc1035LabelName(r.KubeNamePrefix, r.PeerID, chaincodeData, util.ObjectNameSuffixLength+1)
+	logger = logger.With(
+		log.ChaincodeIDKey, chaincodeData.ChaincodeID,
+		log.PackageLabelKey, util.NewChaincodePackageID(chaincodeData.ChaincodeID).Label,
+	)
+	tracing.SetAttributes(ctx,
+		tracing.PeerIDKey.String(r.PeerID),
+		tracing.ChaincodeIDKey.String(chaincodeData.ChaincodeID),
+		tracing.PackageLabelKey.String(util.NewChaincodePackageID(chaincodeData.ChaincodeID).Label),
+	)
 
-	clientset, err := util.GetKubeClientset(logger, r.KubeconfigPath)
+	resources, err := util.GetChaincodeResources(r.ChaincodeResources, imageData)
 	if err != nil {
 		return fmt.Errorf(
-			"unable to connect kubernetes client for chaincode ID %s: %w",
+			"invalid resource requirements for chaincode ID %s: %w",
 			chaincodeData.ChaincodeID,
 			err,
 		)
 	}
 
-	secretsClient := clientset.CoreV1().Secrets(r.KubeNamespace)
+	var jobTemplate []byte
+	if r.KubeJobTemplatePath != "" {
+		jobTemplate, err = util.ReadJobTemplate(logger, r.KubeJobTemplatePath)
+		if err != nil {
+			return err
+		}
+	}
 
-	err = util.ApplyChaincodeSecrets(
-		ctx,
-		logger,
-		secretsClient,
-		kubeObjectName,
-		r.KubeNamespace,
-		r.PeerID,
-		chaincodeData,
-	)
+	kubeObjectName := util.GetValidRfc1035LabelName(r.KubeNamePrefix, r.PeerID, chaincodeData, util.ObjectNameSuffixLength+1)
+
+	clientset, err := util.GetKubeClientset(logger, r.KubeconfigPath)
 	if err != nil {
 		return fmt.Errorf(
-			"unable to create kubernetes secret for chaincode ID %s: %w",
+			"unable to connect kubernetes client for chaincode ID %s: %w",
 			chaincodeData.ChaincodeID,
 			err,
 		)
 	}
 
+	secretsClient := clientset.CoreV1().Secrets(r.KubeNamespace)
 	jobsClient := clientset.BatchV1().Jobs(r.KubeNamespace)
+	podsClient := clientset.CoreV1().Pods(r.KubeNamespace)
+	recorder := util.NewChaincodeEventRecorder(logger, clientset.CoreV1().Events(r.KubeNamespace), r.PeerID)
+	credentialStore := r.getCredentialStore(secretsClient)
+
+	ctx, metricsDone := r.exportChaincodeMetrics(ctx, logger, kubeObjectName)
+
+	var job *batchv1.Job
 
-	job, err := util.CreateChaincodeJob(
+	err = credentialStore.DeleteOrphaned(ctx, logger, jobsClient)
+	if err != nil {
+		logger.Warnf("Unable to delete orphaned chaincode credentials: %v", err)
+	}
+
+	defer func() {
+		// Everything must be cleaned up within the shutdown grace period, before
+		// the peer kills the run command
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.ShutdownGracePeriod)
+		defer cancel()
+
+		r.cleanUpChaincode(ctx, shutdownCtx, logger, credentialStore, jobsClient, podsClient, kubeObjectName, job)
+		metricsDone(shutdownCtx)
+	}()
+
+	job, err = r.startChaincodeJob(
 		ctx,
 		logger,
+		secretsClient,
+		credentialStore,
 		jobsClient,
+		podsClient,
+		recorder,
 		kubeObjectName,
-		r.KubeNamespace,
-		r.KubeServiceAccount,
-		r.KubeNodeRole,
-		r.PeerID,
 		chaincodeData,
 		imageData,
+		resources,
+		jobTemplate,
 	)
 	if err != nil {
 		return err
 	}
 
+	logger = logger.With(log.JobNamespaceKey, job.Namespace, log.JobNameKey, job.Name)
+	tracing.SetAttributes(ctx, tracing.NamespaceKey.String(job.Namespace), tracing.JobNameKey.String(job.Name))
+
+	return r.waitForChaincodeJob(ctx, logger, clientset.BatchV1().RESTClient(), jobsClient, podsClient, recorder, job, chaincodeData.ChaincodeID)
+}
+
+// waitForChaincodeJob waits for the chaincode job to terminate, optionally
+// following the chaincode log, and includes diagnostics from the chaincode
+// pods in any error unless the chaincode was stopped.
+func (r *Run) waitForChaincodeJob(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	batchClient cache.Getter,
+	jobsClient typedBatchv1.JobInterface,
+	podsClient v1.PodInterface,
+	recorder record.EventRecorder,
+	job *batchv1.Job,
+	chaincodeID string,
+) error {
 	logger.Printf(
 		"Running chaincode ID %s with kubernetes job %s/%s",
-		chaincodeData.ChaincodeID,
+		chaincodeID,
 		job.Namespace,
 		job.Name,
 	)
 
-	batchClient := clientset.BatchV1().RESTClient()
+	if r.StreamChaincodeLogs {
+		logsDone := followChaincodeLogs(ctx, logger, jobsClient, podsClient, job)
+		defer logsDone()
+	}
+
+	err := util.WaitForChaincodeJob(ctx, logger, batchClient, recorder, job, chaincodeID, r.ChaincodeStartTimeout)
+	if err != nil && ctx.Err() == nil {
+		if diagnostics := util.GetChaincodePodDiagnostics(ctx, logger, podsClient, job, r.ChaincodeLogTailLines); diagnostics != "" {
+			return fmt.Errorf("%w\n%s", err, diagnostics)
+		}
+
+		return err
+	}
+
+	return err
+}
+
+// startChaincodeJob either reattaches to an existing chaincode job, or deletes
+// any stale jobs and creates a new one. If the peer uses TLS, the chaincode
+// secret is applied after stale jobs have been deleted, and the job is made the
+// owner of the secret.
+func (r *Run) startChaincodeJob(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	secretsClient v1.SecretInterface,
+	credentialStore util.CredentialStore,
+	jobsClient typedBatchv1.JobInterface,
+	podsClient v1.PodInterface,
+	recorder record.EventRecorder,
+	kubeObjectName string,
+	chaincodeData *util.ChaincodeJSON,
+	imageData *util.ImageJSON,
+	resources apiv1.ResourceRequirements,
+	jobTemplate []byte,
+) (*batchv1.Job, error) {
+	job, err := util.AdoptExistingChaincodeJob(ctx, logger, jobsClient, kubeObjectName, r.PeerID, r.ExistingJobPolicy, chaincodeData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check for existing kubernetes jobs for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	// Stale jobs have been deleted, so make sure they cannot take the chaincode
+	// credentials with them before the credentials are reused
+	err = r.applyChaincodeCredentials(ctx, logger, credentialStore, kubeObjectName, chaincodeData)
+	if err != nil {
+		return nil, err
+	}
+
+	created := job == nil
+
+	if job != nil {
+		logger.Printf(
+			"Reattaching to existing kubernetes job %s/%s for chaincode ID %s",
+			job.Namespace,
+			job.Name,
+			chaincodeData.ChaincodeID,
+		)
+		recorder.Eventf(job, apiv1.EventTypeNormal, util.EventReasonJobAdopted, "Reattached to existing job for chaincode ID %s", chaincodeData.ChaincodeID)
+	} else {
+		err = verifyImageSignature(ctx, logger, r.SignaturePolicyPath, imageData, chaincodeData.ChaincodeID)
+		if err != nil {
+			return nil, err
+		}
+
+		podOptions, err := r.getPodOptions(ctx, logger, secretsClient, r.KubeNamespace, chaincodeData, imageData)
+		if err != nil {
+			return nil, err
+		}
+
+		podOptions.CredentialStore = credentialStore
+
+		job, err = util.CreateChaincodeJob(
+			ctx,
+			logger,
+			jobsClient,
+			kubeObjectName,
+			r.KubeNamespace,
+			r.KubeServiceAccount,
+			r.KubeNodeRole,
+			r.PeerID,
+			chaincodeData,
+			imageData,
+			resources,
+			podOptions,
+			r.ChaincodeRetryPolicy,
+			jobTemplate,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		recorder.Eventf(job, apiv1.EventTypeNormal, util.EventReasonJobCreated, "Created job for chaincode ID %s", chaincodeData.ChaincodeID)
+	}
+
+	if chaincodeData.TLSEnabled() {
+		recorder.Eventf(job, apiv1.EventTypeNormal, util.EventReasonSecretApplied, "Applied chaincode secret %s", credentialStore.Location(kubeObjectName))
+
+		// Make sure the secret is deleted with the job if the run command exits
+		// without deleting it
+		err = credentialStore.SetOwner(ctx, logger, kubeObjectName, job)
+		if err != nil {
+			if created {
+				if deleteErr := util.DeleteChaincodeJob(ctx, logger, jobsClient, podsClient, job); deleteErr != nil {
+					logger.Warnf("Unable to delete chaincode job: %v", deleteErr)
+				}
+			}
+
+			return nil, fmt.Errorf("unable to set owner of credentials for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+		}
+	}
+
+	return job, nil
+}
+
+// applyChaincodeCredentials stores the peer TLS certificates and client key
+// in the credential store, after removing any owners of credentials stored for
+// earlier jobs. There are no credentials if the peer does not use TLS, since
+// the chaincode connects to the peer using plain text.
+func (r *Run) applyChaincodeCredentials(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	credentialStore util.CredentialStore,
+	name string,
+	chaincodeData *util.ChaincodeJSON,
+) error {
+	if !chaincodeData.TLSEnabled() {
+		logger.Debugf("Peer TLS is not enabled, chaincode ID %s will connect to the peer using plain text", chaincodeData.ChaincodeID)
+
+		return nil
+	}
+
+	err := credentialStore.RemoveOwners(ctx, logger, name)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to store credentials for chaincode ID %s: %w",
+			chaincodeData.ChaincodeID,
+			err,
+		)
+	}
+
+	err = credentialStore.Apply(ctx, logger, name, r.PeerID, chaincodeData)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to store credentials for chaincode ID %s: %w",
+			chaincodeData.ChaincodeID,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// getCredentialStore returns the Vault credential store, if one has been
+// configured, or a credential store which uses Kubernetes secrets.
+func (r *Run) getCredentialStore(secretsClient v1.SecretInterface) util.CredentialStore {
+	if r.VaultConfig != nil {
+		return util.NewVaultCredentialStore(*r.VaultConfig, r.KubeNamespace)
+	}
+
+	return util.NewKubernetesCredentialStore(secretsClient, r.KubeNamespace)
+}
+
+// verifyImageSignature checks the chaincode image signature before running the
+// chaincode, if a signature policy has been configured for the run command.
+func verifyImageSignature(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	policyPath string,
+	imageData *util.ImageJSON,
+	chaincodeID string,
+) error {
+	if policyPath == "" {
+		return nil
+	}
+
+	policy, err := util.ReadSignaturePolicy(logger, policyPath)
+	if err != nil {
+		return err
+	}
+
+	err = util.VerifyImageSignature(ctx, logger, policy, imageData, util.NewChaincodePackageID(chaincodeID).Label)
+	if err != nil {
+		return fmt.Errorf("unable to run chaincode ID %s: %w", chaincodeID, err)
+	}
+
+	return nil
+}
+
+// cleanUpChaincode deletes the chaincode credentials when the run command
+// exits, if the chaincode job no longer needs them. If the run command has been
+// stopped, it first deletes the chaincode job and waits for the chaincode pods
+// to terminate, for at most half of the time remaining before the shutdown
+// deadline. Credentials for a job which is still running are left for the job,
+// which owns them, since a pod which restarts needs them.
+func (r *Run) cleanUpChaincode(
+	ctx context.Context,
+	shutdownCtx context.Context,
+	logger *log.CmdLogger,
+	credentialStore util.CredentialStore,
+	jobsClient typedBatchv1.JobInterface,
+	podsClient v1.PodInterface,
+	secretName string,
+	job *batchv1.Job,
+) {
+	stopped := ctx.Err() != nil
+	if stopped {
+		logger.Printf("Stopping chaincode: %v", context.Cause(ctx))
+	}
+
+	checkJob := job != nil
+
+	if stopped && job != nil {
+		deleteCtx, cancel := newCleanUpStepContext(shutdownCtx)
+		err := util.DeleteChaincodeJob(deleteCtx, logger, jobsClient, podsClient, job)
+
+		cancel()
+
+		if err != nil {
+			logger.Warnf("Unable to delete chaincode job: %v", err)
+		} else {
+			logger.Printf("Deleted chaincode job %s/%s", job.Namespace, job.Name)
+
+			checkJob = false
+		}
+	}
+
+	if checkJob {
+		checkCtx, cancel := newCleanUpStepContext(shutdownCtx)
+		running, err := util.IsChaincodeJobRunning(checkCtx, jobsClient, job)
+
+		cancel()
+
+		if err != nil {
+			logger.Warnf("Unable to check chaincode job status: %v", err)
+		}
+
+		if running {
+			logger.Debugf("Keeping chaincode secret %s for running chaincode job %s/%s", credentialStore.Location(secretName), job.Namespace, job.Name)
 
-	return util.WaitForChaincodeJob(ctx, logger, batchClient, job, chaincodeData.ChaincodeID, r.ChaincodeStartTimeout)
+			return
+		}
+	}
+
+	deleteCtx, cancel := newCleanUpStepContext(shutdownCtx)
+	defer cancel()
+
+	if err := credentialStore.Delete(deleteCtx, logger, secretName); err != nil {
+		logger.Warnf("Unable to delete chaincode secret: %v", err)
+	} else {
+		logger.Debugf("Deleted chaincode secret %s", credentialStore.Location(secretName))
+	}
+}
+
+// newCleanUpStepContext returns a context for one clean up step, which expires
+// after half of the time remaining before the shutdown deadline, leaving the
+// rest for later steps and the final metrics export.
+func newCleanUpStepContext(shutdownCtx context.Context) (context.Context, context.CancelFunc) {
+	const cleanUpStepShare = 2
+
+	deadline, ok := shutdownCtx.Deadline()
+	if !ok {
+		return context.WithCancel(shutdownCtx)
+	}
+
+	return context.WithTimeout(shutdownCtx, time.Until(deadline)/cleanUpStepShare)
+}
+
+// exportChaincodeMetrics returns a new Context which carries the chaincode
+// metrics, if metrics are enabled, and periodically exports them in the
+// background. The returned function stops exporting, after a final export using
+// the provided context, so that the metrics include the reason the chaincode
+// terminated.
+func (r *Run) exportChaincodeMetrics(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	kubeObjectName string,
+) (context.Context, func(context.Context)) {
+	const metricsExportInterval = 30 * time.Second
+
+	if r.MetricsPushgatewayURL == "" && r.MetricsTextfileDir == "" {
+		return ctx, func(context.Context) {}
+	}
+
+	chaincodeMetrics := metrics.New(r.PeerID, kubeObjectName)
+	ctx = metrics.NewContext(ctx, chaincodeMetrics)
+
+	exportCtx, cancelExport := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(metricsExportInterval)
+		defer ticker.Stop()
+
+		r.exportMetrics(exportCtx, logger, chaincodeMetrics)
+
+		for {
+			select {
+			case <-exportCtx.Done():
+				return
+			case <-ticker.C:
+				r.exportMetrics(exportCtx, logger, chaincodeMetrics)
+			}
+		}
+	}()
+
+	return ctx, func(finalCtx context.Context) {
+		cancelExport()
+		<-done
+
+		r.exportMetrics(finalCtx, logger, chaincodeMetrics)
+	}
+}
+
+// exportMetrics pushes the chaincode metrics to the Pushgateway and writes
+// them to the textfile collector directory, if configured. Failing to export
+// metrics does not stop the chaincode.
+func (r *Run) exportMetrics(ctx context.Context, logger *log.CmdLogger, chaincodeMetrics *metrics.Metrics) {
+	if r.MetricsPushgatewayURL != "" {
+		if err := chaincodeMetrics.Push(ctx, r.MetricsPushgatewayURL); err != nil {
+			logger.Warnf("Unable to export chaincode metrics: %v", err)
+		}
+	}
+
+	if r.MetricsTextfileDir != "" {
+		if err := chaincodeMetrics.WriteTextfile(r.MetricsTextfileDir); err != nil {
+			logger.Warnf("Unable to export chaincode metrics: %v", err)
+		}
+	}
+}
+
+// followChaincodeLogs relays the chaincode log in the background and returns a
+// function which waits briefly for the remaining log lines before stopping.
+func followChaincodeLogs(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	jobsClient typedBatchv1.JobInterface,
+	podsClient v1.PodInterface,
+	job *batchv1.Job,
+) func() {
+	const logDrainTimeout = 5 * time.Second
+
+	logsCtx, cancelLogs := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if err := util.FollowChaincodeLogs(logsCtx, logger, jobsClient, podsClient, job); err != nil {
+			logger.Debugf("Stopped following chaincode log for job %s/%s: %v", job.Namespace, job.Name, err)
+		}
+	}()
+
+	return func() {
+		select {
+		case <-done:
+		case <-time.After(logDrainTimeout):
+		}
+
+		cancelLogs()
+		<-done
+	}
 }
@@ -3,15 +3,89 @@
 package cmd
 
 import (
-	"context"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/builder"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
 )
 
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getVerifyImageDigest(logger *log.CmdLogger) (verifyImageDigest bool, ok bool) {
+	verifyImageDigestValue := util.GetOptionalEnv(util.VerifyImageDigestVariable, "false")
+	logger.Debugf("%s=%s", util.VerifyImageDigestVariable, verifyImageDigestValue)
+
+	verifyImageDigest, err := strconv.ParseBool(verifyImageDigestValue)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid boolean value, e.g. true or false: %v", util.VerifyImageDigestVariable, err)
+
+		return false, false
+	}
+
+	return verifyImageDigest, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getResolveImageTags(logger *log.CmdLogger) (resolveImageTags bool, ok bool) {
+	resolveImageTagsValue := util.GetOptionalEnv(util.ResolveImageTagsVariable, "false")
+	logger.Debugf("%s=%s", util.ResolveImageTagsVariable, resolveImageTagsValue)
+
+	resolveImageTags, err := strconv.ParseBool(resolveImageTagsValue)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid boolean value, e.g. true or false: %v", util.ResolveImageTagsVariable, err)
+
+		return false, false
+	}
+
+	return resolveImageTags, true
+}
+
+func getSignaturePolicyPath(logger *log.CmdLogger) string {
+	signaturePolicyPath := util.GetOptionalEnv(util.SignaturePolicyVariable, "")
+	logger.Debugf("%s=%s", util.SignaturePolicyVariable, signaturePolicyPath)
+
+	return signaturePolicyPath
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getImagePolicy(logger *log.CmdLogger) (imagePolicy *util.ImagePolicy, ok bool) {
+	allowlist := util.GetOptionalEnv(util.ImageAllowlistVariable, "")
+	logger.Debugf("%s=%s", util.ImageAllowlistVariable, allowlist)
+
+	denylist := util.GetOptionalEnv(util.ImageDenylistVariable, "")
+	logger.Debugf("%s=%s", util.ImageDenylistVariable, denylist)
+
+	imagePolicy, err := util.NewImagePolicy(splitImageRules(allowlist), splitImageRules(denylist))
+	if err != nil {
+		logger.Errorf(
+			"The %s and %s environment variables must be comma separated lists of glob patterns, or regular expressions with a '%s' prefix: %v",
+			util.ImageAllowlistVariable,
+			util.ImageDenylistVariable,
+			util.RegexImageRulePrefix,
+			err,
+		)
+
+		return nil, false
+	}
+
+	return imagePolicy, true
+}
+
+func splitImageRules(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	rules := strings.Split(value, ",")
+	for i, rule := range rules {
+		rules[i] = strings.TrimSpace(rule)
+	}
+
+	return rules
+}
+
 func Build() {
 	const (
 		expectedArgsLength            = 4
@@ -20,12 +94,13 @@ func Build() {
 		buildOutputDirectoryArg       = 3
 	)
 
-	debug, _ := strconv.ParseBool(util.GetOptionalEnv(util.DebugVariable, "false"))
-	ctx := log.NewCmdContext(context.Background(), debug)
-	logger := log.New(ctx)
+	ctx, logger, ok := newCmdLogger()
+	if !ok {
+		os.Exit(1)
+	}
 
 	if len(os.Args) != expectedArgsLength {
-		logger.Println(
+		logger.Errorln(
 			"Expected CHAINCODE_SOURCE_DIR, CHAINCODE_METADATA_DIR and BUILD_OUTPUT_DIR arguments",
 		)
 
@@ -40,14 +115,39 @@ func Build() {
 	logger.Debugf("Chaincode metadata directory: %s", chaincodeMetadataDirectory)
 	logger.Debugf("Build output directory: %s", buildOutputDirectory)
 
+	verifyImageDigest, ok := getVerifyImageDigest(logger)
+	if !ok {
+		os.Exit(1)
+	}
+
+	imagePolicy, ok := getImagePolicy(logger)
+	if !ok {
+		os.Exit(1)
+	}
+
+	resolveImageTags, ok := getResolveImageTags(logger)
+	if !ok {
+		os.Exit(1)
+	}
+
+	runMode, ok := getRunMode(logger)
+	if !ok {
+		os.Exit(1)
+	}
+
 	build := &builder.Build{
 		ChaincodeSourceDirectory:   chaincodeSourceDirectory,
 		ChaincodeMetadataDirectory: chaincodeMetadataDirectory,
 		BuildOutputDirectory:       buildOutputDirectory,
+		VerifyImageDigest:          verifyImageDigest,
+		SignaturePolicyPath:        getSignaturePolicyPath(logger),
+		ImagePolicy:                imagePolicy,
+		ResolveImageTags:           resolveImageTags,
+		RunMode:                    runMode,
 	}
 
-	if err := build.Run(ctx); err != nil {
-		logger.Printf("Error building chaincode: %+v", err)
+	if err := runTraced(ctx, logger, "build", build.Run); err != nil {
+		logger.WithError(err).Errorf("Error building chaincode: %+v", err)
 
 		os.Exit(1)
 	}
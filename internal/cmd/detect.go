@@ -3,14 +3,10 @@
 package cmd
 
 import (
-	"context"
 	"errors"
 	"os"
-	"strconv"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/builder"
-	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
-	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
 )
 
 func Detect() {
@@ -20,12 +16,13 @@ func Detect() {
 		chaincodeMetadataDirectoryArg = 2
 	)
 
-	debug, _ := strconv.ParseBool(util.GetOptionalEnv(util.DebugVariable, "false"))
-	ctx := log.NewCmdContext(context.Background(), debug)
-	logger := log.New(ctx)
+	ctx, logger, ok := newCmdLogger()
+	if !ok {
+		os.Exit(1)
+	}
 
 	if len(os.Args) != expectedArgsLength {
-		logger.Println("Expected CHAINCODE_SOURCE_DIR and CHAINCODE_METADATA_DIR arguments")
+		logger.Errorln("Expected CHAINCODE_SOURCE_DIR and CHAINCODE_METADATA_DIR arguments")
 
 		os.Exit(1)
 	}
@@ -41,10 +38,10 @@ func Detect() {
 		ChaincodeMetadataDirectory: chaincodeMetadataDirectory,
 	}
 
-	if err := detect.Run(ctx); err != nil {
+	if err := runTraced(ctx, logger, "detect", detect.Run); err != nil {
 		if !errors.Is(err, builder.ErrUnsupportedChaincodeType) {
 			// don't spam the peer log if it's just chaincode we don't recognise
-			logger.Printf("Error detecting chaincode: %+v", err)
+			logger.WithError(err).Errorf("Error detecting chaincode: %+v", err)
 		}
 
 		os.Exit(1)
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+)
+
+// newCmdLogger returns a context and logger for a builder command, using the
+// log level and format configured by the FABRIC_K8S_BUILDER_LOG_LEVEL and
+// FABRIC_K8S_BUILDER_LOG_FORMAT environment variables. If FABRIC_K8S_BUILDER_LOG_LEVEL
+// is not set, FABRIC_K8S_BUILDER_DEBUG selects the debug level. Invalid log
+// levels and formats are logged using the default log settings.
+//
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func newCmdLogger() (ctx context.Context, logger *log.CmdLogger, ok bool) {
+	debugValue := util.GetOptionalEnv(util.DebugVariable, "false")
+	debug, debugErr := strconv.ParseBool(debugValue)
+
+	logLevelValue := util.GetOptionalEnv(util.LogLevelVariable, "")
+	logLevel, logLevelOK := log.ParseLevel(logLevelValue)
+
+	logFormat := util.GetOptionalEnv(util.LogFormatVariable, log.FormatText)
+
+	ctx = log.WithFormat(log.NewCmdContext(context.Background(), debug), logFormat)
+	if logLevelValue != "" && logLevelOK {
+		ctx = log.WithLevel(ctx, logLevel)
+	}
+
+	logger = log.New(ctx)
+
+	if !log.IsValidFormat(logFormat) {
+		logger.Errorf(
+			"The %s environment variable must be '%s', '%s', or '%s'",
+			util.LogFormatVariable,
+			log.FormatText,
+			log.FormatJSON,
+			log.FormatLogfmt,
+		)
+
+		return ctx, logger, false
+	}
+
+	if logLevelValue != "" && !logLevelOK {
+		logger.Errorf(
+			"The %s environment variable must be '%s', '%s', '%s', '%s', or '%s'",
+			util.LogLevelVariable,
+			log.LevelNameError,
+			log.LevelNameWarn,
+			log.LevelNameInfo,
+			log.LevelNameDebug,
+			log.LevelNameTrace,
+		)
+
+		return ctx, logger, false
+	}
+
+	if debugErr != nil {
+		logger.Warnf("Ignoring the %s environment variable, which must be a valid boolean value, e.g. true or false: %v", util.DebugVariable, debugErr)
+	}
+
+	logger.Debugf("%s=%s", util.DebugVariable, debugValue)
+	logger.Debugf("%s=%s", util.LogLevelVariable, logLevelValue)
+	logger.Debugf("%s=%s", util.LogFormatVariable, logFormat)
+
+	return ctx, logger, true
+}
@@ -3,7 +3,6 @@
 package cmd
 
 import (
-	"context"
 	"os"
 	"strconv"
 
@@ -12,6 +11,60 @@ import (
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
 )
 
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getRunMode(logger *log.CmdLogger) (runMode string, ok bool) {
+	runMode = util.GetOptionalEnv(util.RunModeVariable, util.DefaultRunMode)
+	logger.Debugf("%s=%s", util.RunModeVariable, runMode)
+
+	if runMode != util.RunModeJob && runMode != util.RunModeService {
+		logger.Errorf("The %s environment variable must be either '%s' or '%s'", util.RunModeVariable, util.RunModeJob, util.RunModeService)
+
+		return runMode, false
+	}
+
+	return runMode, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getServiceReplicas(logger *log.CmdLogger) (serviceReplicas int32, ok bool) {
+	replicas := util.GetOptionalEnv(util.ServiceReplicasVariable, util.DefaultServiceReplicas)
+	logger.Debugf("%s=%s", util.ServiceReplicasVariable, replicas)
+
+	parsedReplicas, err := strconv.ParseInt(replicas, 10, 32)
+	if err != nil || parsedReplicas < 1 {
+		logger.Errorf("The %s environment variable must be a positive integer", util.ServiceReplicasVariable)
+
+		return 0, false
+	}
+
+	return int32(parsedReplicas), true
+}
+
+// getPeerTLSDisabled checks that the peer does not use TLS, since the
+// connection.json file for chaincode run as a service does not configure TLS.
+func getPeerTLSDisabled(logger *log.CmdLogger) bool {
+	tlsEnabled, err := util.GetRequiredEnv(util.PeerTLSEnabledVariable)
+	if err != nil {
+		logger.Errorf("Expected %s environment variable in %s run mode\n", util.PeerTLSEnabledVariable, util.RunModeService)
+
+		return false
+	}
+
+	logger.Debugf("%s=%s", util.PeerTLSEnabledVariable, tlsEnabled)
+
+	if parsedTLSEnabled, err := strconv.ParseBool(tlsEnabled); err != nil || parsedTLSEnabled {
+		logger.Errorf(
+			"The %s environment variable must be 'false' in %s run mode, since the connection to the chaincode service does not use TLS",
+			util.PeerTLSEnabledVariable,
+			util.RunModeService,
+		)
+
+		return false
+	}
+
+	return true
+}
+
 func Release() {
 	const (
 		expectedArgsLength        = 3
@@ -19,12 +72,13 @@ func Release() {
 		releaseOutputDirectoryArg = 2
 	)
 
-	debug, _ := strconv.ParseBool(util.GetOptionalEnv(util.DebugVariable, "false"))
-	ctx := log.NewCmdContext(context.Background(), debug)
-	logger := log.New(ctx)
+	ctx, logger, ok := newCmdLogger()
+	if !ok {
+		os.Exit(1)
+	}
 
 	if len(os.Args) != expectedArgsLength {
-		logger.Println("Expected BUILD_OUTPUT_DIR and RELEASE_OUTPUT_DIR arguments")
+		logger.Errorln("Expected BUILD_OUTPUT_DIR and RELEASE_OUTPUT_DIR arguments")
 
 		os.Exit(1)
 	}
@@ -35,16 +89,84 @@ func Release() {
 	logger.Debugf("Build output directory: %s", buildOutputDirectory)
 	logger.Debugf("Release output directory: %s", releaseOutputDirectory)
 
+	runMode, ok := getRunMode(logger)
+	if !ok {
+		os.Exit(1)
+	}
+
 	release := &builder.Release{
 		BuildOutputDirectory:   buildOutputDirectory,
 		ReleaseOutputDirectory: releaseOutputDirectory,
+		RunMode:                runMode,
 	}
 
-	if err := release.Run(ctx); err != nil {
-		logger.Printf("Error releasing chaincode: %+v", err)
+	if runMode == util.RunModeService {
+		if ok := configureServiceRelease(logger, release); !ok {
+			os.Exit(1)
+		}
+	}
+
+	if err := runTraced(ctx, logger, "release", release.Run); err != nil {
+		logger.WithError(err).Errorf("Error releasing chaincode: %+v", err)
 
 		os.Exit(1)
 	}
 
 	os.Exit(0)
 }
+
+// configureServiceRelease adds the kubernetes configuration required to run
+// chaincode as a service to the release.
+func configureServiceRelease(logger *log.CmdLogger, release *builder.Release) bool {
+	//nolint:varnamelen // using the ok bool convention to indicate errors
+	var ok bool
+
+	release.PeerID, ok = getPeerID(logger)
+	if !ok {
+		return false
+	}
+
+	if ok := getPeerTLSDisabled(logger); !ok {
+		return false
+	}
+
+	release.KubeconfigPath = getKubeconfigPath(logger)
+	release.KubeNamespace = getKubeNamespace(logger)
+
+	release.KubeNodeRole, ok = getKubeNodeRole(logger)
+	if !ok {
+		return false
+	}
+
+	release.KubeServiceAccount = getKubeServiceAccount(logger)
+
+	release.KubeNamePrefix, ok = getKubeNamePrefix(logger)
+	if !ok {
+		return false
+	}
+
+	release.KubeJobTemplatePath = getKubeJobTemplatePath(logger)
+
+	if ok := configurePodSettings(logger, &release.PodSettings); !ok {
+		return false
+	}
+
+	release.SignaturePolicyPath, ok = getRunSignaturePolicyPath(logger)
+	if !ok {
+		return false
+	}
+
+	release.ImagePolicy, ok = getImagePolicy(logger)
+	if !ok {
+		return false
+	}
+
+	release.ChaincodeResources, ok = getChaincodeResources(logger)
+	if !ok {
+		return false
+	}
+
+	release.ServiceReplicas, ok = getServiceReplicas(logger)
+
+	return ok
+}
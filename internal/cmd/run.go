@@ -3,13 +3,19 @@
 package cmd
 
 import (
-	"context"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/builder"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
@@ -18,7 +24,7 @@ import (
 func getPeerID(logger *log.CmdLogger) (peerID string, ok bool) {
 	peerID, err := util.GetRequiredEnv(util.PeerIDVariable)
 	if err != nil {
-		logger.Printf("Expected %s environment variable\n", util.PeerIDVariable)
+		logger.Errorf("Expected %s environment variable\n", util.PeerIDVariable)
 
 		return peerID, false
 	}
@@ -44,12 +50,12 @@ func getKubeNamespace(logger *log.CmdLogger) string {
 
 		kubeNamespace, err = util.GetKubeNamespace()
 		if err != nil {
-			logger.Debugf("Error getting namespace: %+v\n", err)
+			logger.Warnf("Unable to get the peer namespace, using the %s namespace: %v", util.DefaultNamespace, err)
 
 			kubeNamespace = util.DefaultNamespace
 		}
 
-		logger.Debugf("Using namespace: %s\n", kubeNamespace)
+		logger.Debugf("Using namespace: %s", kubeNamespace)
 	}
 
 	return kubeNamespace
@@ -62,7 +68,7 @@ func getKubeNodeRole(logger *log.CmdLogger) (kubeNodeRole string, ok bool) {
 
 	// TODO: are valid taint values the same?!
 	if msgs := validation.IsValidLabelValue(kubeNodeRole); len(msgs) > 0 {
-		logger.Printf("The %s environment variable must be a valid Kubernetes label value: %s", util.ChaincodeNodeRoleVariable, msgs[0])
+		logger.Errorf("The %s environment variable must be a valid Kubernetes label value: %s", util.ChaincodeNodeRoleVariable, msgs[0])
 
 		return kubeNodeRole, false
 	}
@@ -85,13 +91,13 @@ func getKubeNamePrefix(logger *log.CmdLogger) (kubeNamePrefix string, ok bool) {
 	logger.Debugf("%s=%s", util.ObjectNamePrefixVariable, kubeNamePrefix)
 
 	if len(kubeNamePrefix) > maximumKubeNamePrefixLength {
-		logger.Printf("The %s environment variable must be a maximum of 30 characters", util.ObjectNamePrefixVariable)
+		logger.Errorf("The %s environment variable must be a maximum of 30 characters", util.ObjectNamePrefixVariable)
 
 		return kubeNamePrefix, false
 	}
 
 	if msgs := apivalidation.NameIsDNS1035Label(kubeNamePrefix, true); len(msgs) > 0 {
-		logger.Printf("The %s environment variable must be a valid DNS-1035 label: %s", util.ObjectNamePrefixVariable, msgs[0])
+		logger.Errorf("The %s environment variable must be a valid DNS-1035 label: %s", util.ObjectNamePrefixVariable, msgs[0])
 
 		return kubeNamePrefix, false
 	}
@@ -99,6 +105,146 @@ func getKubeNamePrefix(logger *log.CmdLogger) (kubeNamePrefix string, ok bool) {
 	return kubeNamePrefix, true
 }
 
+func getKubeJobTemplatePath(logger *log.CmdLogger) string {
+	kubeJobTemplatePath := util.GetOptionalEnv(util.JobTemplateVariable, "")
+	logger.Debugf("%s=%s", util.JobTemplateVariable, kubeJobTemplatePath)
+
+	return kubeJobTemplatePath
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getImagePullSecrets(logger *log.CmdLogger) (imagePullSecrets []string, ok bool) {
+	imagePullSecretsValue := util.GetOptionalEnv(util.ImagePullSecretsVariable, "")
+	logger.Debugf("%s=%s", util.ImagePullSecretsVariable, imagePullSecretsValue)
+
+	if imagePullSecretsValue == "" {
+		return nil, true
+	}
+
+	for _, imagePullSecret := range strings.Split(imagePullSecretsValue, ",") {
+		imagePullSecret = strings.TrimSpace(imagePullSecret)
+
+		if msgs := apivalidation.NameIsDNSSubdomain(imagePullSecret, false); len(msgs) > 0 {
+			logger.Errorf(
+				"The %s environment variable must be a comma separated list of Kubernetes secret names, e.g. registry-a,registry-b: %s",
+				util.ImagePullSecretsVariable,
+				msgs[0],
+			)
+
+			return nil, false
+		}
+
+		imagePullSecrets = append(imagePullSecrets, imagePullSecret)
+	}
+
+	return imagePullSecrets, true
+}
+
+// getRunSignaturePolicyPath returns the signature policy path if chaincode
+// image signatures should be verified before running chaincode, as well as
+// when chaincode is installed.
+//
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getRunSignaturePolicyPath(logger *log.CmdLogger) (signaturePolicyPath string, ok bool) {
+	verifyOnRunValue := util.GetOptionalEnv(util.VerifySignaturesOnRunVariable, "false")
+	logger.Debugf("%s=%s", util.VerifySignaturesOnRunVariable, verifyOnRunValue)
+
+	verifyOnRun, err := strconv.ParseBool(verifyOnRunValue)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid boolean value, e.g. true or false: %v", util.VerifySignaturesOnRunVariable, err)
+
+		return "", false
+	}
+
+	if !verifyOnRun {
+		return "", true
+	}
+
+	signaturePolicyPath = getSignaturePolicyPath(logger)
+	if signaturePolicyPath == "" {
+		logger.Errorf("The %s environment variable requires the %s environment variable to be set", util.VerifySignaturesOnRunVariable, util.SignaturePolicyVariable)
+
+		return "", false
+	}
+
+	return signaturePolicyPath, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getExistingJobPolicy(logger *log.CmdLogger) (existingJobPolicy string, ok bool) {
+	existingJobPolicy = util.GetOptionalEnv(util.ExistingJobPolicyVariable, util.DefaultExistingJobPolicy)
+	logger.Debugf("%s=%s", util.ExistingJobPolicyVariable, existingJobPolicy)
+
+	switch existingJobPolicy {
+	case util.ExistingJobPolicyAdopt, util.ExistingJobPolicyReplace, util.ExistingJobPolicyIgnore:
+		return existingJobPolicy, true
+	default:
+		logger.Errorf(
+			"The %s environment variable must be '%s', '%s', or '%s'",
+			util.ExistingJobPolicyVariable,
+			util.ExistingJobPolicyAdopt,
+			util.ExistingJobPolicyReplace,
+			util.ExistingJobPolicyIgnore,
+		)
+
+		return existingJobPolicy, false
+	}
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getVaultConfig(logger *log.CmdLogger) (vaultConfig *util.VaultConfig, ok bool) {
+	credentialStore := util.GetOptionalEnv(util.CredentialStoreVariable, util.DefaultCredentialStore)
+	logger.Debugf("%s=%s", util.CredentialStoreVariable, credentialStore)
+
+	if credentialStore == util.CredentialStoreKubernetes {
+		return nil, true
+	}
+
+	if credentialStore != util.CredentialStoreVault {
+		logger.Errorf(
+			"The %s environment variable must be either '%s' or '%s'",
+			util.CredentialStoreVariable,
+			util.CredentialStoreKubernetes,
+			util.CredentialStoreVault,
+		)
+
+		return nil, false
+	}
+
+	vaultConfig = &util.VaultConfig{
+		Address:   util.GetOptionalEnv(util.VaultAddressVariable, ""),
+		TokenFile: util.GetOptionalEnv(util.VaultTokenFileVariable, ""),
+		KVMount:   util.GetOptionalEnv(util.VaultKVMountVariable, util.DefaultVaultKVMount),
+		Role:      util.GetOptionalEnv(util.VaultRoleVariable, ""),
+	}
+	logger.Debugf("%s=%s", util.VaultAddressVariable, vaultConfig.Address)
+	logger.Debugf("%s=%s", util.VaultTokenFileVariable, vaultConfig.TokenFile)
+	logger.Debugf("%s=%s", util.VaultKVMountVariable, vaultConfig.KVMount)
+	logger.Debugf("%s=%s", util.VaultRoleVariable, vaultConfig.Role)
+
+	parsedURL, err := url.Parse(vaultConfig.Address)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		logger.Errorf("The %s environment variable must be a valid http or https URL, e.g. https://vault:8200", util.VaultAddressVariable)
+
+		return nil, false
+	}
+
+	if vaultConfig.TokenFile == "" || vaultConfig.KVMount == "" || vaultConfig.Role == "" {
+		logger.Errorf(
+			"The %s, %s, and %s environment variables must be set when the %s environment variable is '%s'",
+			util.VaultTokenFileVariable,
+			util.VaultKVMountVariable,
+			util.VaultRoleVariable,
+			util.CredentialStoreVariable,
+			util.CredentialStoreVault,
+		)
+
+		return nil, false
+	}
+
+	return vaultConfig, true
+}
+
 //nolint:nonamedreturns // using the ok bool convention to indicate errors
 func getChaincodeStartTimeout(logger *log.CmdLogger) (chaincodeStartTimeoutDuration time.Duration, ok bool) {
 	chaincodeStartTimeout := util.GetOptionalEnv(util.ChaincodeStartTimeoutVariable, util.DefaultStartTimeout)
@@ -106,7 +252,7 @@ func getChaincodeStartTimeout(logger *log.CmdLogger) (chaincodeStartTimeoutDurat
 
 	chaincodeStartTimeoutDuration, err := time.ParseDuration(chaincodeStartTimeout)
 	if err != nil {
-		logger.Printf("The %s environment variable must be a valid Go duration string, e.g. 3m40s: %v", util.ChaincodeStartTimeoutVariable, err)
+		logger.Errorf("The %s environment variable must be a valid Go duration string, e.g. 3m40s: %v", util.ChaincodeStartTimeoutVariable, err)
 
 		return 0 * time.Minute, false
 	}
@@ -114,6 +260,497 @@ func getChaincodeStartTimeout(logger *log.CmdLogger) (chaincodeStartTimeoutDurat
 	return chaincodeStartTimeoutDuration, true
 }
 
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getShutdownGracePeriod(logger *log.CmdLogger) (shutdownGracePeriodDuration time.Duration, ok bool) {
+	shutdownGracePeriod := util.GetOptionalEnv(util.ShutdownGracePeriodVariable, util.DefaultShutdownGracePeriod)
+	logger.Debugf("%s=%s", util.ShutdownGracePeriodVariable, shutdownGracePeriod)
+
+	shutdownGracePeriodDuration, err := time.ParseDuration(shutdownGracePeriod)
+	if err != nil || shutdownGracePeriodDuration <= 0 {
+		logger.Errorf("The %s environment variable must be a positive Go duration string, e.g. 4s", util.ShutdownGracePeriodVariable)
+
+		return 0 * time.Second, false
+	}
+
+	return shutdownGracePeriodDuration, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getResourceQuantity(logger *log.CmdLogger, key string) (quantity *resource.Quantity, ok bool) {
+	value := util.GetOptionalEnv(key, "")
+	logger.Debugf("%s=%s", key, value)
+
+	if value == "" {
+		return nil, true
+	}
+
+	parsedQuantity, err := util.ParseResourceQuantity(value)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid, non-negative Kubernetes quantity, e.g. 500m or 128Mi: %v", key, err)
+
+		return nil, false
+	}
+
+	return &parsedQuantity, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getChaincodeResources(logger *log.CmdLogger) (resources apiv1.ResourceRequirements, ok bool) {
+	resourceVariables := []struct {
+		key  string
+		name apiv1.ResourceName
+		list *apiv1.ResourceList
+	}{
+		{util.ChaincodeCPURequestVariable, apiv1.ResourceCPU, &resources.Requests},
+		{util.ChaincodeCPULimitVariable, apiv1.ResourceCPU, &resources.Limits},
+		{util.ChaincodeMemoryRequestVariable, apiv1.ResourceMemory, &resources.Requests},
+		{util.ChaincodeMemoryLimitVariable, apiv1.ResourceMemory, &resources.Limits},
+	}
+
+	for _, variable := range resourceVariables {
+		quantity, valid := getResourceQuantity(logger, variable.key)
+		if !valid {
+			return resources, false
+		}
+
+		if quantity == nil {
+			continue
+		}
+
+		if *variable.list == nil {
+			*variable.list = apiv1.ResourceList{}
+		}
+
+		(*variable.list)[variable.name] = *quantity
+	}
+
+	for name, request := range resources.Requests {
+		if limit, found := resources.Limits[name]; found && request.Cmp(limit) > 0 {
+			logger.Errorf("The %s request %s must be less than or equal to the %s limit %s", name, request.String(), name, limit.String())
+
+			return resources, false
+		}
+	}
+
+	return resources, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getFailJobExitCodes(logger *log.CmdLogger) (exitCodes []int32, ok bool) {
+	exitCodesValue := util.GetOptionalEnv(util.FailJobExitCodesVariable, "")
+	logger.Debugf("%s=%s", util.FailJobExitCodesVariable, exitCodesValue)
+
+	if exitCodesValue == "" {
+		return nil, true
+	}
+
+	for _, exitCodeValue := range strings.Split(exitCodesValue, ",") {
+		exitCode, err := strconv.ParseInt(strings.TrimSpace(exitCodeValue), 10, 32)
+		if err != nil || exitCode == 0 {
+			logger.Errorf("The %s environment variable must be a comma separated list of non-zero exit codes, e.g. 1,42", util.FailJobExitCodesVariable)
+
+			return nil, false
+		}
+
+		exitCodes = append(exitCodes, int32(exitCode))
+	}
+
+	return exitCodes, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getChaincodeRetryPolicy(logger *log.CmdLogger) (retryPolicy util.RetryPolicy, ok bool) {
+	restartPolicy := util.GetOptionalEnv(util.RestartPolicyVariable, util.DefaultRestartPolicy)
+	logger.Debugf("%s=%s", util.RestartPolicyVariable, restartPolicy)
+
+	if restartPolicy != string(apiv1.RestartPolicyNever) && restartPolicy != string(apiv1.RestartPolicyOnFailure) {
+		logger.Errorf("The %s environment variable must be either '%s' or '%s'", util.RestartPolicyVariable, apiv1.RestartPolicyNever, apiv1.RestartPolicyOnFailure)
+
+		return retryPolicy, false
+	}
+
+	retryPolicy.RestartPolicy = apiv1.RestartPolicy(restartPolicy)
+
+	backoffLimitValue := util.GetOptionalEnv(util.BackoffLimitVariable, util.DefaultBackoffLimit)
+	logger.Debugf("%s=%s", util.BackoffLimitVariable, backoffLimitValue)
+
+	backoffLimit, err := strconv.ParseInt(backoffLimitValue, 10, 32)
+	if err != nil || backoffLimit < 0 {
+		logger.Errorf("The %s environment variable must be zero or a positive integer", util.BackoffLimitVariable)
+
+		return retryPolicy, false
+	}
+
+	retryPolicy.BackoffLimit = int32(backoffLimit)
+
+	ignoreDisruptionsValue := util.GetOptionalEnv(util.IgnorePodDisruptionsVariable, "false")
+	logger.Debugf("%s=%s", util.IgnorePodDisruptionsVariable, ignoreDisruptionsValue)
+
+	retryPolicy.IgnoreDisruptions, err = strconv.ParseBool(ignoreDisruptionsValue)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid boolean value, e.g. true or false: %v", util.IgnorePodDisruptionsVariable, err)
+
+		return retryPolicy, false
+	}
+
+	retryPolicy.FailJobExitCodes, ok = getFailJobExitCodes(logger)
+	if !ok {
+		return retryPolicy, false
+	}
+
+	if (retryPolicy.IgnoreDisruptions || len(retryPolicy.FailJobExitCodes) > 0) && retryPolicy.RestartPolicy != apiv1.RestartPolicyNever {
+		logger.Errorf(
+			"The %s and %s environment variables can only be used with the '%s' restart policy",
+			util.IgnorePodDisruptionsVariable,
+			util.FailJobExitCodesVariable,
+			apiv1.RestartPolicyNever,
+		)
+
+		return retryPolicy, false
+	}
+
+	return retryPolicy, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getBoolEnv(logger *log.CmdLogger, variable string) (value bool, ok bool) {
+	boolValue := util.GetOptionalEnv(variable, "false")
+	logger.Debugf("%s=%s", variable, boolValue)
+
+	value, err := strconv.ParseBool(boolValue)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid boolean value, e.g. true or false: %v", variable, err)
+
+		return false, false
+	}
+
+	return value, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getRunAsUser(logger *log.CmdLogger) (runAsUser *int64, ok bool) {
+	runAsUserValue := util.GetOptionalEnv(util.RunAsUserVariable, "")
+	logger.Debugf("%s=%s", util.RunAsUserVariable, runAsUserValue)
+
+	if runAsUserValue == "" {
+		return nil, true
+	}
+
+	userID, err := strconv.ParseInt(runAsUserValue, 10, 64)
+	if err != nil || userID < 0 {
+		logger.Errorf("The %s environment variable must be zero or a positive integer", util.RunAsUserVariable)
+
+		return nil, false
+	}
+
+	return &userID, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getAddCapabilities(logger *log.CmdLogger) (capabilities []apiv1.Capability, ok bool) {
+	capabilitiesValue := util.GetOptionalEnv(util.AddCapabilitiesVariable, "")
+	logger.Debugf("%s=%s", util.AddCapabilitiesVariable, capabilitiesValue)
+
+	if capabilitiesValue == "" {
+		return nil, true
+	}
+
+	for _, capability := range strings.Split(capabilitiesValue, ",") {
+		capability = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(capability)), "CAP_")
+
+		if !isCapabilityName(capability) {
+			logger.Errorf("The %s environment variable must be a comma separated list of capabilities, e.g. NET_BIND_SERVICE", util.AddCapabilitiesVariable)
+
+			return nil, false
+		}
+
+		capabilities = append(capabilities, apiv1.Capability(capability))
+	}
+
+	return capabilities, true
+}
+
+// isCapabilityName returns true if the value looks like a Linux capability
+// name, without the CAP_ prefix. Adding ALL capabilities is not allowed.
+func isCapabilityName(value string) bool {
+	if value == "" || value == "ALL" {
+		return false
+	}
+
+	return strings.IndexFunc(value, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_'
+	}) == -1
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getChaincodeSecurityPolicy(logger *log.CmdLogger) (securityPolicy util.SecurityPolicy, ok bool) {
+	securityPolicy.AllowRunAsRoot, ok = getBoolEnv(logger, util.AllowRunAsRootVariable)
+	if !ok {
+		return securityPolicy, false
+	}
+
+	securityPolicy.RunAsUser, ok = getRunAsUser(logger)
+	if !ok {
+		return securityPolicy, false
+	}
+
+	if securityPolicy.RunAsUser != nil && *securityPolicy.RunAsUser == 0 && !securityPolicy.AllowRunAsRoot {
+		logger.Errorf("The %s environment variable cannot be 0 unless %s is true", util.RunAsUserVariable, util.AllowRunAsRootVariable)
+
+		return securityPolicy, false
+	}
+
+	securityPolicy.AllowPrivilegeEscalation, ok = getBoolEnv(logger, util.AllowPrivilegeEscalationVariable)
+	if !ok {
+		return securityPolicy, false
+	}
+
+	securityPolicy.AddCapabilities, ok = getAddCapabilities(logger)
+	if !ok {
+		return securityPolicy, false
+	}
+
+	seccompProfile := util.GetOptionalEnv(util.SeccompProfileVariable, string(apiv1.SeccompProfileTypeRuntimeDefault))
+	logger.Debugf("%s=%s", util.SeccompProfileVariable, seccompProfile)
+
+	if seccompProfile != string(apiv1.SeccompProfileTypeRuntimeDefault) && seccompProfile != string(apiv1.SeccompProfileTypeUnconfined) {
+		logger.Errorf(
+			"The %s environment variable must be either '%s' or '%s'",
+			util.SeccompProfileVariable,
+			apiv1.SeccompProfileTypeRuntimeDefault,
+			apiv1.SeccompProfileTypeUnconfined,
+		)
+
+		return securityPolicy, false
+	}
+
+	securityPolicy.SeccompProfile = apiv1.SeccompProfileType(seccompProfile)
+
+	securityPolicy.WritableRootFilesystem, ok = getBoolEnv(logger, util.WritableRootFilesystemVariable)
+
+	return securityPolicy, ok
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getProbe(logger *log.CmdLogger, variable string) (probe *apiv1.Probe, ok bool) {
+	probeValue := util.GetOptionalEnv(variable, "")
+	logger.Debugf("%s=%s", variable, probeValue)
+
+	if probeValue == "" {
+		return nil, true
+	}
+
+	probe, err := util.ParseProbe(probeValue)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid probe, e.g. exec:/bin/healthcheck, tcp:9999, or grpc:9999: %v", variable, err)
+
+		return nil, false
+	}
+
+	return probe, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getChaincodeProbes(logger *log.CmdLogger) (probes util.ChaincodeProbes, ok bool) {
+	probes.Readiness, ok = getProbe(logger, util.ReadinessProbeVariable)
+	if !ok {
+		return probes, false
+	}
+
+	probes.Liveness, ok = getProbe(logger, util.LivenessProbeVariable)
+
+	return probes, ok
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getChaincodeConfig(logger *log.CmdLogger) (config util.ChaincodeConfig, ok bool) {
+	envValue := util.GetOptionalEnv(util.ChaincodeEnvVariable, "")
+	logger.Debugf("%s=%s", util.ChaincodeEnvVariable, envValue)
+
+	var err error
+
+	if envValue != "" {
+		config.Env, err = util.ParseEnv(envValue)
+		if err != nil {
+			logger.Errorf("The %s environment variable must be a comma separated list of NAME=value pairs, e.g. LOG_LEVEL=debug: %v", util.ChaincodeEnvVariable, err)
+
+			return config, false
+		}
+	}
+
+	envFromValue := util.GetOptionalEnv(util.ChaincodeEnvFromVariable, "")
+	logger.Debugf("%s=%s", util.ChaincodeEnvFromVariable, envFromValue)
+
+	if envFromValue != "" {
+		config.EnvFrom, err = util.ParseConfigSources(envFromValue)
+		if err != nil {
+			logger.Errorf(
+				"The %s environment variable must be a comma separated list of ConfigMaps and Secrets, e.g. configmap:chaincode-env,secret:chaincode-credentials: %v",
+				util.ChaincodeEnvFromVariable,
+				err,
+			)
+
+			return config, false
+		}
+	}
+
+	configFilesValue := util.GetOptionalEnv(util.ChaincodeConfigFilesVariable, "")
+	logger.Debugf("%s=%s", util.ChaincodeConfigFilesVariable, configFilesValue)
+
+	if configFilesValue != "" {
+		config.ConfigFiles, err = util.ParseConfigFiles(configFilesValue)
+		if err != nil {
+			logger.Errorf(
+				"The %s environment variable must be a comma separated list of ConfigMaps and Secrets with mount paths, e.g. configmap:chaincode-config:/etc/chaincode: %v",
+				util.ChaincodeConfigFilesVariable,
+				err,
+			)
+
+			return config, false
+		}
+	}
+
+	return config, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getChaincodeConfigAllowlist(logger *log.CmdLogger) (allowlist []util.ConfigSource, ok bool) {
+	allowlistValue := util.GetOptionalEnv(util.ChaincodeConfigAllowlistVariable, "")
+	logger.Debugf("%s=%s", util.ChaincodeConfigAllowlistVariable, allowlistValue)
+
+	if allowlistValue == "" {
+		return nil, true
+	}
+
+	allowlist, err := util.ParseConfigAllowlist(allowlistValue)
+	if err != nil {
+		logger.Errorf(
+			"The %s environment variable must be a comma separated list of ConfigMap and Secret name patterns, e.g. configmap:fabcar-*,secret:fabcar-*: %v",
+			util.ChaincodeConfigAllowlistVariable,
+			err,
+		)
+
+		return nil, false
+	}
+
+	return allowlist, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getSchedulingPolicy(logger *log.CmdLogger) (policy util.SchedulingPolicy, ok bool) {
+	nodeSelectorValue := util.GetOptionalEnv(util.NodeSelectorVariable, "")
+	logger.Debugf("%s=%s", util.NodeSelectorVariable, nodeSelectorValue)
+
+	var err error
+
+	if nodeSelectorValue != "" {
+		policy.NodeRequirements, err = util.ParseNodeSelector(nodeSelectorValue)
+		if err != nil {
+			logger.Errorf("The %s environment variable must be a valid node label selector, e.g. disktype=ssd: %v", util.NodeSelectorVariable, err)
+
+			return policy, false
+		}
+	}
+
+	tolerationsValue := util.GetOptionalEnv(util.TolerationsVariable, "")
+	logger.Debugf("%s=%s", util.TolerationsVariable, tolerationsValue)
+
+	if tolerationsValue != "" {
+		policy.Tolerations, err = util.ParseTolerations(tolerationsValue)
+		if err != nil {
+			logger.Errorf("The %s environment variable must be a comma separated list of taints, e.g. dedicated=chaincode:NoSchedule: %v", util.TolerationsVariable, err)
+
+			return policy, false
+		}
+	}
+
+	topologySpreadValue := util.GetOptionalEnv(util.TopologySpreadVariable, "")
+	logger.Debugf("%s=%s", util.TopologySpreadVariable, topologySpreadValue)
+
+	if topologySpreadValue != "" {
+		policy.TopologySpread, err = util.ParseTopologySpread(topologySpreadValue)
+		if err != nil {
+			logger.Errorf(
+				"The %s environment variable must be a comma separated list of topology keys, e.g. topology.kubernetes.io/zone,kubernetes.io/hostname:DoNotSchedule: %v",
+				util.TopologySpreadVariable,
+				err,
+			)
+
+			return policy, false
+		}
+	}
+
+	podAntiAffinityValue := util.GetOptionalEnv(util.PodAntiAffinityVariable, "")
+	logger.Debugf("%s=%s", util.PodAntiAffinityVariable, podAntiAffinityValue)
+
+	if podAntiAffinityValue != "" {
+		policy.PodAntiAffinity, err = util.ParsePodAntiAffinity(podAntiAffinityValue)
+		if err != nil {
+			logger.Errorf("The %s environment variable must be preferred or required, with an optional topology key, e.g. required:kubernetes.io/hostname: %v", util.PodAntiAffinityVariable, err)
+
+			return policy, false
+		}
+	}
+
+	return policy, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getStreamChaincodeLogs(logger *log.CmdLogger) (streamLogs bool, ok bool) {
+	streamLogsValue := util.GetOptionalEnv(util.StreamLogsVariable, "false")
+	logger.Debugf("%s=%s", util.StreamLogsVariable, streamLogsValue)
+
+	streamLogs, err := strconv.ParseBool(streamLogsValue)
+	if err != nil {
+		logger.Errorf("The %s environment variable must be a valid boolean value, e.g. true or false: %v", util.StreamLogsVariable, err)
+
+		return false, false
+	}
+
+	return streamLogs, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getChaincodeLogTailLines(logger *log.CmdLogger) (tailLines int64, ok bool) {
+	tailLinesValue := util.GetOptionalEnv(util.LogTailLinesVariable, util.DefaultLogTailLines)
+	logger.Debugf("%s=%s", util.LogTailLinesVariable, tailLinesValue)
+
+	tailLines, err := strconv.ParseInt(tailLinesValue, 10, 64)
+	if err != nil || tailLines < 0 {
+		logger.Errorf("The %s environment variable must be zero or a positive integer", util.LogTailLinesVariable)
+
+		return 0, false
+	}
+
+	return tailLines, true
+}
+
+//nolint:nonamedreturns // using the ok bool convention to indicate errors
+func getMetricsPushgatewayURL(logger *log.CmdLogger) (pushgatewayURL string, ok bool) {
+	pushgatewayURL = util.GetOptionalEnv(util.MetricsPushgatewayURLVariable, "")
+	logger.Debugf("%s=%s", util.MetricsPushgatewayURLVariable, pushgatewayURL)
+
+	if pushgatewayURL == "" {
+		return pushgatewayURL, true
+	}
+
+	parsedURL, err := url.Parse(pushgatewayURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		logger.Errorf("The %s environment variable must be a valid http or https URL, e.g. http://pushgateway:9091", util.MetricsPushgatewayURLVariable)
+
+		return pushgatewayURL, false
+	}
+
+	return pushgatewayURL, true
+}
+
+func getMetricsTextfileDirectory(logger *log.CmdLogger) string {
+	textfileDirectory := util.GetOptionalEnv(util.MetricsTextfileDirVariable, "")
+	logger.Debugf("%s=%s", util.MetricsTextfileDirVariable, textfileDirectory)
+
+	return textfileDirectory
+}
+
 func Run() {
 	const (
 		expectedArgsLength      = 3
@@ -121,12 +758,13 @@ func Run() {
 		runMetadataDirectoryArg = 2
 	)
 
-	debug := util.GetOptionalEnv(util.DebugVariable, "false")
-	ctx := log.NewCmdContext(context.Background(), debug == "true")
-	logger := log.New(ctx)
+	ctx, logger, ok := newCmdLogger()
+	if !ok {
+		os.Exit(1)
+	}
 
 	if len(os.Args) != expectedArgsLength {
-		logger.Println("Expected BUILD_OUTPUT_DIR and RUN_METADATA_DIR arguments")
+		logger.Errorln("Expected BUILD_OUTPUT_DIR and RUN_METADATA_DIR arguments")
 
 		os.Exit(1)
 	}
@@ -137,51 +775,165 @@ func Run() {
 	logger.Debugf("Build output directory: %s", buildOutputDirectory)
 	logger.Debugf("Run metadata directory: %s", runMetadataDirectory)
 
+	run := &builder.Run{
+		BuildOutputDirectory: buildOutputDirectory,
+		RunMetadataDirectory: runMetadataDirectory,
+	}
+
+	if ok := configureChaincodeJob(logger, run); !ok {
+		os.Exit(1)
+	}
+
+	if ok := configureChaincodeRun(logger, run); !ok {
+		os.Exit(1)
+	}
+
+	// The peer sends SIGTERM when it stops the chaincode, followed by SIGKILL
+	// if the run command has not exited within five seconds
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	err := runTraced(signalCtx, logger, "run", run.Run)
+
+	stop()
+
+	if err != nil {
+		logger.WithError(err).Errorf("Error running chaincode: %+v", err)
+
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+// configureChaincodeJob adds the kubernetes configuration required to create
+// chaincode jobs to the run.
+func configureChaincodeJob(logger *log.CmdLogger, run *builder.Run) bool {
 	//nolint:varnamelen // using the ok bool convention to indicate errors
 	var ok bool
 
-	peerID, ok := getPeerID(logger)
+	run.PeerID, ok = getPeerID(logger)
 	if !ok {
-		os.Exit(1)
+		return false
 	}
 
-	kubeconfigPath := getKubeconfigPath(logger)
-	kubeNamespace := getKubeNamespace(logger)
+	run.KubeconfigPath = getKubeconfigPath(logger)
+	run.KubeNamespace = getKubeNamespace(logger)
 
-	kubeNodeRole, ok := getKubeNodeRole(logger)
+	run.KubeNodeRole, ok = getKubeNodeRole(logger)
 	if !ok {
-		os.Exit(1)
+		return false
 	}
 
-	kubeServiceAccount := getKubeServiceAccount(logger)
+	run.KubeServiceAccount = getKubeServiceAccount(logger)
 
-	kubeNamePrefix, ok := getKubeNamePrefix(logger)
+	run.KubeNamePrefix, ok = getKubeNamePrefix(logger)
 	if !ok {
-		os.Exit(1)
+		return false
+	}
+
+	run.KubeJobTemplatePath = getKubeJobTemplatePath(logger)
+
+	if ok := configurePodSettings(logger, &run.PodSettings); !ok {
+		return false
 	}
 
-	chaincodeStartTimeout, ok := getChaincodeStartTimeout(logger)
+	run.SignaturePolicyPath, ok = getRunSignaturePolicyPath(logger)
 	if !ok {
-		os.Exit(1)
+		return false
 	}
 
-	run := &builder.Run{
-		BuildOutputDirectory:  buildOutputDirectory,
-		RunMetadataDirectory:  runMetadataDirectory,
-		PeerID:                peerID,
-		KubeconfigPath:        kubeconfigPath,
-		KubeNamespace:         kubeNamespace,
-		KubeNodeRole:          kubeNodeRole,
-		KubeServiceAccount:    kubeServiceAccount,
-		KubeNamePrefix:        kubeNamePrefix,
-		ChaincodeStartTimeout: chaincodeStartTimeout,
+	run.ImagePolicy, ok = getImagePolicy(logger)
+	if !ok {
+		return false
 	}
 
-	if err := run.Run(ctx); err != nil {
-		logger.Printf("Error running chaincode: %+v", err)
+	run.ExistingJobPolicy, ok = getExistingJobPolicy(logger)
+	if !ok {
+		return false
+	}
 
-		os.Exit(1)
+	run.VaultConfig, ok = getVaultConfig(logger)
+	if !ok {
+		return false
 	}
 
-	os.Exit(0)
+	run.ChaincodeResources, ok = getChaincodeResources(logger)
+	if !ok {
+		return false
+	}
+
+	run.ChaincodeRetryPolicy, ok = getChaincodeRetryPolicy(logger)
+
+	return ok
+}
+
+// configurePodSettings adds the chaincode pod configuration, which is used
+// whether chaincode is run as a job or as a service.
+func configurePodSettings(logger *log.CmdLogger, settings *builder.PodSettings) bool {
+	//nolint:varnamelen // using the ok bool convention to indicate errors
+	var ok bool
+
+	settings.ImagePullSecrets, ok = getImagePullSecrets(logger)
+	if !ok {
+		return false
+	}
+
+	settings.ChaincodeSecurityPolicy, ok = getChaincodeSecurityPolicy(logger)
+	if !ok {
+		return false
+	}
+
+	settings.ChaincodeProbes, ok = getChaincodeProbes(logger)
+	if !ok {
+		return false
+	}
+
+	settings.ChaincodeConfig, ok = getChaincodeConfig(logger)
+	if !ok {
+		return false
+	}
+
+	settings.ChaincodeConfigAllowlist, ok = getChaincodeConfigAllowlist(logger)
+	if !ok {
+		return false
+	}
+
+	settings.SchedulingPolicy, ok = getSchedulingPolicy(logger)
+
+	return ok
+}
+
+// configureChaincodeRun adds the configuration for starting, stopping, and
+// monitoring chaincode to the run.
+func configureChaincodeRun(logger *log.CmdLogger, run *builder.Run) bool {
+	//nolint:varnamelen // using the ok bool convention to indicate errors
+	var ok bool
+
+	run.ChaincodeStartTimeout, ok = getChaincodeStartTimeout(logger)
+	if !ok {
+		return false
+	}
+
+	run.ShutdownGracePeriod, ok = getShutdownGracePeriod(logger)
+	if !ok {
+		return false
+	}
+
+	run.StreamChaincodeLogs, ok = getStreamChaincodeLogs(logger)
+	if !ok {
+		return false
+	}
+
+	run.ChaincodeLogTailLines, ok = getChaincodeLogTailLines(logger)
+	if !ok {
+		return false
+	}
+
+	run.MetricsPushgatewayURL, ok = getMetricsPushgatewayURL(logger)
+	if !ok {
+		return false
+	}
+
+	run.MetricsTextfileDir = getMetricsTextfileDirectory(logger)
+
+	return true
 }
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+// tracingShutdownTimeout limits how long commands wait to export spans, since
+// the peer kills the run command shortly after stopping chaincode.
+const tracingShutdownTimeout = time.Second
+
+// runTraced runs a builder command in a span, which is exported using OTLP if
+// an endpoint is configured using the standard OpenTelemetry environment
+// variables. If the TRACEPARENT environment variable is set, the span is part
+// of the existing trace.
+func runTraced(ctx context.Context, logger *log.CmdLogger, command string, run func(context.Context) error) error {
+	if !tracing.IsEnabled() {
+		return run(ctx)
+	}
+
+	provider, err := tracing.NewOTLPTracerProvider(ctx, command)
+	if err != nil {
+		logger.Warnf("Unable to export traces: %v", err)
+
+		return run(ctx)
+	}
+
+	otel.SetTracerProvider(provider)
+
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tracingShutdownTimeout)
+		defer cancel()
+
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			logger.Warnf("Unable to export traces: %v", err)
+		}
+	}()
+
+	ctx, span := tracing.Start(tracing.ContextFromEnv(ctx), command, tracing.CommandKey.String(command))
+	err = run(ctx)
+	tracing.End(span, err)
+
+	return err
+}
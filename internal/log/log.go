@@ -5,66 +5,179 @@ package log
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
+	"sync"
 )
 
 type logContextKeyType string
 
 const (
-	cmdKey   logContextKeyType = "cmd"
-	debugKey logContextKeyType = "debug"
-	pidKey   logContextKeyType = "pid"
+	cmdKey    logContextKeyType = "cmd"
+	debugKey  logContextKeyType = "debug"
+	formatKey logContextKeyType = "format"
+	levelKey  logContextKeyType = "level"
+	pidKey    logContextKeyType = "pid"
 )
 
-type minimalLogger interface {
-	Print(v ...interface{})
-	Printf(format string, v ...interface{})
-	Println(v ...interface{})
-}
+// LevelTrace is the log level for detailed messages, such as Kubernetes
+// object definitions and watch events, which are more verbose than debug.
+const LevelTrace = slog.Level(-8)
+
+// Log level names.
+const (
+	LevelNameError = "error"
+	LevelNameWarn  = "warn"
+	LevelNameInfo  = "info"
+	LevelNameDebug = "debug"
+	LevelNameTrace = "trace"
+)
+
+// Log formats.
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// Structured log field names.
+const (
+	CommandKey      = "command"
+	PidKey          = "pid"
+	ChaincodeIDKey  = "chaincode_id"
+	PackageLabelKey = "package_label"
+	JobNamespaceKey = "job_namespace"
+	JobNameKey      = "job_name"
+	ErrorKey        = "error"
+)
 
 type CmdLogger struct {
-	infoLogger  minimalLogger
-	debugLogger minimalLogger
+	logger *slog.Logger
 }
 
-type nilLogger struct{}
+// textHandler is a slog handler which writes the original plain text log
+// format, "cmd [pid]: message", without any additional fields. Warning, debug,
+// and trace messages include the level in the prefix, e.g.
+// "cmd [pid] DEBUG: message".
+type textHandler struct {
+	mu    *sync.Mutex
+	out   io.Writer
+	level slog.Level
+	cmd   string
+	pid   int
+}
 
-func (nl *nilLogger) Print(_ ...interface{}) {
-	// do nothing
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
 }
 
-func (nl *nilLogger) Printf(_ string, _ ...interface{}) {
-	// do nothing
+//nolint:gocritic // the slog.Handler interface passes records by value
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var prefix string
+
+	switch {
+	case record.Level >= slog.LevelError, record.Level == slog.LevelInfo:
+		prefix = fmt.Sprintf("%s [%v]: ", h.cmd, h.pid)
+	default:
+		prefix = fmt.Sprintf("%s [%v] %s: ", h.cmd, h.pid, levelLabel(record.Level))
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := io.WriteString(h.out, prefix+record.Message+"\n")
+
+	return err
 }
 
-func (nl *nilLogger) Println(_ ...interface{}) {
-	// do nothing
+func (h *textHandler) WithAttrs(_ []slog.Attr) slog.Handler {
+	return h
 }
 
-const (
-	flags = 0
-)
+func (h *textHandler) WithGroup(_ string) slog.Handler {
+	return h
+}
+
+// levelLabel returns the upper case label for a log level, including the
+// custom trace level.
+func levelLabel(level slog.Level) string {
+	if level <= LevelTrace {
+		return "TRACE"
+	}
+
+	return level.String()
+}
+
+// replaceLevel uses the trace label for the custom trace level in structured
+// log output.
+func replaceLevel(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) == 0 && attr.Key == slog.LevelKey {
+		if level, ok := attr.Value.Any().(slog.Level); ok {
+			return slog.String(slog.LevelKey, levelLabel(level))
+		}
+	}
+
+	return attr
+}
+
+// ParseLevel returns the log level for the provided level name.
+func ParseLevel(name string) (slog.Level, bool) {
+	switch name {
+	case LevelNameError:
+		return slog.LevelError, true
+	case LevelNameWarn:
+		return slog.LevelWarn, true
+	case LevelNameInfo:
+		return slog.LevelInfo, true
+	case LevelNameDebug:
+		return slog.LevelDebug, true
+	case LevelNameTrace:
+		return LevelTrace, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
+// IsValidFormat returns true if the provided log format is supported.
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatText, FormatJSON, FormatLogfmt:
+		return true
+	default:
+		return false
+	}
+}
 
 func New(ctx context.Context) *CmdLogger {
 	cmd, _ := CmdFromContext(ctx)
 	pid, _ := PidFromContext(ctx)
 
-	infoPrefix := fmt.Sprintf("%s [%v]: ", cmd, pid)
-	infoLogger := log.New(os.Stderr, infoPrefix, flags)
-
-	var debugLogger minimalLogger
-
-	if DebugFromContext(ctx) {
-		debugPrefix := fmt.Sprintf("%s [%v] DEBUG: ", cmd, pid)
-		debugLogger = log.New(os.Stderr, debugPrefix, flags)
-	} else {
-		debugLogger = &nilLogger{}
+	level := LevelFromContext(ctx)
+	options := &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevel}
+
+	var handler slog.Handler
+
+	switch FormatFromContext(ctx) {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, options).
+			WithAttrs([]slog.Attr{slog.String(CommandKey, cmd), slog.Int(PidKey, pid)})
+	case FormatLogfmt:
+		handler = slog.NewTextHandler(os.Stderr, options).
+			WithAttrs([]slog.Attr{slog.String(CommandKey, cmd), slog.Int(PidKey, pid)})
+	default:
+		handler = &textHandler{
+			mu:    &sync.Mutex{},
+			out:   os.Stderr,
+			level: level,
+			cmd:   cmd,
+			pid:   pid,
+		}
 	}
 
 	cl := &CmdLogger{
-		infoLogger:  infoLogger,
-		debugLogger: debugLogger,
+		logger: slog.New(handler),
 	}
 
 	return cl
@@ -84,6 +197,17 @@ func NewCmdContext(ctx context.Context, debug bool) context.Context {
 	return cmdContext
 }
 
+// WithFormat returns a new Context with the provided log format.
+func WithFormat(ctx context.Context, format string) context.Context {
+	return context.WithValue(ctx, formatKey, format)
+}
+
+// WithLevel returns a new Context with the provided log level, which takes
+// precedence over the debug value.
+func WithLevel(ctx context.Context, level slog.Level) context.Context {
+	return context.WithValue(ctx, levelKey, level)
+}
+
 // CmdFromContext returns the program name value from the provided Context.
 func CmdFromContext(ctx context.Context) (string, bool) {
 	cmd, ok := ctx.Value(cmdKey).(string)
@@ -107,26 +231,132 @@ func DebugFromContext(ctx context.Context) bool {
 	return false
 }
 
+// LevelFromContext returns the log level from the provided Context, which
+// defaults to debug if debug is enabled, or info otherwise.
+func LevelFromContext(ctx context.Context) slog.Level {
+	if l, ok := ctx.Value(levelKey).(slog.Level); ok {
+		return l
+	}
+
+	if DebugFromContext(ctx) {
+		return slog.LevelDebug
+	}
+
+	return slog.LevelInfo
+}
+
+// FormatFromContext returns the log format from the provided Context, which
+// defaults to text.
+func FormatFromContext(ctx context.Context) string {
+	if f, ok := ctx.Value(formatKey).(string); ok && IsValidFormat(f) {
+		return f
+	}
+
+	return FormatText
+}
+
+// With returns a logger which includes the provided key value pairs as fields
+// in structured log output. Fields are not included in text log output.
+func (cl *CmdLogger) With(args ...any) *CmdLogger {
+	return &CmdLogger{logger: cl.logger.With(args...)}
+}
+
+// WithError returns a logger which includes the provided error as a field in
+// structured log output.
+func (cl *CmdLogger) WithError(err error) *CmdLogger {
+	return cl.With(ErrorKey, err)
+}
+
+func (cl *CmdLogger) log(level slog.Level, msg string) {
+	cl.logger.Log(context.Background(), level, strings.TrimSuffix(msg, "\n"))
+}
+
+// TraceEnabled returns true if trace messages will be logged, so that callers
+// can avoid preparing expensive trace messages.
+func (cl *CmdLogger) TraceEnabled() bool {
+	return cl.logger.Enabled(context.Background(), LevelTrace)
+}
+
+func (cl *CmdLogger) Error(v ...interface{}) {
+	cl.log(slog.LevelError, fmt.Sprint(v...))
+}
+
+func (cl *CmdLogger) Errorf(format string, v ...interface{}) {
+	cl.log(slog.LevelError, fmt.Sprintf(format, v...))
+}
+
+func (cl *CmdLogger) Errorln(v ...interface{}) {
+	cl.log(slog.LevelError, fmt.Sprintln(v...))
+}
+
+func (cl *CmdLogger) Warn(v ...interface{}) {
+	cl.log(slog.LevelWarn, fmt.Sprint(v...))
+}
+
+func (cl *CmdLogger) Warnf(format string, v ...interface{}) {
+	cl.log(slog.LevelWarn, fmt.Sprintf(format, v...))
+}
+
+func (cl *CmdLogger) Warnln(v ...interface{}) {
+	cl.log(slog.LevelWarn, fmt.Sprintln(v...))
+}
+
 func (cl *CmdLogger) Print(v ...interface{}) {
-	cl.infoLogger.Print(v...)
+	cl.log(slog.LevelInfo, fmt.Sprint(v...))
 }
 
 func (cl *CmdLogger) Printf(format string, v ...interface{}) {
-	cl.infoLogger.Printf(format, v...)
+	cl.log(slog.LevelInfo, fmt.Sprintf(format, v...))
 }
 
 func (cl *CmdLogger) Println(v ...interface{}) {
-	cl.infoLogger.Println(v...)
+	cl.log(slog.LevelInfo, fmt.Sprintln(v...))
 }
 
 func (cl *CmdLogger) Debug(v ...interface{}) {
-	cl.debugLogger.Print(v...)
+	if !cl.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	cl.log(slog.LevelDebug, fmt.Sprint(v...))
 }
 
 func (cl *CmdLogger) Debugf(format string, v ...interface{}) {
-	cl.debugLogger.Printf(format, v...)
+	if !cl.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	cl.log(slog.LevelDebug, fmt.Sprintf(format, v...))
 }
 
 func (cl *CmdLogger) Debugln(v ...interface{}) {
-	cl.debugLogger.Println(v...)
+	if !cl.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	cl.log(slog.LevelDebug, fmt.Sprintln(v...))
+}
+
+func (cl *CmdLogger) Trace(v ...interface{}) {
+	if !cl.TraceEnabled() {
+		return
+	}
+
+	cl.log(LevelTrace, fmt.Sprint(v...))
+}
+
+func (cl *CmdLogger) Tracef(format string, v ...interface{}) {
+	if !cl.TraceEnabled() {
+		return
+	}
+
+	cl.log(LevelTrace, fmt.Sprintf(format, v...))
+}
+
+func (cl *CmdLogger) Traceln(v ...interface{}) {
+	if !cl.TraceEnabled() {
+		return
+	}
+
+	cl.log(LevelTrace, fmt.Sprintln(v...))
 }
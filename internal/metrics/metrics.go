@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+type metricsContextKeyType string
+
+const (
+	metricsKey metricsContextKeyType = "metrics"
+
+	namespace = "fabric_builder_k8s"
+
+	// JobName is the Pushgateway job name for k8s builder metrics.
+	JobName = "fabric-builder-k8s"
+
+	peerLabel      = "peer"
+	chaincodeLabel = "chaincode"
+	resultLabel    = "result"
+	reasonLabel    = "reason"
+
+	// Results.
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Metrics records k8s builder activity for a single chaincode. Exported
+// metrics are labelled with the peer ID and chaincode object name, since each
+// builder command is a separate short lived process. All methods can be called
+// on a nil Metrics, in which case nothing is recorded.
+type Metrics struct {
+	peerID        string
+	chaincodeName string
+
+	// pushRegistry is used with the Pushgateway, which adds the peer and
+	// chaincode labels from the grouping key, while textfileRegistry includes
+	// them in every metric.
+	pushRegistry     *prometheus.Registry
+	textfileRegistry *prometheus.Registry
+
+	jobCreations        *prometheus.CounterVec
+	jobStartDuration    *prometheus.HistogramVec
+	jobTerminations     *prometheus.CounterVec
+	jobRunning          prometheus.Gauge
+	secretApplyDuration *prometheus.HistogramVec
+}
+
+// New returns a new Metrics for the provided peer ID and chaincode object name.
+func New(peerID, chaincodeName string) *Metrics {
+	metrics := &Metrics{
+		peerID:           peerID,
+		chaincodeName:    chaincodeName,
+		pushRegistry:     prometheus.NewRegistry(),
+		textfileRegistry: prometheus.NewRegistry(),
+		jobCreations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chaincode_job_creations_total",
+			Help:      "Number of chaincode jobs created, by result.",
+		}, []string{resultLabel}),
+		jobStartDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "chaincode_job_start_duration_seconds",
+			Help:      "Time waiting for chaincode jobs to become ready, by result.",
+			Buckets:   []float64{1, 2, 5, 10, 20, 30, 60, 120, 180, 300},
+		}, []string{resultLabel}),
+		jobTerminations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chaincode_job_terminations_total",
+			Help:      "Number of chaincode jobs which terminated, by reason.",
+		}, []string{reasonLabel}),
+		jobRunning: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "chaincode_job_running",
+			Help:      "Whether the chaincode job is running.",
+		}),
+		secretApplyDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "chaincode_secret_apply_duration_seconds",
+			Help:      "Time taken to apply chaincode secrets, by result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{resultLabel}),
+	}
+
+	collectors := []prometheus.Collector{
+		metrics.jobCreations,
+		metrics.jobStartDuration,
+		metrics.jobTerminations,
+		metrics.jobRunning,
+		metrics.secretApplyDuration,
+	}
+
+	metrics.pushRegistry.MustRegister(collectors...)
+	prometheus.WrapRegistererWith(prometheus.Labels{
+		peerLabel:      peerID,
+		chaincodeLabel: chaincodeName,
+	}, metrics.textfileRegistry).MustRegister(collectors...)
+
+	return metrics
+}
+
+// NewContext returns a new Context which carries the provided Metrics.
+func NewContext(ctx context.Context, metrics *Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey, metrics)
+}
+
+// FromContext returns the Metrics from the provided Context, or nil if there
+// are none.
+func FromContext(ctx context.Context) *Metrics {
+	if m, ok := ctx.Value(metricsKey).(*Metrics); ok {
+		return m
+	}
+
+	return nil
+}
+
+// JobCreated records a chaincode job creation attempt.
+func (m *Metrics) JobCreated(result string) {
+	if m == nil {
+		return
+	}
+
+	m.jobCreations.WithLabelValues(result).Inc()
+}
+
+// JobStarted records how long it took for a chaincode job to become ready,
+// or to fail to start.
+func (m *Metrics) JobStarted(result string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.jobStartDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+// JobRunning records whether the chaincode job is running.
+func (m *Metrics) JobRunning(running bool) {
+	if m == nil {
+		return
+	}
+
+	if running {
+		m.jobRunning.Set(1)
+	} else {
+		m.jobRunning.Set(0)
+	}
+}
+
+// JobTerminated records the reason a chaincode job terminated.
+func (m *Metrics) JobTerminated(reason string) {
+	if m == nil {
+		return
+	}
+
+	m.jobTerminations.WithLabelValues(reason).Inc()
+}
+
+// SecretApplied records how long it took to apply chaincode secrets.
+func (m *Metrics) SecretApplied(result string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.secretApplyDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+// Push pushes the metrics to a Pushgateway compatible endpoint, replacing
+// any metrics previously pushed for the same peer and chaincode.
+func (m *Metrics) Push(ctx context.Context, pushgatewayURL string) error {
+	err := push.New(pushgatewayURL, JobName).
+		Gatherer(m.pushRegistry).
+		Grouping(peerLabel, m.peerID).
+		Grouping(chaincodeLabel, m.chaincodeName).
+		PushContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to push metrics to %s: %w", pushgatewayURL, err)
+	}
+
+	return nil
+}
+
+// WriteTextfile writes the metrics to a file in the provided textfile
+// collector directory, replacing any metrics previously written for the same
+// chaincode.
+func (m *Metrics) WriteTextfile(textfileDirectory string) error {
+	if _, err := os.Stat(textfileDirectory); err != nil {
+		return fmt.Errorf("unable to write metrics to %s: %w", textfileDirectory, err)
+	}
+
+	filename := filepath.Join(textfileDirectory, JobName+"-"+m.chaincodeName+".prom")
+
+	if err := prometheus.WriteToTextfile(filename, m.textfileRegistry); err != nil {
+		return fmt.Errorf("unable to write metrics to %s: %w", filename, err)
+	}
+
+	return nil
+}
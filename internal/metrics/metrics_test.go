@@ -0,0 +1,113 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	var chaincodeMetrics *metrics.Metrics
+
+	BeforeEach(func() {
+		chaincodeMetrics = metrics.New("CongaOrgPeer0", "hlfcc-fabcar-s6pwkq6bepi2e")
+		chaincodeMetrics.JobCreated(metrics.ResultSuccess)
+		chaincodeMetrics.SecretApplied(metrics.ResultSuccess, 50*time.Millisecond)
+		chaincodeMetrics.JobStarted(metrics.ResultSuccess, 5*time.Second)
+		chaincodeMetrics.JobRunning(true)
+		chaincodeMetrics.JobTerminated("BackoffLimitExceeded")
+	})
+
+	It("should carry metrics in a context", func() {
+		ctx := metrics.NewContext(context.Background(), chaincodeMetrics)
+		Expect(metrics.FromContext(ctx)).To(BeIdenticalTo(chaincodeMetrics))
+	})
+
+	It("should not record metrics if there are none in the context", func() {
+		ctx := context.Background()
+		Expect(metrics.FromContext(ctx)).To(BeNil())
+
+		Expect(func() {
+			metrics.FromContext(ctx).JobCreated(metrics.ResultError)
+			metrics.FromContext(ctx).JobRunning(false)
+		}).NotTo(Panic())
+	})
+
+	Describe("Push", func() {
+		var (
+			server      *httptest.Server
+			mu          sync.Mutex
+			requestPath string
+			requestBody []byte
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				requestPath = r.URL.Path
+				requestBody = body
+
+				w.WriteHeader(http.StatusOK)
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("should push metrics grouped by peer and chaincode", func() {
+			err := chaincodeMetrics.Push(context.Background(), server.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			Expect(requestPath).To(Equal("/metrics/job/fabric-builder-k8s/peer/CongaOrgPeer0/chaincode/hlfcc-fabcar-s6pwkq6bepi2e"))
+			Expect(requestBody).NotTo(BeEmpty())
+		})
+
+		It("should return an error if the push fails", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			err := chaincodeMetrics.Push(context.Background(), server.URL)
+			Expect(err).To(MatchError(ContainSubstring("unable to push metrics to " + server.URL)))
+		})
+	})
+
+	Describe("WriteTextfile", func() {
+		It("should write metrics to the textfile collector directory", func() {
+			textfileDirectory := GinkgoT().TempDir()
+
+			err := chaincodeMetrics.WriteTextfile(textfileDirectory)
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := os.ReadFile(filepath.Join(textfileDirectory, "fabric-builder-k8s-hlfcc-fabcar-s6pwkq6bepi2e.prom"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(SatisfyAll(
+				ContainSubstring(`fabric_builder_k8s_chaincode_job_creations_total{chaincode="hlfcc-fabcar-s6pwkq6bepi2e",peer="CongaOrgPeer0",result="success"} 1`),
+				ContainSubstring(`fabric_builder_k8s_chaincode_job_start_duration_seconds_count{chaincode="hlfcc-fabcar-s6pwkq6bepi2e",peer="CongaOrgPeer0",result="success"} 1`),
+				ContainSubstring(`fabric_builder_k8s_chaincode_job_terminations_total{chaincode="hlfcc-fabcar-s6pwkq6bepi2e",peer="CongaOrgPeer0",reason="BackoffLimitExceeded"} 1`),
+				ContainSubstring(`fabric_builder_k8s_chaincode_job_running{chaincode="hlfcc-fabcar-s6pwkq6bepi2e",peer="CongaOrgPeer0"} 1`),
+				ContainSubstring(`fabric_builder_k8s_chaincode_secret_apply_duration_seconds_count{chaincode="hlfcc-fabcar-s6pwkq6bepi2e",peer="CongaOrgPeer0",result="success"} 1`),
+			))
+		})
+
+		It("should return an error if the textfile collector directory does not exist", func() {
+			err := chaincodeMetrics.WriteTextfile(filepath.Join(GinkgoT().TempDir(), "missing"))
+			Expect(err).To(MatchError(ContainSubstring("unable to write metrics to")))
+		})
+	})
+})
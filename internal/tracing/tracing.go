@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracerName is the name of the k8s builder OpenTelemetry tracer.
+	TracerName = "github.com/hyperledger-labs/fabric-builder-k8s"
+
+	serviceName = "fabric-builder-k8s"
+
+	// Standard OpenTelemetry environment variables which enable tracing.
+	OTLPEndpointVariable       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	OTLPTracesEndpointVariable = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+
+	// Environment variables which carry W3C trace context between processes.
+	TraceparentVariable = "TRACEPARENT"
+	TracestateVariable  = "TRACESTATE"
+
+	traceparentKey = "traceparent"
+	tracestateKey  = "tracestate"
+)
+
+// Span attribute keys.
+const (
+	CommandKey      = attribute.Key("fabric_builder_k8s.command")
+	PeerIDKey       = attribute.Key("fabric.peer.id")
+	ChaincodeIDKey  = attribute.Key("fabric.chaincode.id")
+	PackageLabelKey = attribute.Key("fabric.chaincode.label")
+	NamespaceKey    = attribute.Key("k8s.namespace.name")
+	JobNameKey      = attribute.Key("k8s.job.name")
+	ObjectNameKey   = attribute.Key("k8s.object.name")
+	ImageKey        = attribute.Key("container.image.name")
+)
+
+// IsEnabled returns true if an OTLP endpoint has been configured using the
+// standard OpenTelemetry environment variables.
+func IsEnabled() bool {
+	return os.Getenv(OTLPEndpointVariable) != "" || os.Getenv(OTLPTracesEndpointVariable) != ""
+}
+
+// NewTracerProvider returns a tracer provider which exports spans for the
+// provided builder command using the provided span processor options, e.g.
+// sdktrace.WithBatcher for an OTLP exporter.
+func NewTracerProvider(command string, options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	commandResource := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		CommandKey.String(command),
+	)
+
+	builderResource, err := resource.Merge(resource.Default(), commandResource)
+	if err != nil {
+		builderResource = commandResource
+	}
+
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(builderResource)}, options...)...)
+}
+
+// NewOTLPTracerProvider returns a tracer provider which exports spans using
+// OTLP over HTTP, configured using the standard OpenTelemetry environment
+// variables.
+func NewOTLPTracerProvider(ctx context.Context, command string) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create OTLP trace exporter: %w", err)
+	}
+
+	return NewTracerProvider(command, sdktrace.WithBatcher(exporter)), nil
+}
+
+// ContextFromEnv returns a new Context with the trace context from the
+// TRACEPARENT and TRACESTATE environment variables, if they are set, so that
+// builder commands can be part of an existing trace.
+func ContextFromEnv(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{
+		traceparentKey: os.Getenv(TraceparentVariable),
+		tracestateKey:  os.Getenv(TracestateVariable),
+	}
+
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
+
+// Start starts a new span using the k8s builder tracer.
+//
+//nolint:ireturn // spans are only available as an interface
+func Start(ctx context.Context, spanName string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, spanName, trace.WithAttributes(attributes...))
+}
+
+// End ends the span, recording the error if there was one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// SetAttributes adds attributes to the current span, for example once the
+// chaincode package ID is known.
+func SetAttributes(ctx context.Context, attributes ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attributes...)
+}
+
+// GetTraceContext returns the W3C trace context for the current span, with
+// the environment variable names as keys, or nil if there is no valid span.
+func GetTraceContext(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	traceContext := make(map[string]string)
+
+	for _, key := range carrier.Keys() {
+		if value := carrier.Get(key); value != "" {
+			traceContext[strings.ToUpper(key)] = value
+		}
+	}
+
+	return traceContext
+}
@@ -0,0 +1,82 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("Tracing", func() {
+	var exporter *tracetest.InMemoryExporter
+
+	BeforeEach(func() {
+		exporter = tracetest.NewInMemoryExporter()
+		provider := tracing.NewTracerProvider("run", sdktrace.WithSyncer(exporter))
+
+		previousProvider := otel.GetTracerProvider()
+		otel.SetTracerProvider(provider)
+		DeferCleanup(func() {
+			otel.SetTracerProvider(previousProvider)
+		})
+	})
+
+	It("should record spans with attributes", func() {
+		ctx, span := tracing.Start(context.Background(), "run", tracing.PeerIDKey.String("CongaOrgPeer0"))
+		tracing.SetAttributes(ctx, tracing.JobNameKey.String("hlfcc-fabcar-s6pwkq6bepi2e-abcde"))
+		tracing.End(span, nil)
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name).To(Equal("run"))
+		Expect(spans[0].Attributes).To(ContainElements(
+			tracing.PeerIDKey.String("CongaOrgPeer0"),
+			tracing.JobNameKey.String("hlfcc-fabcar-s6pwkq6bepi2e-abcde"),
+		))
+		Expect(spans[0].Resource.Attributes()).To(ContainElement(tracing.CommandKey.String("run")))
+		Expect(spans[0].Status.Code).To(Equal(codes.Unset))
+	})
+
+	It("should record errors", func() {
+		_, span := tracing.Start(context.Background(), "k8s.jobs.create")
+		tracing.End(span, errors.New("forbidden"))
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status.Code).To(Equal(codes.Error))
+		Expect(spans[0].Status.Description).To(Equal("forbidden"))
+		Expect(spans[0].Events).To(HaveLen(1))
+	})
+
+	It("should return the trace context for the current span", func() {
+		ctx, span := tracing.Start(context.Background(), "run")
+		defer span.End()
+
+		traceContext := tracing.GetTraceContext(ctx)
+		Expect(traceContext).To(HaveKeyWithValue("TRACEPARENT", ContainSubstring(span.SpanContext().TraceID().String())))
+	})
+
+	It("should not return a trace context without a span", func() {
+		Expect(tracing.GetTraceContext(context.Background())).To(BeNil())
+	})
+
+	It("should continue a trace from the TRACEPARENT environment variable", func() {
+		GinkgoT().Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		ctx, span := tracing.Start(tracing.ContextFromEnv(context.Background()), "build")
+		tracing.End(span, nil)
+
+		Expect(trace.SpanContextFromContext(ctx).TraceID().String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Parent.SpanID().String()).To(Equal("00f067aa0ba902b7"))
+	})
+})
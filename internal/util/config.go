@@ -0,0 +1,390 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// Config source prefixes for chaincode config environment variables.
+	ConfigSourceConfigMap = "configmap"
+	ConfigSourceSecret    = "secret"
+
+	configVolumeNamePrefix = "config-"
+	configFileParts        = 3
+)
+
+var errConfigSource = errors.New("must have exactly one of configMap or secret")
+
+// ChaincodeConfig is additional configuration for the chaincode container,
+// from the k8s builder settings or the image.json file.
+type ChaincodeConfig struct {
+	// Env contains additional environment variables for the chaincode.
+	Env map[string]string `json:"env,omitempty"`
+
+	// EnvFrom are ConfigMaps and Secrets in the chaincode namespace which
+	// contain environment variables for the chaincode.
+	EnvFrom []ConfigSource `json:"envFrom,omitempty"`
+
+	// ConfigFiles are ConfigMaps and Secrets in the chaincode namespace which
+	// are mounted as files in the chaincode container.
+	ConfigFiles []ConfigFile `json:"configFiles,omitempty"`
+}
+
+// ConfigSource is a ConfigMap or a Secret in the chaincode namespace.
+type ConfigSource struct {
+	ConfigMap string `json:"configMap,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+}
+
+// ConfigFile is a ConfigMap or a Secret which is mounted read-only at the
+// mount path in the chaincode container.
+type ConfigFile struct {
+	ConfigSource
+
+	MountPath string `json:"mountPath"`
+}
+
+// ParseEnv returns the environment variables for a comma separated list of
+// NAME=value pairs.
+func ParseEnv(value string) (map[string]string, error) {
+	env := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		name, envValue, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid environment variable '%s', must be NAME=value", pair)
+		}
+
+		env[name] = envValue
+	}
+
+	if err := validateEnv(env); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// ParseConfigSources returns the config sources for a comma separated list of
+// configmap:<name> or secret:<name> values.
+func ParseConfigSources(value string) ([]ConfigSource, error) {
+	var sources []ConfigSource
+
+	for _, sourceValue := range strings.Split(value, ",") {
+		sourceType, name, _ := strings.Cut(strings.TrimSpace(sourceValue), ":")
+
+		source, err := newConfigSource(sourceType, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// ParseConfigFiles returns the config files for a comma separated list of
+// configmap:<name>:<mount path> or secret:<name>:<mount path> values.
+func ParseConfigFiles(value string) ([]ConfigFile, error) {
+	var configFiles []ConfigFile
+
+	for _, configFileValue := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(configFileValue), ":", configFileParts)
+		if len(parts) != configFileParts {
+			return nil, fmt.Errorf("invalid config file '%s', must be configmap:<name>:<mount path> or secret:<name>:<mount path>", configFileValue)
+		}
+
+		source, err := newConfigSource(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		configFiles = append(configFiles, ConfigFile{ConfigSource: source, MountPath: parts[2]})
+	}
+
+	if err := validateConfigFiles(configFiles); err != nil {
+		return nil, err
+	}
+
+	return configFiles, nil
+}
+
+// ParseConfigAllowlist returns the config source patterns for a comma
+// separated list of configmap:<pattern> or secret:<pattern> values, using
+// path.Match syntax.
+func ParseConfigAllowlist(value string) ([]ConfigSource, error) {
+	var allowlist []ConfigSource
+
+	for _, patternValue := range strings.Split(value, ",") {
+		sourceType, pattern, _ := strings.Cut(strings.TrimSpace(patternValue), ":")
+
+		if pattern == "" {
+			return nil, fmt.Errorf("invalid config source pattern '%s', must be configmap:<pattern> or secret:<pattern>", patternValue)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid config source pattern '%s': %w", patternValue, err)
+		}
+
+		switch sourceType {
+		case ConfigSourceConfigMap:
+			allowlist = append(allowlist, ConfigSource{ConfigMap: pattern})
+		case ConfigSourceSecret:
+			allowlist = append(allowlist, ConfigSource{Secret: pattern})
+		default:
+			return nil, fmt.Errorf("unsupported config source type '%s', must be '%s' or '%s'", sourceType, ConfigSourceConfigMap, ConfigSourceSecret)
+		}
+	}
+
+	return allowlist, nil
+}
+
+// GetChaincodeConfig returns the configuration for the chaincode container,
+// combining the builder defaults with any configuration in the image.json
+// file. Environment variables in the image.json file replace builder defaults
+// with the same name. ConfigMaps and Secrets in the image.json file must match
+// one of the allowlist patterns, so that chaincode packages cannot use other
+// ConfigMaps and Secrets in the chaincode namespace.
+func GetChaincodeConfig(defaults ChaincodeConfig, allowlist []ConfigSource, imageData *ImageJSON) (ChaincodeConfig, error) {
+	if err := imageData.ChaincodeConfig.validate(); err != nil {
+		return ChaincodeConfig{}, err
+	}
+
+	if err := imageData.ChaincodeConfig.checkAllowed(allowlist); err != nil {
+		return ChaincodeConfig{}, err
+	}
+
+	config := ChaincodeConfig{
+		Env:         maps.Clone(defaults.Env),
+		EnvFrom:     slices.Concat(defaults.EnvFrom, imageData.EnvFrom),
+		ConfigFiles: slices.Concat(defaults.ConfigFiles, imageData.ConfigFiles),
+	}
+
+	if len(imageData.Env) > 0 {
+		if config.Env == nil {
+			config.Env = make(map[string]string)
+		}
+
+		maps.Copy(config.Env, imageData.Env)
+	}
+
+	if err := validateConfigFiles(config.ConfigFiles); err != nil {
+		return ChaincodeConfig{}, err
+	}
+
+	return config, nil
+}
+
+// IsReservedEnvName returns true if the environment variable is set by the k8s
+// builder, and cannot be overridden.
+func IsReservedEnvName(name string) bool {
+	switch name {
+	case "CORE_CHAINCODE_ID_NAME", "CHAINCODE_ID", "CHAINCODE_SERVER_ADDRESS", "TRACEPARENT", "TRACESTATE":
+		return true
+	default:
+		return strings.HasPrefix(name, "CORE_PEER_") || strings.HasPrefix(name, "CORE_TLS_CLIENT_")
+	}
+}
+
+// validate checks the chaincode configuration in the image.json file.
+func (c *ChaincodeConfig) validate() error {
+	if err := validateEnv(c.Env); err != nil {
+		return err
+	}
+
+	for _, source := range c.EnvFrom {
+		if err := source.validate(); err != nil {
+			return fmt.Errorf("invalid envFrom: %w", err)
+		}
+	}
+
+	return validateConfigFiles(c.ConfigFiles)
+}
+
+// checkAllowed checks that the ConfigMaps and Secrets in the image.json file
+// match the allowlist patterns.
+func (c *ChaincodeConfig) checkAllowed(allowlist []ConfigSource) error {
+	sources := slices.Clone(c.EnvFrom)
+	for _, configFile := range c.ConfigFiles {
+		sources = append(sources, configFile.ConfigSource)
+	}
+
+	for _, source := range sources {
+		if !source.isAllowed(allowlist) {
+			return fmt.Errorf("%s is not allowed by the chaincode config allowlist", source)
+		}
+	}
+
+	return nil
+}
+
+func validateEnv(env map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if msgs := validation.IsEnvVarName(name); len(msgs) > 0 {
+			return fmt.Errorf("invalid environment variable name '%s': %s", name, msgs[0])
+		}
+
+		if IsReservedEnvName(name) {
+			return fmt.Errorf("environment variable %s is reserved for the k8s builder", name)
+		}
+	}
+
+	return nil
+}
+
+func validateConfigFiles(configFiles []ConfigFile) error {
+	mountPaths := []string{certsMountPath, tmpMountPath}
+
+	for _, configFile := range configFiles {
+		if err := configFile.validate(); err != nil {
+			return fmt.Errorf("invalid config file: %w", err)
+		}
+
+		mountPath := path.Clean(configFile.MountPath)
+		if slices.Contains(mountPaths, mountPath) || strings.HasPrefix(mountPath, certsMountPath+"/") {
+			return fmt.Errorf("config file mount path %s is already in use", configFile.MountPath)
+		}
+
+		mountPaths = append(mountPaths, mountPath)
+	}
+
+	return nil
+}
+
+func newConfigSource(sourceType, name string) (ConfigSource, error) {
+	var source ConfigSource
+
+	switch sourceType {
+	case ConfigSourceConfigMap:
+		source.ConfigMap = name
+	case ConfigSourceSecret:
+		source.Secret = name
+	default:
+		return source, fmt.Errorf("unsupported config source type '%s', must be '%s' or '%s'", sourceType, ConfigSourceConfigMap, ConfigSourceSecret)
+	}
+
+	return source, source.validate()
+}
+
+func (s ConfigSource) validate() error {
+	if (s.ConfigMap == "") == (s.Secret == "") {
+		return errConfigSource
+	}
+
+	name := s.ConfigMap + s.Secret
+	if msgs := apivalidation.NameIsDNSSubdomain(name, false); len(msgs) > 0 {
+		return fmt.Errorf("invalid name '%s': %s", name, msgs[0])
+	}
+
+	return nil
+}
+
+// isAllowed returns true if the ConfigMap or Secret name matches one of the
+// allowlist patterns for the same source type.
+func (s ConfigSource) isAllowed(allowlist []ConfigSource) bool {
+	for _, pattern := range allowlist {
+		var matched bool
+
+		switch {
+		case s.ConfigMap != "" && pattern.ConfigMap != "":
+			matched, _ = path.Match(pattern.ConfigMap, s.ConfigMap)
+		case s.Secret != "" && pattern.Secret != "":
+			matched, _ = path.Match(pattern.Secret, s.Secret)
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s ConfigSource) String() string {
+	if s.ConfigMap != "" {
+		return "ConfigMap " + s.ConfigMap
+	}
+
+	return "Secret " + s.Secret
+}
+
+func (f ConfigFile) validate() error {
+	if err := f.ConfigSource.validate(); err != nil {
+		return err
+	}
+
+	if !path.IsAbs(f.MountPath) {
+		return fmt.Errorf("mount path '%s' must be an absolute path", f.MountPath)
+	}
+
+	return nil
+}
+
+// setChaincodeConfig adds the additional configuration to the chaincode
+// container.
+func setChaincodeConfig(podSpec *apiv1.PodSpec, config ChaincodeConfig) {
+	container := findContainer(podSpec.Containers, chaincodeContainerName)
+	if container == nil {
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(config.Env)) {
+		container.Env = append(container.Env, apiv1.EnvVar{Name: name, Value: config.Env[name]})
+	}
+
+	for _, source := range config.EnvFrom {
+		container.EnvFrom = append(container.EnvFrom, source.toEnvFromSource())
+	}
+
+	for i, configFile := range config.ConfigFiles {
+		volumeName := configVolumeNamePrefix + strconv.Itoa(i)
+
+		container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+			Name:      volumeName,
+			MountPath: configFile.MountPath,
+			ReadOnly:  true,
+		})
+
+		podSpec.Volumes = append(podSpec.Volumes, apiv1.Volume{
+			Name:         volumeName,
+			VolumeSource: configFile.toVolumeSource(),
+		})
+	}
+}
+
+func (s ConfigSource) toEnvFromSource() apiv1.EnvFromSource {
+	if s.ConfigMap != "" {
+		return apiv1.EnvFromSource{
+			ConfigMapRef: &apiv1.ConfigMapEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: s.ConfigMap}},
+		}
+	}
+
+	return apiv1.EnvFromSource{
+		SecretRef: &apiv1.SecretEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: s.Secret}},
+	}
+}
+
+func (f ConfigFile) toVolumeSource() apiv1.VolumeSource {
+	if f.ConfigMap != "" {
+		return apiv1.VolumeSource{
+			ConfigMap: &apiv1.ConfigMapVolumeSource{LocalObjectReference: apiv1.LocalObjectReference{Name: f.ConfigMap}},
+		}
+	}
+
+	return apiv1.VolumeSource{
+		Secret: &apiv1.SecretVolumeSource{SecretName: f.Secret},
+	}
+}
@@ -0,0 +1,201 @@
+package util_test
+
+import (
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	Describe("ParseEnv", func() {
+		It("should return environment variables for NAME=value pairs", func() {
+			env, err := util.ParseEnv("LOG_LEVEL=debug, API_URL=https://api.example.com/v1?a=b,EMPTY=")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env).To(Equal(map[string]string{
+				"LOG_LEVEL": "debug",
+				"API_URL":   "https://api.example.com/v1?a=b",
+				"EMPTY":     "",
+			}))
+		})
+
+		DescribeTable("should return an error for invalid environment variables",
+			func(value, expectedError string) {
+				_, err := util.ParseEnv(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When a pair has no value", "LOG_LEVEL", "invalid environment variable 'LOG_LEVEL', must be NAME=value"),
+			Entry("When a name is invalid", "1LOG=debug", "invalid environment variable name '1LOG'"),
+			Entry("When the name is CORE_CHAINCODE_ID_NAME", "CORE_CHAINCODE_ID_NAME=mycc:1", "environment variable CORE_CHAINCODE_ID_NAME is reserved for the k8s builder"),
+			Entry("When the name starts with CORE_PEER_", "CORE_PEER_ADDRESS=peer1:7052", "environment variable CORE_PEER_ADDRESS is reserved for the k8s builder"),
+			Entry("When the name starts with CORE_TLS_CLIENT_", "CORE_TLS_CLIENT_KEY_FILE=/tmp/key", "environment variable CORE_TLS_CLIENT_KEY_FILE is reserved for the k8s builder"),
+		)
+	})
+
+	Describe("ParseConfigSources", func() {
+		It("should return ConfigMap and Secret config sources", func() {
+			sources, err := util.ParseConfigSources("configmap:chaincode-env, secret:chaincode-credentials")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sources).To(Equal([]util.ConfigSource{
+				{ConfigMap: "chaincode-env"},
+				{Secret: "chaincode-credentials"},
+			}))
+		})
+
+		DescribeTable("should return an error for invalid config sources",
+			func(value, expectedError string) {
+				_, err := util.ParseConfigSources(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the type is missing", "chaincode-env", "unsupported config source type 'chaincode-env', must be 'configmap' or 'secret'"),
+			Entry("When the name is missing", "secret:", "must have exactly one of configMap or secret"),
+			Entry("When the name is invalid", "configmap:Chaincode_Env", "invalid name 'Chaincode_Env'"),
+		)
+	})
+
+	Describe("ParseConfigFiles", func() {
+		It("should return config files with mount paths", func() {
+			configFiles, err := util.ParseConfigFiles("configmap:chaincode-config:/etc/chaincode,secret:chaincode-tls:/var/run/chaincode/tls")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configFiles).To(Equal([]util.ConfigFile{
+				{ConfigSource: util.ConfigSource{ConfigMap: "chaincode-config"}, MountPath: "/etc/chaincode"},
+				{ConfigSource: util.ConfigSource{Secret: "chaincode-tls"}, MountPath: "/var/run/chaincode/tls"},
+			}))
+		})
+
+		DescribeTable("should return an error for invalid config files",
+			func(value, expectedError string) {
+				_, err := util.ParseConfigFiles(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the mount path is missing", "configmap:chaincode-config", "invalid config file 'configmap:chaincode-config', must be configmap:<name>:<mount path> or secret:<name>:<mount path>"),
+			Entry("When the mount path is relative", "configmap:chaincode-config:config", "mount path 'config' must be an absolute path"),
+			Entry("When the mount path is used by the peer certificates", "secret:chaincode-tls:/etc/hyperledger/fabric/tls", "config file mount path /etc/hyperledger/fabric/tls is already in use"),
+			Entry("When the mount path is /tmp", "configmap:chaincode-config:/tmp/", "config file mount path /tmp/ is already in use"),
+			Entry("When the mount path is used twice", "configmap:chaincode-config:/etc/chaincode,secret:chaincode-tls:/etc/chaincode", "config file mount path /etc/chaincode is already in use"),
+		)
+	})
+
+	Describe("ParseConfigAllowlist", func() {
+		It("should return ConfigMap and Secret name patterns", func() {
+			allowlist, err := util.ParseConfigAllowlist("configmap:fabcar-*, secret:fabcar-credentials")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowlist).To(Equal([]util.ConfigSource{
+				{ConfigMap: "fabcar-*"},
+				{Secret: "fabcar-credentials"},
+			}))
+		})
+
+		DescribeTable("should return an error for invalid config source patterns",
+			func(value, expectedError string) {
+				_, err := util.ParseConfigAllowlist(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the type is missing", "fabcar-*", "invalid config source pattern 'fabcar-*', must be configmap:<pattern> or secret:<pattern>"),
+			Entry("When the type is not supported", "vault:fabcar-*", "unsupported config source type 'vault', must be 'configmap' or 'secret'"),
+			Entry("When the pattern is invalid", "secret:fabcar-[", "invalid config source pattern 'secret:fabcar-['"),
+		)
+	})
+
+	Describe("GetChaincodeConfig", func() {
+		var (
+			defaults  util.ChaincodeConfig
+			allowlist []util.ConfigSource
+		)
+
+		BeforeEach(func() {
+			allowlist = []util.ConfigSource{{ConfigMap: "chaincode-*"}, {Secret: "chaincode-*"}}
+			defaults = util.ChaincodeConfig{
+				Env:         map[string]string{"LOG_LEVEL": "info", "REGION": "eu"},
+				EnvFrom:     []util.ConfigSource{{ConfigMap: "chaincode-env"}},
+				ConfigFiles: []util.ConfigFile{{ConfigSource: util.ConfigSource{ConfigMap: "chaincode-config"}, MountPath: "/etc/chaincode"}},
+			}
+		})
+
+		It("should return the builder defaults when image.json does not contain configuration", func() {
+			imageData := &util.ImageJSON{Name: "nginx", Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"}
+
+			config, err := util.GetChaincodeConfig(defaults, allowlist, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal(defaults))
+		})
+
+		It("should combine the builder defaults with configuration in image.json", func() {
+			imageData := &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+				ChaincodeConfig: util.ChaincodeConfig{
+					Env:         map[string]string{"LOG_LEVEL": "debug", "FEATURE_X": "true"},
+					EnvFrom:     []util.ConfigSource{{Secret: "chaincode-credentials"}},
+					ConfigFiles: []util.ConfigFile{{ConfigSource: util.ConfigSource{Secret: "chaincode-tls"}, MountPath: "/etc/chaincode/tls"}},
+				},
+			}
+
+			config, err := util.GetChaincodeConfig(defaults, allowlist, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Env).To(Equal(map[string]string{"LOG_LEVEL": "debug", "REGION": "eu", "FEATURE_X": "true"}))
+			Expect(config.EnvFrom).To(Equal([]util.ConfigSource{{ConfigMap: "chaincode-env"}, {Secret: "chaincode-credentials"}}))
+			Expect(config.ConfigFiles).To(HaveLen(2))
+			Expect(defaults.Env).To(HaveKeyWithValue("LOG_LEVEL", "info"))
+		})
+
+		It("should return an error if image.json overrides a reserved environment variable", func() {
+			imageData := &util.ImageJSON{
+				Name:            "nginx",
+				Digest:          "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+				ChaincodeConfig: util.ChaincodeConfig{Env: map[string]string{"CORE_PEER_LOCALMSPID": "OtherMSP"}},
+			}
+
+			_, err := util.GetChaincodeConfig(defaults, allowlist, imageData)
+			Expect(err).To(MatchError("environment variable CORE_PEER_LOCALMSPID is reserved for the k8s builder"))
+		})
+
+		It("should return an error if image.json uses the same mount path as the builder defaults", func() {
+			imageData := &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+				ChaincodeConfig: util.ChaincodeConfig{
+					ConfigFiles: []util.ConfigFile{{ConfigSource: util.ConfigSource{Secret: "chaincode-tls"}, MountPath: "/etc/chaincode"}},
+				},
+			}
+
+			_, err := util.GetChaincodeConfig(defaults, allowlist, imageData)
+			Expect(err).To(MatchError("config file mount path /etc/chaincode is already in use"))
+		})
+
+		It("should not check the builder defaults against the allowlist", func() {
+			imageData := &util.ImageJSON{Name: "nginx", Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"}
+
+			config, err := util.GetChaincodeConfig(defaults, nil, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal(defaults))
+		})
+
+		DescribeTable("should return an error if image.json uses a ConfigMap or Secret which is not allowed",
+			func(allowlist []util.ConfigSource, config util.ChaincodeConfig, expectedError string) {
+				imageData := &util.ImageJSON{
+					Name:            "nginx",
+					Digest:          "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+					ChaincodeConfig: config,
+				}
+
+				_, err := util.GetChaincodeConfig(defaults, allowlist, imageData)
+				Expect(err).To(MatchError(expectedError))
+			},
+			Entry("When there is no allowlist",
+				nil,
+				util.ChaincodeConfig{EnvFrom: []util.ConfigSource{{ConfigMap: "chaincode-env"}}},
+				"ConfigMap chaincode-env is not allowed by the chaincode config allowlist",
+			),
+			Entry("When a Secret is another chaincode's TLS secret",
+				[]util.ConfigSource{{Secret: "chaincode-*"}},
+				util.ChaincodeConfig{ConfigFiles: []util.ConfigFile{{ConfigSource: util.ConfigSource{Secret: "hlfcc-fabcar-s6pwkq6bepi2e"}, MountPath: "/etc/chaincode/tls"}}},
+				"Secret hlfcc-fabcar-s6pwkq6bepi2e is not allowed by the chaincode config allowlist",
+			),
+			Entry("When only a ConfigMap with the same name is allowed",
+				[]util.ConfigSource{{ConfigMap: "chaincode-*"}},
+				util.ChaincodeConfig{EnvFrom: []util.ConfigSource{{Secret: "chaincode-credentials"}}},
+				"Secret chaincode-credentials is not allowed by the chaincode config allowlist",
+			),
+		)
+	})
+})
@@ -13,7 +13,7 @@ import (
 )
 
 // CopyImageJSON validates and copies the chaincode image file.
-func CopyImageJSON(logger *log.CmdLogger, src, dest string) error {
+func CopyImageJSON(logger *log.CmdLogger, src, dest string, imagePolicy *ImagePolicy) error {
 	imageSrcPath := filepath.Join(src, ImageFile)
 	imageDestPath := filepath.Join(dest, ImageFile)
 
@@ -31,7 +31,7 @@ func CopyImageJSON(logger *log.CmdLogger, src, dest string) error {
 
 	logger.Debugf("Verifying chaincode image file %s", imageDestPath)
 
-	_, err = ReadImageJSON(logger, dest)
+	_, err = ReadImageJSON(logger, dest, imagePolicy)
 	if err != nil {
 		return err
 	}
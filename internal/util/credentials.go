@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"encoding/base64"
+	"path"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	typedBatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// Supported credential stores.
+const (
+	CredentialStoreKubernetes = "kubernetes"
+	CredentialStoreVault      = "vault"
+	DefaultCredentialStore    = CredentialStoreKubernetes
+)
+
+// CredentialStore stores the peer TLS certificates and client private key
+// which chaincode needs to connect to the peer, and configures chaincode pods
+// to retrieve them.
+type CredentialStore interface {
+	// Apply stores the credentials for a chaincode job.
+	Apply(ctx context.Context, logger *log.CmdLogger, name, peerID string, chaincodeData *ChaincodeJSON) error
+
+	// SetOwner makes the chaincode job the owner of the stored credentials, if
+	// the store supports it, so that they are deleted with the job.
+	SetOwner(ctx context.Context, logger *log.CmdLogger, name string, job *batchv1.Job) error
+
+	// RemoveOwners removes any owners of the stored credentials, if the store
+	// supports them, so that they are not deleted with earlier jobs.
+	RemoveOwners(ctx context.Context, logger *log.CmdLogger, name string) error
+
+	// Delete deletes the stored credentials, if they exist.
+	Delete(ctx context.Context, logger *log.CmdLogger, name string) error
+
+	// DeleteOrphaned deletes stored credentials in the namespace which are not
+	// used by any chaincode jobs that are still running.
+	DeleteOrphaned(ctx context.Context, logger *log.CmdLogger, jobsClient typedBatchv1.JobInterface) error
+
+	// ConfigurePod configures the chaincode pod template so that the
+	// credentials are available in the chaincode container.
+	ConfigurePod(podTemplate *apiv1.PodTemplateSpec, name string)
+
+	// Location describes where the credentials are stored, for log messages
+	// and events.
+	Location(name string) string
+}
+
+// KubernetesCredentialStore stores chaincode credentials in a Kubernetes
+// secret, which is mounted in the chaincode pod.
+type KubernetesCredentialStore struct {
+	secretsClient v1.SecretInterface
+	namespace     string
+}
+
+// NewKubernetesCredentialStore returns a credential store which stores
+// chaincode credentials in Kubernetes secrets in the provided namespace.
+func NewKubernetesCredentialStore(secretsClient v1.SecretInterface, namespace string) *KubernetesCredentialStore {
+	return &KubernetesCredentialStore{
+		secretsClient: secretsClient,
+		namespace:     namespace,
+	}
+}
+
+// Apply applies the chaincode secret.
+func (s *KubernetesCredentialStore) Apply(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	name, peerID string,
+	chaincodeData *ChaincodeJSON,
+) error {
+	return ApplyChaincodeSecrets(ctx, logger, s.secretsClient, name, s.namespace, peerID, chaincodeData)
+}
+
+// SetOwner makes the chaincode job the owner of the chaincode secret.
+func (s *KubernetesCredentialStore) SetOwner(ctx context.Context, logger *log.CmdLogger, name string, job *batchv1.Job) error {
+	return SetChaincodeSecretOwner(ctx, logger, s.secretsClient, name, job)
+}
+
+// RemoveOwners removes any owners of the chaincode secret.
+func (s *KubernetesCredentialStore) RemoveOwners(ctx context.Context, logger *log.CmdLogger, name string) error {
+	return RemoveChaincodeSecretOwners(ctx, logger, s.secretsClient, name, s.namespace)
+}
+
+// Delete deletes the chaincode secret.
+func (s *KubernetesCredentialStore) Delete(ctx context.Context, logger *log.CmdLogger, name string) error {
+	return DeleteChaincodeSecrets(ctx, logger, s.secretsClient, name, s.namespace)
+}
+
+// DeleteOrphaned deletes chaincode secrets which are not used by any chaincode
+// jobs that are still running.
+func (s *KubernetesCredentialStore) DeleteOrphaned(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	jobsClient typedBatchv1.JobInterface,
+) error {
+	return DeleteOrphanedChaincodeSecrets(ctx, logger, s.secretsClient, jobsClient, s.namespace)
+}
+
+// ConfigurePod mounts the chaincode secret in the chaincode container.
+func (s *KubernetesCredentialStore) ConfigurePod(podTemplate *apiv1.PodTemplateSpec, name string) {
+	setCertsVolume(&podTemplate.Spec, name)
+}
+
+// Location returns the namespace and name of the chaincode secret.
+func (s *KubernetesCredentialStore) Location(name string) string {
+	return s.namespace + "/" + name
+}
+
+// getChaincodeCredentials returns the chaincode credential files, keyed by the
+// file name in the chaincode container.
+func getChaincodeCredentials(chaincodeData *ChaincodeJSON) map[string]string {
+	return map[string]string{
+		"peer.crt":       chaincodeData.RootCert,
+		"client_pem.crt": chaincodeData.ClientCert,
+		"client_pem.key": chaincodeData.ClientKey,
+		"client.crt":     base64.StdEncoding.EncodeToString([]byte(chaincodeData.ClientCert)),
+		"client.key":     base64.StdEncoding.EncodeToString([]byte(chaincodeData.ClientKey)),
+	}
+}
+
+// getChaincodeCredentialFiles returns the names of the chaincode credential
+// files in the chaincode container.
+func getChaincodeCredentialFiles() []string {
+	return []string{
+		path.Base(TLSClientRootCertFile),
+		path.Base(TLSClientCertFile),
+		path.Base(TLSClientKeyFile),
+		path.Base(TLSClientCertPath),
+		path.Base(TLSClientKeyPath),
+	}
+}
+
+// setChaincodeCredentials configures the chaincode pod to retrieve the peer
+// TLS credentials from the credential store, or from the chaincode secret if
+// there is no credential store.
+func setChaincodeCredentials(podTemplate *apiv1.PodTemplateSpec, credentialStore CredentialStore, name string) {
+	if credentialStore == nil {
+		setCertsVolume(&podTemplate.Spec, name)
+
+		return
+	}
+
+	credentialStore.ConfigurePod(podTemplate, name)
+}
+
+// setCertsVolume mounts the chaincode secret containing the peer TLS
+// certificates in the chaincode container.
+func setCertsVolume(podSpec *apiv1.PodSpec, secretName string) {
+	container := findContainer(podSpec.Containers, chaincodeContainerName)
+	container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+		Name:      certsVolumeName,
+		MountPath: certsMountPath,
+		ReadOnly:  true,
+	})
+
+	podSpec.Volumes = append(podSpec.Volumes, apiv1.Volume{
+		Name: certsVolumeName,
+		VolumeSource: apiv1.VolumeSource{
+			Secret: &apiv1.SecretVolumeSource{
+				SecretName: secretName,
+			},
+		},
+	})
+}
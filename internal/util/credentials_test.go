@@ -0,0 +1,232 @@
+package util_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// vaultStub is a minimal Vault KV version 2 secrets engine.
+type vaultStub struct {
+	mu      sync.Mutex
+	token   string
+	secrets map[string]map[string]string
+	updated map[string]time.Time
+}
+
+func (v *vaultStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		secretPath := strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")
+		v.secrets[secretPath] = body.Data
+		v.updated[secretPath] = time.Now()
+		_, _ = w.Write([]byte(`{"data":{"version":1}}`))
+	case r.Method == "LIST" && strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		v.list(w, strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/"))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		updated, ok := v.updated[strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"updated_time": updated}})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		delete(v.secrets, strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	}
+}
+
+func (v *vaultStub) list(w http.ResponseWriter, directory string) {
+	var keys []string
+
+	for secretPath := range v.secrets {
+		if name, found := strings.CutPrefix(secretPath, strings.TrimSuffix(directory, "/")+"/"); found {
+			keys = append(keys, name)
+		}
+	}
+
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"keys": keys}})
+}
+
+var _ = Describe("Credentials", func() {
+	var (
+		ctx           context.Context
+		logger        *log.CmdLogger
+		chaincodeData *util.ChaincodeJSON
+		imageData     *util.ImageJSON
+	)
+
+	BeforeEach(func() {
+		ctx = log.NewCmdContext(context.Background(), false)
+		logger = log.New(ctx)
+		chaincodeData = &util.ChaincodeJSON{
+			ChaincodeID: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b",
+			PeerAddress: "peer0.org1.example.com",
+			ClientCert:  "CLIENT_CERT",
+			ClientKey:   "CLIENT_KEY",
+			RootCert:    "ROOT_CERT",
+			MspID:       "CongaOrg",
+		}
+		imageData = &util.ImageJSON{
+			Name:   "nginx",
+			Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+		}
+	})
+
+	Describe("KubernetesCredentialStore", func() {
+		It("should store chaincode credentials in a chaincode secret", func() {
+			clientset := fake.NewClientset()
+			credentialStore := util.NewKubernetesCredentialStore(clientset.CoreV1().Secrets("chaincode"), "chaincode")
+
+			Expect(credentialStore.Apply(ctx, logger, "hlfcc-fabcar-s6pwkq6bepi2e", "CongaOrgPeer0", chaincodeData)).To(Succeed())
+			Expect(credentialStore.Location("hlfcc-fabcar-s6pwkq6bepi2e")).To(Equal("chaincode/hlfcc-fabcar-s6pwkq6bepi2e"))
+
+			secret, err := clientset.CoreV1().Secrets("chaincode").Get(ctx, "hlfcc-fabcar-s6pwkq6bepi2e", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secret.StringData).To(HaveKeyWithValue("client_pem.key", "CLIENT_KEY"))
+
+			Expect(credentialStore.Delete(ctx, logger, "hlfcc-fabcar-s6pwkq6bepi2e")).To(Succeed())
+
+			_, err = clientset.CoreV1().Secrets("chaincode").Get(ctx, "hlfcc-fabcar-s6pwkq6bepi2e", metav1.GetOptions{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("VaultCredentialStore", func() {
+		var (
+			stub            *vaultStub
+			credentialStore *util.VaultCredentialStore
+		)
+
+		BeforeEach(func() {
+			stub = &vaultStub{token: "s.chaincode", secrets: make(map[string]map[string]string), updated: make(map[string]time.Time)}
+			server := httptest.NewServer(stub)
+			DeferCleanup(server.Close)
+
+			tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+			Expect(os.WriteFile(tokenFile, []byte("s.chaincode\n"), 0o600)).To(Succeed())
+
+			credentialStore = util.NewVaultCredentialStore(util.VaultConfig{
+				Address:   server.URL,
+				TokenFile: tokenFile,
+				Role:      "fabric-chaincode",
+			}, "chaincode")
+		})
+
+		It("should write chaincode credentials to vault", func() {
+			Expect(credentialStore.Apply(ctx, logger, "hlfcc-fabcar-s6pwkq6bepi2e", "CongaOrgPeer0", chaincodeData)).To(Succeed())
+			Expect(stub.secrets).To(HaveKeyWithValue("fabric-builder-k8s/chaincode/hlfcc-fabcar-s6pwkq6bepi2e", map[string]string{
+				"peer.crt":       "ROOT_CERT",
+				"client_pem.crt": "CLIENT_CERT",
+				"client_pem.key": "CLIENT_KEY",
+				"client.crt":     "Q0xJRU5UX0NFUlQ=",
+				"client.key":     "Q0xJRU5UX0tFWQ==",
+			}))
+			Expect(credentialStore.Location("hlfcc-fabcar-s6pwkq6bepi2e")).To(Equal("vault:secret/data/fabric-builder-k8s/chaincode/hlfcc-fabcar-s6pwkq6bepi2e"))
+		})
+
+		It("should delete chaincode credentials from vault", func() {
+			Expect(credentialStore.Apply(ctx, logger, "hlfcc-fabcar-s6pwkq6bepi2e", "CongaOrgPeer0", chaincodeData)).To(Succeed())
+			Expect(credentialStore.Delete(ctx, logger, "hlfcc-fabcar-s6pwkq6bepi2e")).To(Succeed())
+			Expect(stub.secrets).To(BeEmpty())
+		})
+
+		It("should delete orphaned chaincode credentials from vault", func() {
+			jobsClient := fake.NewClientset().BatchV1().Jobs("chaincode")
+			podOptions := util.PodOptions{CredentialStore: credentialStore}
+
+			_, err := util.CreateChaincodeJob(ctx, logger, jobsClient, "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, podOptions, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, name := range []string{"hlfcc-fabcar-s6pwkq6bepi2e", "hlfcc-orphaned-hl3b2lcr7eeuc", "hlfcc-recent-7pxyhfm2rfkgm"} {
+				Expect(credentialStore.Apply(ctx, logger, name, "CongaOrgPeer0", chaincodeData)).To(Succeed())
+			}
+
+			stub.updated["fabric-builder-k8s/chaincode/hlfcc-fabcar-s6pwkq6bepi2e"] = time.Now().Add(-time.Hour)
+			stub.updated["fabric-builder-k8s/chaincode/hlfcc-orphaned-hl3b2lcr7eeuc"] = time.Now().Add(-time.Hour)
+
+			Expect(credentialStore.DeleteOrphaned(ctx, logger, jobsClient)).To(Succeed())
+			Expect(stub.secrets).To(HaveLen(2))
+			Expect(stub.secrets).To(HaveKey("fabric-builder-k8s/chaincode/hlfcc-fabcar-s6pwkq6bepi2e"))
+			Expect(stub.secrets).To(HaveKey("fabric-builder-k8s/chaincode/hlfcc-recent-7pxyhfm2rfkgm"))
+		})
+
+		It("should not return an error if there are no chaincode credentials in vault", func() {
+			Expect(credentialStore.DeleteOrphaned(ctx, logger, fake.NewClientset().BatchV1().Jobs("chaincode"))).To(Succeed())
+		})
+
+		It("should return an error if vault rejects the token", func() {
+			stub.token = "s.other"
+
+			err := credentialStore.Apply(ctx, logger, "hlfcc-fabcar-s6pwkq6bepi2e", "CongaOrgPeer0", chaincodeData)
+			Expect(err).To(MatchError(ContainSubstring("vault returned 403 Forbidden: permission denied")))
+		})
+
+		It("should create a chaincode job which uses the vault agent injector to retrieve the credentials", func() {
+			podOptions := util.PodOptions{CredentialStore: credentialStore}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, fake.NewClientset().BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, podOptions, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			annotations := job.Spec.Template.Annotations
+			Expect(annotations).To(HaveKeyWithValue("vault.hashicorp.com/agent-inject", "true"))
+			Expect(annotations).To(HaveKeyWithValue("vault.hashicorp.com/agent-pre-populate-only", "true"))
+			Expect(annotations).To(HaveKeyWithValue("vault.hashicorp.com/role", "fabric-chaincode"))
+			Expect(annotations).To(HaveKeyWithValue("vault.hashicorp.com/secret-volume-path", "/etc/hyperledger/fabric"))
+			Expect(annotations).To(HaveKeyWithValue("vault.hashicorp.com/agent-inject-secret-client_pem.key", "secret/data/fabric-builder-k8s/chaincode/hlfcc-fabcar-s6pwkq6bepi2e"))
+			Expect(annotations).To(HaveKeyWithValue(
+				"vault.hashicorp.com/agent-inject-template-client_pem.key",
+				`{{- with secret "secret/data/fabric-builder-k8s/chaincode/hlfcc-fabcar-s6pwkq6bepi2e" -}}{{ index .Data.data "client_pem.key" }}{{- end -}}`,
+			))
+			Expect(job.Annotations).NotTo(HaveKey("vault.hashicorp.com/agent-inject"))
+			Expect(job.Spec.Template.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "certs")))
+		})
+	})
+})
@@ -8,15 +8,63 @@ import (
 )
 
 const (
-	builderVariablePrefix           = "FABRIC_K8S_BUILDER_"
-	ChaincodeNamespaceVariable      = builderVariablePrefix + "NAMESPACE"
-	ChaincodeNodeRoleVariable       = builderVariablePrefix + "NODE_ROLE"
-	ObjectNamePrefixVariable        = builderVariablePrefix + "OBJECT_NAME_PREFIX"
-	ChaincodeServiceAccountVariable = builderVariablePrefix + "SERVICE_ACCOUNT"
-	ChaincodeStartTimeoutVariable   = builderVariablePrefix + "START_TIMEOUT"
-	DebugVariable                   = builderVariablePrefix + "DEBUG"
-	KubeconfigPathVariable          = "KUBECONFIG_PATH"
-	PeerIDVariable                  = "CORE_PEER_ID"
+	builderVariablePrefix            = "FABRIC_K8S_BUILDER_"
+	ChaincodeNamespaceVariable       = builderVariablePrefix + "NAMESPACE"
+	ChaincodeNodeRoleVariable        = builderVariablePrefix + "NODE_ROLE"
+	NodeSelectorVariable             = builderVariablePrefix + "NODE_SELECTOR"
+	TolerationsVariable              = builderVariablePrefix + "TOLERATIONS"
+	TopologySpreadVariable           = builderVariablePrefix + "TOPOLOGY_SPREAD"
+	PodAntiAffinityVariable          = builderVariablePrefix + "POD_ANTI_AFFINITY"
+	ObjectNamePrefixVariable         = builderVariablePrefix + "OBJECT_NAME_PREFIX"
+	ChaincodeServiceAccountVariable  = builderVariablePrefix + "SERVICE_ACCOUNT"
+	ChaincodeStartTimeoutVariable    = builderVariablePrefix + "START_TIMEOUT"
+	ShutdownGracePeriodVariable      = builderVariablePrefix + "SHUTDOWN_GRACE_PERIOD"
+	ChaincodeCPURequestVariable      = builderVariablePrefix + "CPU_REQUEST"
+	ChaincodeCPULimitVariable        = builderVariablePrefix + "CPU_LIMIT"
+	ChaincodeMemoryRequestVariable   = builderVariablePrefix + "MEMORY_REQUEST"
+	ChaincodeMemoryLimitVariable     = builderVariablePrefix + "MEMORY_LIMIT"
+	JobTemplateVariable              = builderVariablePrefix + "JOB_TEMPLATE"
+	ImagePullSecretsVariable         = builderVariablePrefix + "IMAGE_PULL_SECRETS"
+	ImageAllowlistVariable           = builderVariablePrefix + "IMAGE_ALLOWLIST"
+	ImageDenylistVariable            = builderVariablePrefix + "IMAGE_DENYLIST"
+	VerifyImageDigestVariable        = builderVariablePrefix + "VERIFY_IMAGE_DIGEST"
+	ResolveImageTagsVariable         = builderVariablePrefix + "RESOLVE_IMAGE_TAGS"
+	SignaturePolicyVariable          = builderVariablePrefix + "SIGNATURE_POLICY"
+	AllowRunAsRootVariable           = builderVariablePrefix + "ALLOW_RUN_AS_ROOT"
+	RunAsUserVariable                = builderVariablePrefix + "RUN_AS_USER"
+	AllowPrivilegeEscalationVariable = builderVariablePrefix + "ALLOW_PRIVILEGE_ESCALATION"
+	AddCapabilitiesVariable          = builderVariablePrefix + "ADD_CAPABILITIES"
+	SeccompProfileVariable           = builderVariablePrefix + "SECCOMP_PROFILE"
+	WritableRootFilesystemVariable   = builderVariablePrefix + "WRITABLE_ROOT_FILESYSTEM"
+	ReadinessProbeVariable           = builderVariablePrefix + "READINESS_PROBE"
+	LivenessProbeVariable            = builderVariablePrefix + "LIVENESS_PROBE"
+	ChaincodeEnvVariable             = builderVariablePrefix + "CHAINCODE_ENV"
+	ChaincodeEnvFromVariable         = builderVariablePrefix + "CHAINCODE_ENV_FROM"
+	ChaincodeConfigFilesVariable     = builderVariablePrefix + "CHAINCODE_CONFIG_FILES"
+	ChaincodeConfigAllowlistVariable = builderVariablePrefix + "CHAINCODE_CONFIG_ALLOWLIST"
+	VerifySignaturesOnRunVariable    = builderVariablePrefix + "VERIFY_SIGNATURES_ON_RUN"
+	RunModeVariable                  = builderVariablePrefix + "RUN_MODE"
+	ServiceReplicasVariable          = builderVariablePrefix + "SERVICE_REPLICAS"
+	RestartPolicyVariable            = builderVariablePrefix + "RESTART_POLICY"
+	BackoffLimitVariable             = builderVariablePrefix + "BACKOFF_LIMIT"
+	IgnorePodDisruptionsVariable     = builderVariablePrefix + "IGNORE_POD_DISRUPTIONS"
+	FailJobExitCodesVariable         = builderVariablePrefix + "FAIL_JOB_EXIT_CODES"
+	ExistingJobPolicyVariable        = builderVariablePrefix + "EXISTING_JOB_POLICY"
+	CredentialStoreVariable          = builderVariablePrefix + "CREDENTIAL_STORE"
+	VaultAddressVariable             = builderVariablePrefix + "VAULT_ADDR"
+	VaultTokenFileVariable           = builderVariablePrefix + "VAULT_TOKEN_FILE"
+	VaultKVMountVariable             = builderVariablePrefix + "VAULT_KV_MOUNT"
+	VaultRoleVariable                = builderVariablePrefix + "VAULT_ROLE"
+	StreamLogsVariable               = builderVariablePrefix + "STREAM_LOGS"
+	LogTailLinesVariable             = builderVariablePrefix + "LOG_TAIL_LINES"
+	MetricsPushgatewayURLVariable    = builderVariablePrefix + "METRICS_PUSHGATEWAY_URL"
+	MetricsTextfileDirVariable       = builderVariablePrefix + "METRICS_TEXTFILE_DIR"
+	DebugVariable                    = builderVariablePrefix + "DEBUG"
+	LogLevelVariable                 = builderVariablePrefix + "LOG_LEVEL"
+	LogFormatVariable                = builderVariablePrefix + "LOG_FORMAT"
+	KubeconfigPathVariable           = "KUBECONFIG_PATH"
+	PeerIDVariable                   = "CORE_PEER_ID"
+	PeerTLSEnabledVariable           = "CORE_PEER_TLS_ENABLED"
 )
 
 func GetOptionalEnv(key, defaultValue string) string {
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/reference"
+)
+
+const (
+	eventTimeout = 2 * time.Second
+
+	// Chaincode job event reasons, in addition to the job termination reasons.
+	EventReasonSecretApplied  string = "SecretApplied"
+	EventReasonJobCreated     string = "JobCreated"
+	EventReasonJobAdopted     string = "JobAdopted"
+	EventReasonChaincodeReady string = "ChaincodeReady"
+)
+
+// ChaincodeEventRecorder is a Kubernetes EventRecorder which creates events
+// immediately, rather than queuing them in the background, so that events are
+// not lost when a short lived builder command exits.
+type ChaincodeEventRecorder struct {
+	logger       *log.CmdLogger
+	eventsClient v1.EventInterface
+	peerID       string
+}
+
+// NewChaincodeEventRecorder returns a new ChaincodeEventRecorder which records
+// events for the provided peer.
+func NewChaincodeEventRecorder(logger *log.CmdLogger, eventsClient v1.EventInterface, peerID string) *ChaincodeEventRecorder {
+	return &ChaincodeEventRecorder{
+		logger:       logger,
+		eventsClient: eventsClient,
+		peerID:       peerID,
+	}
+}
+
+// Event records an event for the provided object. Failing to record an event
+// does not stop the chaincode, so errors are only logged.
+func (r *ChaincodeEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+// Eventf records an event for the provided object with a formatted message.
+func (r *ChaincodeEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+// AnnotatedEventf records an annotated event for the provided object with a
+// formatted message.
+func (r *ChaincodeEventRecorder) AnnotatedEventf(
+	object runtime.Object,
+	annotations map[string]string,
+	eventtype, reason, messageFmt string,
+	args ...interface{},
+) {
+	ref, err := reference.GetReference(scheme.Scheme, object)
+	if err != nil {
+		r.logger.Warnf("Unable to record %s event: %v", reason, err)
+
+		return
+	}
+
+	now := metav1.Now()
+	event := &apiv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%v.%x", ref.Name, now.UnixNano()),
+			Namespace:   ref.Namespace,
+			Annotations: annotations,
+		},
+		InvolvedObject:      *ref,
+		Reason:              reason,
+		Message:             fmt.Sprintf(messageFmt, args...),
+		Type:                eventtype,
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		Source:              apiv1.EventSource{Component: fabricBuilderK8s},
+		ReportingController: fabricBuilderK8s,
+		ReportingInstance:   r.peerID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+
+	if _, err := r.eventsClient.Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		r.logger.Warnf("Unable to record %s event for %s %s/%s: %v", reason, ref.Kind, ref.Namespace, ref.Name, err)
+
+		return
+	}
+
+	r.logger.Debugf("Recorded %s event for %s %s/%s: %s", reason, ref.Kind, ref.Namespace, ref.Name, event.Message)
+}
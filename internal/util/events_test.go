@@ -0,0 +1,67 @@
+package util_test
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Events", func() {
+	Describe("ChaincodeEventRecorder", func() {
+		var (
+			ctx       context.Context
+			logger    *log.CmdLogger
+			clientset *fake.Clientset
+			job       *batchv1.Job
+		)
+
+		BeforeEach(func() {
+			ctx = log.NewCmdContext(context.Background(), false)
+			logger = log.New(ctx)
+			clientset = fake.NewClientset()
+			job = &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hlfcc-fabcar-s6pwkq6bepi2e-abcde",
+					Namespace: "chaincode",
+					UID:       "d4c1e7b2-5a8f-4c3e-9b6d-2f1a0e7c8b9d",
+				},
+			}
+		})
+
+		It("should create an event for the chaincode job", func() {
+			recorder := util.NewChaincodeEventRecorder(logger, clientset.CoreV1().Events("chaincode"), "CongaOrgPeer0")
+			recorder.Eventf(job, apiv1.EventTypeWarning, "BackoffLimitExceeded", "chaincode job %s terminated", job.Name)
+
+			events, err := clientset.CoreV1().Events("chaincode").List(ctx, metav1.ListOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events.Items).To(HaveLen(1))
+
+			event := events.Items[0]
+			Expect(event.Type).To(Equal(apiv1.EventTypeWarning))
+			Expect(event.Reason).To(Equal("BackoffLimitExceeded"))
+			Expect(event.Message).To(Equal("chaincode job hlfcc-fabcar-s6pwkq6bepi2e-abcde terminated"))
+			Expect(event.InvolvedObject.Kind).To(Equal("Job"))
+			Expect(event.InvolvedObject.Name).To(Equal(job.Name))
+			Expect(event.InvolvedObject.UID).To(Equal(job.UID))
+			Expect(event.Source.Component).To(Equal("fabric-builder-k8s"))
+			Expect(event.ReportingInstance).To(Equal("CongaOrgPeer0"))
+		})
+
+		It("should create a separate event for each milestone", func() {
+			recorder := util.NewChaincodeEventRecorder(logger, clientset.CoreV1().Events("chaincode"), "CongaOrgPeer0")
+			recorder.Event(job, apiv1.EventTypeNormal, util.EventReasonJobCreated, "Created job")
+			recorder.Event(job, apiv1.EventTypeNormal, util.EventReasonSecretApplied, "Applied chaincode secret")
+
+			events, err := clientset.CoreV1().Events("chaincode").List(ctx, metav1.ListOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events.Items).To(HaveLen(2))
+		})
+	})
+})
@@ -2,7 +2,23 @@
 
 package util
 
-import "strings"
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Fabric creates a temporary build directory for each chaincode package
+	// with a name based on the package ID.
+	fabricBuildDirPrefix = "fabric-"
+	packageHashLength    = 64
+
+	// The package ID separator may be replaced in build context directory
+	// names.
+	packageIDSeparators = ":-"
+)
 
 type ChaincodePackageID struct {
 	Label string
@@ -26,3 +42,31 @@ func NewChaincodePackageID(chaincodeID string) *ChaincodePackageID {
 		Hash:  substrings[len(substrings)-1],
 	}
 }
+
+// GetChaincodeIDFromBuildContext returns the chaincode package ID for the
+// chaincode label when building the provided BUILD_OUTPUT_DIR.
+//
+// Fabric does not pass the package ID to the build command, however the peer
+// creates the build context directory containing BUILD_OUTPUT_DIR using the
+// package ID, with any characters which are not allowed in file names
+// replaced, followed by a random suffix, e.g.
+// /tmp/fabric-mycc-a7ca45a7cc85f1d89c905b775920361ed089a364e12a9b6d55ba75c965ddd6a9123456/bld
+//
+// The label from metadata.json must match the start of the package ID, so the
+// package hash is the 64 hex characters following the label and a separator.
+func GetChaincodeIDFromBuildContext(buildOutputDirectory, label string) (string, error) {
+	buildContextDir := filepath.Base(filepath.Dir(filepath.Clean(buildOutputDirectory)))
+
+	sanitizedHash, found := strings.CutPrefix(buildContextDir, fabricBuildDirPrefix+label)
+	if !found || len(sanitizedHash) < packageHashLength+1 || !strings.ContainsRune(packageIDSeparators, rune(sanitizedHash[0])) {
+		return "", fmt.Errorf("unable to find package ID for chaincode label %s in build context directory %s", label, buildContextDir)
+	}
+
+	hash := sanitizedHash[1 : packageHashLength+1]
+
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("unable to find package hash for chaincode label %s in build context directory %s: %w", label, buildContextDir, err)
+	}
+
+	return label + ":" + hash, nil
+}
@@ -20,4 +20,27 @@ var _ = Describe("Fabric", func() {
 		Entry("When the chaincode ID is an empty string", "", "", ""),
 		Entry("When the chaincode ID does not contain a colon", "fabcar", "", ""),
 	)
+
+	DescribeTable("GetChaincodeIDFromBuildContext returns the chaincode package ID for Fabric build context directories",
+		func(buildOutputDirectory, label, expectedChaincodeID string) {
+			chaincodeID, err := util.GetChaincodeIDFromBuildContext(buildOutputDirectory, label)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chaincodeID).To(Equal(expectedChaincodeID))
+		},
+		Entry("When the package ID separator has been replaced", "/tmp/fabric-fabcar-cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b2847146102/bld", "fabcar", "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b"),
+		Entry("When the package ID separator has not been replaced", "/tmp/fabric-fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b2847146102/bld", "fabcar", "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b"),
+		Entry("When the chaincode label contains a dash", "/tmp/fabric-go-contract-6f98c4bb29414771312eddd1a813eef583df2121c235c4797792f141a46d4b45123/bld/", "go-contract", "go-contract:6f98c4bb29414771312eddd1a813eef583df2121c235c4797792f141a46d4b45"),
+		Entry("When the random suffix is not numeric", "/tmp/fabric-fabcar-cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004bx7Qz/bld", "fabcar", "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b"),
+	)
+
+	DescribeTable("GetChaincodeIDFromBuildContext returns an error for unexpected build context directories",
+		func(buildOutputDirectory, label string) {
+			_, err := util.GetChaincodeIDFromBuildContext(buildOutputDirectory, label)
+			Expect(err).To(MatchError(ContainSubstring("chaincode label " + label)))
+		},
+		Entry("When the build context directory does not have the fabric prefix", "/tmp/builds/fabcar-cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b/bld", "fabcar"),
+		Entry("When the build context directory is for a different label", "/tmp/fabric-fabcar-v2-cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b/bld", "fabcar"),
+		Entry("When the build context directory contains a short hash", "/tmp/fabric-fabcar-cffa266294278404e5071cb91150d550/bld", "fabcar"),
+		Entry("When the build context directory contains an invalid hash", "/tmp/fabric-fabcar-xyza266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b/bld", "fabcar"),
+	)
 })
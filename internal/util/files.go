@@ -5,10 +5,17 @@ package util
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	registryv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 )
 
 // ChaincodeJSON represents the chaincode.json file that is supplied by Fabric in
@@ -24,8 +31,55 @@ type ChaincodeJSON struct {
 
 // ImageJSON represents the image.json file in the k8s chaincode package.
 type ImageJSON struct {
-	Name   string `json:"name"`
-	Digest string `json:"digest"`
+	Name            string         `json:"name"`
+	Tag             string         `json:"tag,omitempty"`
+	Digest          string         `json:"digest"`
+	ImagePullSecret string         `json:"imagePullSecret,omitempty"`
+	Resources       *ResourcesJSON `json:"resources,omitempty"`
+	Probes          *ProbesJSON    `json:"probes,omitempty"`
+
+	ChaincodeConfig
+}
+
+// ResourcesJSON represents the optional chaincode container resource requests
+// and limits in the image.json file.
+type ResourcesJSON struct {
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+// ProbesJSON represents the optional chaincode container readiness and
+// liveness probes in the image.json file.
+type ProbesJSON struct {
+	Readiness *ProbeJSON `json:"readiness,omitempty"`
+	Liveness  *ProbeJSON `json:"liveness,omitempty"`
+}
+
+// ProbeJSON represents a chaincode container probe in the image.json file,
+// which must contain exactly one of exec, tcpPort, or grpcPort.
+type ProbeJSON struct {
+	Exec                []string `json:"exec,omitempty"`
+	TCPPort             int32    `json:"tcpPort,omitempty"`
+	GRPCPort            int32    `json:"grpcPort,omitempty"`
+	GRPCService         string   `json:"grpcService,omitempty"`
+	InitialDelaySeconds int32    `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int32    `json:"periodSeconds,omitempty"`
+	TimeoutSeconds      int32    `json:"timeoutSeconds,omitempty"`
+	FailureThreshold    int32    `json:"failureThreshold,omitempty"`
+}
+
+// ConnectionJSON represents the connection.json file that the k8s builder
+// writes to the RELEASE_OUTPUT_DIR when chaincode is run as a service.
+type ConnectionJSON struct {
+	Address     string `json:"address"`
+	DialTimeout string `json:"dial_timeout"`
+	TLSRequired bool   `json:"tls_required"`
+}
+
+// BuildJSON represents the build.json file which the build command writes to
+// the build output directory, for the release command.
+type BuildJSON struct {
+	PackageID string `json:"package_id"`
 }
 
 // MetadataJSON represents the metadata.json file in the k8s chaincode package.
@@ -35,10 +89,14 @@ type MetadataJSON struct {
 }
 
 const (
-	ChaincodeFile = "chaincode.json"
-	ImageFile     = "image.json"
-	MetadataFile  = "metadata.json"
-	MetadataDir   = "META-INF"
+	BuildFile      = "build.json"
+	ChaincodeFile  = "chaincode.json"
+	ConnectionFile = "connection.json"
+	ImageFile      = "image.json"
+	MetadataFile   = "metadata.json"
+	MetadataDir    = "META-INF"
+
+	connectionDialTimeout = "10s"
 )
 
 // ReadChaincodeJSON reads and parses the chaincode.json file in the provided directory.
@@ -58,12 +116,94 @@ func ReadChaincodeJSON(logger *log.CmdLogger, dir string) (*ChaincodeJSON, error
 
 	logger.Debugf("Chaincode ID: %s\n", chaincodeData.ChaincodeID)
 
+	if err := validateChaincodeTLS(&chaincodeData); err != nil {
+		return nil, fmt.Errorf("%s file %w", chaincodeJSONPath, err)
+	}
+
+	if chaincodeData.TLSEnabled() {
+		if err := validateChaincodeCertificates(logger, &chaincodeData, time.Now()); err != nil {
+			return nil, fmt.Errorf("%s file %w", chaincodeJSONPath, err)
+		}
+	}
+
+	logger.Debugf("Peer TLS enabled: %t\n", chaincodeData.TLSEnabled())
+
 	return &chaincodeData, nil
 }
 
-// ReadImageJSON reads and parses the image.json file in the provided directory.
-func ReadImageJSON(logger *log.CmdLogger, dir string) (*ImageJSON, error) {
+// TLSEnabled returns true if the peer provided TLS certificates for the
+// chaincode, or false if the chaincode connects to the peer using plain text.
+func (c *ChaincodeJSON) TLSEnabled() bool {
+	return strings.TrimSpace(c.RootCert) != ""
+}
+
+// validateChaincodeTLS checks that the chaincode.json file contains either all
+// of the TLS certificates the chaincode needs to connect to the peer, or none
+// of them if the peer does not use TLS.
+func validateChaincodeTLS(chaincodeData *ChaincodeJSON) error {
+	certs := map[string]string{
+		"client_cert": chaincodeData.ClientCert,
+		"client_key":  chaincodeData.ClientKey,
+		"root_cert":   chaincodeData.RootCert,
+	}
+
+	var missing []string
+
+	for _, field := range slices.Sorted(maps.Keys(certs)) {
+		if strings.TrimSpace(certs[field]) == "" {
+			missing = append(missing, "'"+field+"'")
+		}
+	}
+
+	if len(missing) == 0 || len(missing) == len(certs) {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"must contain all of 'client_cert', 'client_key', and 'root_cert' for a TLS peer connection, or none of them for a plain text peer connection: missing %s",
+		strings.Join(missing, ", "),
+	)
+}
+
+// ReadImageJSON reads and parses the image.json file in the provided directory,
+// and checks that the chaincode image is allowed by the image policy.
+func ReadImageJSON(logger *log.CmdLogger, dir string, imagePolicy *ImagePolicy) (*ImageJSON, error) {
 	imageJSONPath := filepath.Join(dir, ImageFile)
+
+	imageData, err := readImageJSONFile(logger, imageJSONPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(imageData.Name) == 0 || len(imageData.Digest) == 0 {
+		if len(imageData.Tag) > 0 {
+			return nil, fmt.Errorf("%s file must contain a 'digest' unless image tag resolution is enabled", imageJSONPath)
+		}
+
+		return nil, fmt.Errorf("%s file must contain 'name' and 'digest'", imageJSONPath)
+	}
+
+	if _, err := registryv1.NewHash(imageData.Digest); err != nil {
+		return nil, fmt.Errorf(
+			"%s file contains invalid 'digest' %s, which must be an algorithm and hex encoded value, e.g. sha256:<64 hex characters>: %w",
+			imageJSONPath,
+			imageData.Digest,
+			err,
+		)
+	}
+
+	if err := validateImageJSON(imageJSONPath, imageData); err != nil {
+		return nil, err
+	}
+
+	if err := imagePolicy.CheckImage(imageData); err != nil {
+		return nil, err
+	}
+
+	return imageData, nil
+}
+
+func readImageJSONFile(logger *log.CmdLogger, imageJSONPath string) (*ImageJSON, error) {
 	logger.Debugf("Reading %s...", imageJSONPath)
 
 	imageJSONContents, err := os.ReadFile(imageJSONPath)
@@ -76,13 +216,38 @@ func ReadImageJSON(logger *log.CmdLogger, dir string) (*ImageJSON, error) {
 		return nil, fmt.Errorf("unable to parse %s: %w", imageJSONPath, err)
 	}
 
-	logger.Debugf("Image name: %s\nImage digest: %s\n", imageData.Name, imageData.Digest)
+	logger.Debugf("Image name: %s\nImage tag: %s\nImage digest: %s\n", imageData.Name, imageData.Tag, imageData.Digest)
 
-	if len(imageData.Name) == 0 || len(imageData.Digest) == 0 {
-		return nil, fmt.Errorf("%s file must contain 'name' and 'digest'", imageJSONPath)
+	return &imageData, nil
+}
+
+// validateImageJSON validates the optional fields in the image.json file.
+func validateImageJSON(imageJSONPath string, imageData *ImageJSON) error {
+	if imageData.Tag != "" {
+		if _, err := name.NewTag(imageData.Name + ":" + imageData.Tag); err != nil {
+			return fmt.Errorf("%s file contains invalid 'tag': %w", imageJSONPath, err)
+		}
 	}
 
-	return &imageData, nil
+	if imageData.ImagePullSecret != "" {
+		if msgs := apivalidation.NameIsDNSSubdomain(imageData.ImagePullSecret, false); len(msgs) > 0 {
+			return fmt.Errorf("%s file contains invalid 'imagePullSecret': %s", imageJSONPath, msgs[0])
+		}
+	}
+
+	if _, err := ParseResourceRequirements(imageData.Resources); err != nil {
+		return fmt.Errorf("%s file contains invalid 'resources': %w", imageJSONPath, err)
+	}
+
+	if _, err := ParseProbes(imageData.Probes); err != nil {
+		return fmt.Errorf("%s file contains invalid 'probes': %w", imageJSONPath, err)
+	}
+
+	if err := imageData.ChaincodeConfig.validate(); err != nil {
+		return fmt.Errorf("%s file contains invalid chaincode configuration: %w", imageJSONPath, err)
+	}
+
+	return nil
 }
 
 // ReadMetadataJSON reads and parses the metadata.json file in the provided directory.
@@ -108,3 +273,74 @@ func ReadMetadataJSON(logger *log.CmdLogger, dir string) (*MetadataJSON, error)
 
 	return &metadata, nil
 }
+
+// ReadBuildJSON reads and parses the build.json file in the provided directory.
+func ReadBuildJSON(logger *log.CmdLogger, dir string) (*BuildJSON, error) {
+	buildJSONPath := filepath.Join(dir, BuildFile)
+	logger.Debugf("Reading %s...", buildJSONPath)
+
+	buildJSONContents, err := os.ReadFile(buildJSONPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", buildJSONPath, err)
+	}
+
+	var buildData BuildJSON
+	if err := json.Unmarshal(buildJSONContents, &buildData); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", buildJSONPath, err)
+	}
+
+	logger.Debugf("Package ID: %s\n", buildData.PackageID)
+
+	if NewChaincodePackageID(buildData.PackageID).Hash == "" {
+		return nil, fmt.Errorf("%s file must contain a valid 'package_id'", buildJSONPath)
+	}
+
+	return &buildData, nil
+}
+
+// WriteBuildJSON writes a build.json file for the provided chaincode package
+// ID to the provided directory.
+func WriteBuildJSON(logger *log.CmdLogger, dir, packageID string) error {
+	buildJSONPath := filepath.Join(dir, BuildFile)
+	logger.Debugf("Writing %s...", buildJSONPath)
+
+	buildJSONContents, err := json.Marshal(&BuildJSON{PackageID: packageID})
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", buildJSONPath, err)
+	}
+
+	if err := os.WriteFile(buildJSONPath, buildJSONContents, 0o600); err != nil {
+		return fmt.Errorf("unable to write %s: %w", buildJSONPath, err)
+	}
+
+	return nil
+}
+
+// WriteConnectionJSON writes a connection.json file for the provided chaincode
+// server address to the chaincode/server directory in the provided directory.
+func WriteConnectionJSON(logger *log.CmdLogger, dir, address string) error {
+	connectionDir := filepath.Join(dir, "chaincode", "server")
+	connectionJSONPath := filepath.Join(connectionDir, ConnectionFile)
+	logger.Debugf("Writing %s...", connectionJSONPath)
+
+	connectionJSONContents, err := json.Marshal(&ConnectionJSON{
+		Address:     address,
+		DialTimeout: connectionDialTimeout,
+		TLSRequired: false,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", connectionJSONPath, err)
+	}
+
+	if err := os.MkdirAll(connectionDir, 0o750); err != nil {
+		return fmt.Errorf("unable to create directory %s: %w", connectionDir, err)
+	}
+
+	if err := os.WriteFile(connectionJSONPath, connectionJSONContents, 0o600); err != nil {
+		return fmt.Errorf("unable to write %s: %w", connectionJSONPath, err)
+	}
+
+	logger.Debugf("Chaincode server address: %s\n", address)
+
+	return nil
+}
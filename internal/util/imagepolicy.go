@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	// RegexImageRulePrefix identifies image allowlist and denylist rules which
+	// are regular expressions rather than glob patterns.
+	RegexImageRulePrefix = "regex:"
+
+	dockerHubRegistry = "docker.io"
+)
+
+// ImagePolicy restricts which registries and repositories chaincode images
+// can be pulled from.
+type ImagePolicy struct {
+	allow []imageRule
+	deny  []imageRule
+}
+
+// imageRule is a glob pattern or regular expression which matches chaincode
+// image repositories, e.g. ghcr.io/hyperledger-labs/*. Glob patterns without a
+// slash match registries, e.g. ghcr.io.
+type imageRule struct {
+	rule    string
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// NewImagePolicy returns a new ImagePolicy for the provided allowlist and
+// denylist rules. Chaincode images matching a denylist rule are rejected and,
+// if there are any allowlist rules, chaincode images must match at least one
+// of them.
+func NewImagePolicy(allowlist, denylist []string) (*ImagePolicy, error) {
+	allow, err := parseImageRules(allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image allowlist: %w", err)
+	}
+
+	deny, err := parseImageRules(denylist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image denylist: %w", err)
+	}
+
+	return &ImagePolicy{allow: allow, deny: deny}, nil
+}
+
+// CheckImage returns an error naming the rule which rejected the chaincode
+// image, if the image policy does not allow it. A nil policy allows all
+// images.
+func (p *ImagePolicy) CheckImage(imageData *ImageJSON) error {
+	if p == nil || (len(p.allow) == 0 && len(p.deny) == 0) {
+		return nil
+	}
+
+	ref, err := name.ParseReference(imageData.Name)
+	if err != nil {
+		return fmt.Errorf("invalid chaincode image %s: %w", imageData.Name, err)
+	}
+
+	registry, repository := getImageRepository(ref.Context())
+
+	for _, rule := range p.deny {
+		if rule.matches(registry, repository) {
+			return fmt.Errorf("chaincode image %s is not allowed by image denylist rule '%s'", imageData.Name, rule.rule)
+		}
+	}
+
+	if len(p.allow) == 0 {
+		return nil
+	}
+
+	for _, rule := range p.allow {
+		if rule.matches(registry, repository) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("chaincode image %s is not allowed by any image allowlist rule", imageData.Name)
+}
+
+// matches returns true if the rule matches the registry or repository.
+func (r imageRule) matches(registry, repository string) bool {
+	if r.regex != nil {
+		return r.regex.MatchString(repository)
+	}
+
+	if !strings.Contains(r.pattern, "/") {
+		matched, _ := path.Match(r.pattern, registry)
+
+		return matched
+	}
+
+	matched, _ := path.Match(r.pattern, repository)
+
+	return matched
+}
+
+func parseImageRules(rules []string) ([]imageRule, error) {
+	imageRules := make([]imageRule, 0, len(rules))
+
+	for _, rule := range rules {
+		if expression, ok := strings.CutPrefix(rule, RegexImageRulePrefix); ok {
+			regex, err := regexp.Compile("^(?:" + expression + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("rule '%s' is not a valid regular expression: %w", rule, err)
+			}
+
+			imageRules = append(imageRules, imageRule{rule: rule, regex: regex})
+
+			continue
+		}
+
+		if _, err := path.Match(rule, ""); err != nil || rule == "" {
+			return nil, fmt.Errorf("rule '%s' is not a valid glob pattern", rule)
+		}
+
+		imageRules = append(imageRules, imageRule{rule: rule, pattern: rule})
+	}
+
+	return imageRules, nil
+}
+
+// getImageRepository returns the registry and full repository name of a
+// chaincode image, using docker.io for Docker Hub images, e.g. docker.io and
+// docker.io/library/nginx.
+func getImageRepository(repository name.Repository) (string, string) {
+	registry := repository.RegistryStr()
+	if registry == name.DefaultRegistry {
+		registry = dockerHubRegistry
+	}
+
+	return registry, registry + "/" + repository.RepositoryStr()
+}
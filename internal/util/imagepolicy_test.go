@@ -0,0 +1,58 @@
+package util_test
+
+import (
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ImagePolicy", func() {
+	Describe("NewImagePolicy", func() {
+		It("should return an error for an invalid glob pattern", func() {
+			_, err := util.NewImagePolicy([]string{"ghcr.io/["}, nil)
+			Expect(err).To(MatchError("invalid image allowlist: rule 'ghcr.io/[' is not a valid glob pattern"))
+		})
+
+		It("should return an error for an empty rule", func() {
+			_, err := util.NewImagePolicy(nil, []string{""})
+			Expect(err).To(MatchError("invalid image denylist: rule '' is not a valid glob pattern"))
+		})
+
+		It("should return an error for an invalid regular expression", func() {
+			_, err := util.NewImagePolicy(nil, []string{"regex:ghcr\\.io/(.*"})
+			Expect(err).To(MatchError(ContainSubstring("invalid image denylist: rule 'regex:ghcr\\.io/(.*' is not a valid regular expression")))
+		})
+	})
+
+	DescribeTable("CheckImage",
+		func(allowlist, denylist []string, imageName, expectedError string) {
+			imagePolicy, err := util.NewImagePolicy(allowlist, denylist)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = imagePolicy.CheckImage(&util.ImageJSON{Name: imageName})
+			if expectedError == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(expectedError))
+			}
+		},
+		Entry("When there are no rules", nil, nil, "ghcr.io/hyperledger-labs/go-contract", ""),
+		Entry("When the registry is allowed", []string{"ghcr.io"}, nil, "ghcr.io/hyperledger-labs/go-contract", ""),
+		Entry("When the repository is allowed", []string{"ghcr.io/hyperledger-labs/*"}, nil, "ghcr.io/hyperledger-labs/go-contract", ""),
+		Entry("When the repository is allowed by a regular expression", []string{"regex:ghcr\\.io/hyperledger(-labs)?/.+"}, nil, "ghcr.io/hyperledger-labs/go-contract", ""),
+		Entry("When a Docker Hub image is allowed", []string{"docker.io/library/*"}, nil, "nginx", ""),
+		Entry("When the repository is not allowed", []string{"ghcr.io/hyperledger/*"}, nil, "ghcr.io/hyperledger-labs/go-contract", "chaincode image ghcr.io/hyperledger-labs/go-contract is not allowed by any image allowlist rule"),
+		Entry("When a glob pattern does not match nested repositories", []string{"ghcr.io/*"}, nil, "ghcr.io/hyperledger-labs/go-contract", "chaincode image ghcr.io/hyperledger-labs/go-contract is not allowed by any image allowlist rule"),
+		Entry("When a regular expression only matches part of the repository", []string{"regex:ghcr\\.io"}, nil, "ghcr.io/hyperledger-labs/go-contract", "chaincode image ghcr.io/hyperledger-labs/go-contract is not allowed by any image allowlist rule"),
+		Entry("When the registry is denied", nil, []string{"docker.io"}, "nginx", "chaincode image nginx is not allowed by image denylist rule 'docker.io'"),
+		Entry("When the repository is denied", []string{"ghcr.io"}, []string{"ghcr.io/example/*"}, "ghcr.io/example/go-contract", "chaincode image ghcr.io/example/go-contract is not allowed by image denylist rule 'ghcr.io/example/*'"),
+		Entry("When the repository is denied by a regular expression", nil, []string{"regex:.*/untrusted-.*"}, "ghcr.io/hyperledger-labs/untrusted-contract", "chaincode image ghcr.io/hyperledger-labs/untrusted-contract is not allowed by image denylist rule 'regex:.*/untrusted-.*'"),
+		Entry("When the repository is not denied", nil, []string{"ghcr.io/example/*"}, "ghcr.io/hyperledger-labs/go-contract", ""),
+	)
+
+	It("should allow all images for a nil image policy", func() {
+		var imagePolicy *util.ImagePolicy
+
+		Expect(imagePolicy.CheckImage(&util.ImageJSON{Name: "nginx"})).To(Succeed())
+	})
+})
@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	typedBatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// Existing job policies.
+	ExistingJobPolicyAdopt   string = "adopt"
+	ExistingJobPolicyReplace string = "replace"
+	ExistingJobPolicyIgnore  string = "ignore"
+
+	DefaultExistingJobPolicy string = ExistingJobPolicyReplace
+
+	DefaultRestartPolicy string = string(apiv1.RestartPolicyNever)
+	DefaultBackoffLimit  string = "0"
+)
+
+// RetryPolicy configures how Kubernetes retries chaincode which fails.
+type RetryPolicy struct {
+	// RestartPolicy is the chaincode pod restart policy, either Never or
+	// OnFailure. Defaults to Never.
+	RestartPolicy apiv1.RestartPolicy
+
+	// BackoffLimit is the number of retries before the chaincode job fails.
+	BackoffLimit int32
+
+	// IgnoreDisruptions prevents pod failures caused by disruptions, such as
+	// preemption or node drains, counting towards the backoff limit.
+	IgnoreDisruptions bool
+
+	// FailJobExitCodes are chaincode container exit codes which fail the
+	// chaincode job immediately, without any more retries.
+	FailJobExitCodes []int32
+}
+
+var errPodFailurePolicyRestartPolicy = errors.New("pod failure policy rules require the Never restart policy")
+
+// getRestartPolicy returns the pod restart policy, defaulting to Never.
+func (p RetryPolicy) getRestartPolicy() apiv1.RestartPolicy {
+	if p.RestartPolicy == "" {
+		return apiv1.RestartPolicyNever
+	}
+
+	return p.RestartPolicy
+}
+
+// getPodFailurePolicy returns the job pod failure policy, or nil if there are
+// no pod failure policy rules.
+func (p RetryPolicy) getPodFailurePolicy() (*batchv1.PodFailurePolicy, error) {
+	var rules []batchv1.PodFailurePolicyRule
+
+	if len(p.FailJobExitCodes) > 0 {
+		rules = append(rules, batchv1.PodFailurePolicyRule{
+			Action: batchv1.PodFailurePolicyActionFailJob,
+			OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+				ContainerName: ptr.To(chaincodeContainerName),
+				Operator:      batchv1.PodFailurePolicyOnExitCodesOpIn,
+				Values:        p.FailJobExitCodes,
+			},
+		})
+	}
+
+	if p.IgnoreDisruptions {
+		rules = append(rules, batchv1.PodFailurePolicyRule{
+			Action: batchv1.PodFailurePolicyActionIgnore,
+			OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{
+				{
+					Type:   apiv1.DisruptionTarget,
+					Status: apiv1.ConditionTrue,
+				},
+			},
+		})
+	}
+
+	if len(rules) == 0 {
+		return nil, nil //nolint:nilnil // a nil pod failure policy is valid
+	}
+
+	if p.getRestartPolicy() != apiv1.RestartPolicyNever {
+		return nil, errPodFailurePolicyRestartPolicy
+	}
+
+	return &batchv1.PodFailurePolicy{Rules: rules}, nil
+}
+
+// setRetryPolicy configures the restart policy, backoff limit, and pod failure
+// policy for the chaincode job.
+func setRetryPolicy(job *batchv1.Job, retryPolicy RetryPolicy) error {
+	podFailurePolicy, err := retryPolicy.getPodFailurePolicy()
+	if err != nil {
+		return err
+	}
+
+	job.Spec.Template.Spec.RestartPolicy = retryPolicy.getRestartPolicy()
+	job.Spec.BackoffLimit = ptr.To(retryPolicy.BackoffLimit)
+	job.Spec.PodFailurePolicy = podFailurePolicy
+
+	return nil
+}
+
+// isJobFinished returns true if the job has completed or failed.
+func isJobFinished(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == apiv1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isJobReady returns true if the job has a ready pod and is not about to
+// terminate. Jobs with active pods which are not ready, for example because
+// they are pending or crash looping, may never run the chaincode successfully.
+func isJobReady(job *batchv1.Job) bool {
+	return job.Status.Active > 0 && ptr.Deref(job.Status.Ready, 0) > 0 && !isJobTerminating(job)
+}
+
+// IsChaincodeJobRunning returns false if the chaincode job has finished, or
+// has been deleted, so that it no longer needs the chaincode credentials.
+func IsChaincodeJobRunning(ctx context.Context, jobsClient typedBatchv1.JobInterface, job *batchv1.Job) (bool, error) {
+	current, err := jobsClient.Get(ctx, job.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return true, fmt.Errorf("error getting chaincode job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+
+	if current.UID != job.UID || current.DeletionTimestamp != nil {
+		return false, nil
+	}
+
+	return !isJobFinished(current), nil
+}
+
+// isJobTerminating returns true if the job has finished, or will fail once its
+// remaining pods have terminated.
+func isJobTerminating(job *batchv1.Job) bool {
+	if isJobFinished(job) {
+		return true
+	}
+
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobFailureTarget || c.Type == batchv1.JobSuccessCriteriaMet) && c.Status == apiv1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getExistingChaincodeJobs returns the unfinished jobs which were created for
+// the same peer and chaincode, newest first.
+func getExistingChaincodeJobs(
+	ctx context.Context,
+	jobsClient typedBatchv1.JobInterface,
+	objectName, peerID string,
+	chaincodeData *ChaincodeJSON,
+) ([]batchv1.Job, error) {
+	chaincodeLabels, err := getLabels(chaincodeData)
+	if err != nil {
+		return nil, fmt.Errorf("error getting chaincode job labels for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{
+		managedByLabel:    chaincodeLabels[managedByLabel],
+		packageLabelLabel: chaincodeLabels[packageLabelLabel],
+		packageHashLabel:  chaincodeLabels[packageHashLabel],
+	})
+
+	jobs, err := jobsClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing chaincode jobs for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	existingJobs := slices.DeleteFunc(jobs.Items, func(job batchv1.Job) bool {
+		return job.Annotations[peerIDAnnotation] != peerID ||
+			!strings.HasPrefix(job.Name, objectName+"-") ||
+			job.DeletionTimestamp != nil ||
+			isJobFinished(&job)
+	})
+
+	slices.SortFunc(existingJobs, func(a, b batchv1.Job) int {
+		return b.CreationTimestamp.Compare(a.CreationTimestamp.Time)
+	})
+
+	return existingJobs, nil
+}
+
+// AdoptExistingChaincodeJob looks for unfinished jobs which were previously
+// created for the same peer and chaincode, for example before the peer was
+// restarted. Depending on the existing job policy, the newest job with a ready
+// chaincode pod is returned so that it can be reused, and any other existing
+// jobs are deleted.
+// Nil is returned if there is no job to reuse.
+func AdoptExistingChaincodeJob(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	jobsClient typedBatchv1.JobInterface,
+	objectName, peerID, policy string,
+	chaincodeData *ChaincodeJSON,
+) (*batchv1.Job, error) {
+	if policy == ExistingJobPolicyIgnore {
+		return nil, nil //nolint:nilnil // there is no existing job when they are ignored
+	}
+
+	existingJobs, err := getExistingChaincodeJobs(ctx, jobsClient, objectName, peerID, chaincodeData)
+	if err != nil {
+		return nil, err
+	}
+
+	var adoptedJob *batchv1.Job
+
+	var errs []error
+
+	for i := range existingJobs {
+		job := &existingJobs[i]
+
+		if policy == ExistingJobPolicyAdopt && adoptedJob == nil && isJobReady(job) {
+			logger.Debugf("Adopting existing chaincode job %s/%s for chaincode ID %s", job.Namespace, job.Name, chaincodeData.ChaincodeID)
+
+			adoptedJob = job
+
+			continue
+		}
+
+		logger.Printf("Deleting stale chaincode job %s/%s for chaincode ID %s", job.Namespace, job.Name, chaincodeData.ChaincodeID)
+
+		err := jobsClient.Delete(ctx, job.Name, metav1.DeleteOptions{
+			PropagationPolicy: ptr.To(metav1.DeletePropagationForeground),
+			Preconditions:     &metav1.Preconditions{UID: ptr.To(job.UID)},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error deleting stale chaincode job %s/%s: %w", job.Namespace, job.Name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return adoptedJob, nil
+}
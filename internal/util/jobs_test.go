@@ -0,0 +1,224 @@
+package util_test
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	typedBatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Jobs", func() {
+	Describe("CreateChaincodeJob with a retry policy", func() {
+		var (
+			ctx           context.Context
+			logger        *log.CmdLogger
+			jobsClient    typedBatchv1.JobInterface
+			chaincodeData *util.ChaincodeJSON
+			imageData     *util.ImageJSON
+		)
+
+		BeforeEach(func() {
+			ctx = log.NewCmdContext(context.Background(), false)
+			logger = log.New(ctx)
+			jobsClient = fake.NewClientset().BatchV1().Jobs("chaincode")
+			chaincodeData = &util.ChaincodeJSON{
+				ChaincodeID: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b",
+				PeerAddress: "peer0.org1.example.com",
+				MspID:       "CongaOrg",
+			}
+			imageData = &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+			}
+		})
+
+		It("should not retry chaincode by default", func() {
+			job, err := util.CreateChaincodeJob(ctx, logger, jobsClient, "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(apiv1.RestartPolicyNever))
+			Expect(*job.Spec.BackoffLimit).To(BeEquivalentTo(0))
+			Expect(job.Spec.PodFailurePolicy).To(BeNil())
+		})
+
+		It("should set the restart policy and backoff limit", func() {
+			retryPolicy := util.RetryPolicy{RestartPolicy: apiv1.RestartPolicyOnFailure, BackoffLimit: 3}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, jobsClient, "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, retryPolicy, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(apiv1.RestartPolicyOnFailure))
+			Expect(*job.Spec.BackoffLimit).To(BeEquivalentTo(3))
+		})
+
+		It("should set pod failure policy rules", func() {
+			retryPolicy := util.RetryPolicy{BackoffLimit: 3, IgnoreDisruptions: true, FailJobExitCodes: []int32{42}}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, jobsClient, "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, retryPolicy, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.PodFailurePolicy).NotTo(BeNil())
+			Expect(job.Spec.PodFailurePolicy.Rules).To(HaveLen(2))
+			Expect(job.Spec.PodFailurePolicy.Rules[0].Action).To(Equal(batchv1.PodFailurePolicyActionFailJob))
+			Expect(job.Spec.PodFailurePolicy.Rules[0].OnExitCodes.Values).To(ConsistOf(int32(42)))
+			Expect(job.Spec.PodFailurePolicy.Rules[1].Action).To(Equal(batchv1.PodFailurePolicyActionIgnore))
+			Expect(job.Spec.PodFailurePolicy.Rules[1].OnPodConditions[0].Type).To(Equal(apiv1.DisruptionTarget))
+		})
+
+		It("should return an error for pod failure policy rules without the Never restart policy", func() {
+			retryPolicy := util.RetryPolicy{RestartPolicy: apiv1.RestartPolicyOnFailure, IgnoreDisruptions: true}
+
+			_, err := util.CreateChaincodeJob(ctx, logger, jobsClient, "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, retryPolicy, nil)
+			Expect(err).To(MatchError(ContainSubstring("pod failure policy rules require the Never restart policy")))
+		})
+	})
+
+	Describe("AdoptExistingChaincodeJob", func() {
+		const objectName = "hlfcc-fabcar-s6pwkq6bepi2e"
+
+		var (
+			ctx           context.Context
+			logger        *log.CmdLogger
+			jobsClient    typedBatchv1.JobInterface
+			chaincodeData *util.ChaincodeJSON
+			imageData     *util.ImageJSON
+		)
+
+		createJob := func(peerID string, status batchv1.JobStatus) *batchv1.Job {
+			job, err := util.CreateChaincodeJob(ctx, logger, jobsClient, objectName, "chaincode", "default", "", peerID, chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			job.Status = status
+			job, err = jobsClient.UpdateStatus(ctx, job, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			return job
+		}
+
+		jobNames := func() []string {
+			jobs, err := jobsClient.List(ctx, metav1.ListOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			names := make([]string, 0, len(jobs.Items))
+			for _, job := range jobs.Items {
+				names = append(names, job.Name)
+			}
+
+			return names
+		}
+
+		BeforeEach(func() {
+			ctx = log.NewCmdContext(context.Background(), false)
+			logger = log.New(ctx)
+			jobsClient = fake.NewClientset().BatchV1().Jobs("chaincode")
+			chaincodeData = &util.ChaincodeJSON{
+				ChaincodeID: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b",
+				PeerAddress: "peer0.org1.example.com",
+				MspID:       "CongaOrg",
+			}
+			imageData = &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+			}
+		})
+
+		It("should return nil if there are no existing jobs", func() {
+			job, err := util.AdoptExistingChaincodeJob(ctx, logger, jobsClient, objectName, "CongaOrgPeer0", util.ExistingJobPolicyAdopt, chaincodeData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).To(BeNil())
+		})
+
+		It("should adopt a ready job and delete other unfinished jobs with the adopt policy", func() {
+			pendingJob := createJob("CongaOrgPeer0", batchv1.JobStatus{})
+			readyJob := createJob("CongaOrgPeer0", batchv1.JobStatus{Active: 1, Ready: ptr.To[int32](1)})
+
+			job, err := util.AdoptExistingChaincodeJob(ctx, logger, jobsClient, objectName, "CongaOrgPeer0", util.ExistingJobPolicyAdopt, chaincodeData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).NotTo(BeNil())
+			Expect(job.Name).To(Equal(readyJob.Name))
+			Expect(jobNames()).NotTo(ContainElement(pendingJob.Name))
+		})
+
+		It("should not adopt an active job without a ready pod with the adopt policy", func() {
+			crashLoopingJob := createJob("CongaOrgPeer0", batchv1.JobStatus{Active: 1, Ready: ptr.To[int32](0)})
+
+			job, err := util.AdoptExistingChaincodeJob(ctx, logger, jobsClient, objectName, "CongaOrgPeer0", util.ExistingJobPolicyAdopt, chaincodeData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).To(BeNil())
+			Expect(jobNames()).NotTo(ContainElement(crashLoopingJob.Name))
+		})
+
+		It("should delete unfinished jobs with the replace policy", func() {
+			activeJob := createJob("CongaOrgPeer0", batchv1.JobStatus{Active: 1})
+
+			job, err := util.AdoptExistingChaincodeJob(ctx, logger, jobsClient, objectName, "CongaOrgPeer0", util.ExistingJobPolicyReplace, chaincodeData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).To(BeNil())
+			Expect(jobNames()).NotTo(ContainElement(activeJob.Name))
+		})
+
+		It("should not change existing jobs with the ignore policy", func() {
+			activeJob := createJob("CongaOrgPeer0", batchv1.JobStatus{Active: 1})
+
+			job, err := util.AdoptExistingChaincodeJob(ctx, logger, jobsClient, objectName, "CongaOrgPeer0", util.ExistingJobPolicyIgnore, chaincodeData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).To(BeNil())
+			Expect(jobNames()).To(ContainElement(activeJob.Name))
+		})
+
+		It("should not adopt or delete finished jobs or jobs for other peers", func() {
+			finishedJob := createJob("CongaOrgPeer0", batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: apiv1.ConditionTrue}},
+			})
+			otherPeerJob := createJob("CongaOrgPeer1", batchv1.JobStatus{Active: 1})
+
+			job, err := util.AdoptExistingChaincodeJob(ctx, logger, jobsClient, objectName, "CongaOrgPeer0", util.ExistingJobPolicyAdopt, chaincodeData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).To(BeNil())
+			Expect(jobNames()).To(ConsistOf(finishedJob.Name, otherPeerJob.Name))
+		})
+	})
+
+	Describe("IsChaincodeJobRunning", func() {
+		var (
+			ctx        context.Context
+			jobsClient typedBatchv1.JobInterface
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			jobsClient = fake.NewClientset(
+				&batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: "hlfcc-running-abcde", Namespace: "chaincode", UID: "running"},
+					Status:     batchv1.JobStatus{Active: 1},
+				},
+				&batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: "hlfcc-finished-abcde", Namespace: "chaincode", UID: "finished"},
+					Status: batchv1.JobStatus{
+						Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: apiv1.ConditionTrue}},
+					},
+				},
+			).BatchV1().Jobs("chaincode")
+		})
+
+		DescribeTable("should return whether the chaincode job still needs its credentials",
+			func(name, uid string, expectedRunning bool) {
+				job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "chaincode", UID: types.UID(uid)}}
+
+				running, err := util.IsChaincodeJobRunning(ctx, jobsClient, job)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(running).To(Equal(expectedRunning))
+			},
+			Entry("When the job is running", "hlfcc-running-abcde", "running", true),
+			Entry("When the job has finished", "hlfcc-finished-abcde", "finished", false),
+			Entry("When the job has been deleted", "hlfcc-deleted-abcde", "deleted", false),
+			Entry("When the job has been replaced", "hlfcc-running-abcde", "replaced", false),
+		)
+	})
+})
@@ -5,21 +5,29 @@ package util
 import (
 	"context"
 	"encoding/base32"
-	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/metrics"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
 	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	applycorev1 "k8s.io/client-go/applyconfigurations/core/v1"
 	"k8s.io/client-go/kubernetes"
@@ -27,6 +35,7 @@ import (
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	watchtools "k8s.io/client-go/tools/watch"
 	"k8s.io/utils/ptr"
 )
@@ -37,13 +46,37 @@ const (
 	namespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 	jobTTL        = 5 * time.Minute
 
+	podDeletionPollInterval = 500 * time.Millisecond
+
+	chaincodeContainerName = "chaincode"
+	certsVolumeName        = "certs"
+	certsMountPath         = "/etc/hyperledger/fabric"
+
+	managedByLabel        = "app.kubernetes.io/managed-by"
+	packageLabelLabel     = "fabric-builder-k8s-cclabel"
+	packageHashLabel      = "fabric-builder-k8s-cchash"
+	chaincodeIDAnnotation = "fabric-builder-k8s-ccid"
+	mspIDAnnotation       = "fabric-builder-k8s-mspid"
+	peerAddressAnnotation = "fabric-builder-k8s-peeraddress"
+	peerIDAnnotation      = "fabric-builder-k8s-peerid"
+
+	traceContextAnnotationPrefix = "fabric-builder-k8s-"
+
 	ObjectNameSuffixLength int = 5
 
+	// Chaincode job termination reasons, in addition to the reasons from
+	// failed job conditions.
+	JobReasonCompleted    string = "Completed"
+	JobReasonStopped      string = "Stopped"
+	JobReasonStartTimeout string = "StartTimeout"
+	JobReasonError        string = "Error"
+
 	// Defaults.
-	DefaultNamespace          string = "default"
-	DefaultObjectNamePrefix   string = "hlfcc"
-	DefaultServiceAccountName string = "default"
-	DefaultStartTimeout       string = "3m"
+	DefaultNamespace           string = "default"
+	DefaultObjectNamePrefix    string = "hlfcc"
+	DefaultServiceAccountName  string = "default"
+	DefaultStartTimeout        string = "3m"
+	DefaultShutdownGracePeriod string = "4s"
 
 	// Mutual TLS auth client key and cert paths in the chaincode container.
 	TLSClientKeyPath      string = "/etc/hyperledger/fabric/client.key"
@@ -66,7 +99,10 @@ func waitForJob(
 	ctx, cancel := watchtools.ContextWithOptionalTimeout(ctx, timeout)
 	defer cancel()
 
+	ctx, span := tracing.Start(ctx, "k8s.jobs.watch", tracing.NamespaceKey.String(namespace), tracing.JobNameKey.String(jobName))
 	event, err := watchtools.UntilWithSync(ctx, listWatch, &batchv1.Job{}, nil, conditionFunc)
+	tracing.End(span, err)
+
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +128,7 @@ func waitForJobStart(
 ) (*batchv1.JobStatus, error) {
 	jobStartedCondition := func(event watch.Event) (bool, error) {
 		logger.Debugf("Event for job %s/%s: type=%v, object=%T", namespace, jobName, event.Type, event.Object)
+		traceObject(logger, fmt.Sprintf("Event object for job %s/%s", namespace, jobName), event.Object)
 
 		if event.Type == watch.Deleted {
 			return false, fmt.Errorf(
@@ -113,7 +150,13 @@ func waitForJobStart(
 
 		logger.Debugf("Status for job %s/%s: active=%v, ready=%v, succeeded=%v, failed=%v", namespace, jobName, job.Status.Active, ptr.Deref(job.Status.Ready, 0), job.Status.Succeeded, job.Status.Failed)
 
-		if job.Status.Active > 0 && ptr.Deref(job.Status.Ready, 0) > 0 && job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+		// Previous chaincode pods may have failed if the job has a backoff limit,
+		// and there is no need to wait for a job which has already finished
+		if job.Status.Active > 0 && ptr.Deref(job.Status.Ready, 0) > 0 && job.Status.Succeeded == 0 {
+			return true, nil
+		}
+
+		if isJobTerminating(job) {
 			return true, nil
 		}
 
@@ -129,8 +172,11 @@ func waitForJobTermination(
 	client cache.Getter,
 	jobName, namespace string,
 ) (*batchv1.JobStatus, error) {
+	var failed int32
+
 	jobTerminationCondition := func(event watch.Event) (bool, error) {
 		logger.Debugf("Event for job %s/%s: type=%v, object=%T", namespace, jobName, event.Type, event.Object)
+		traceObject(logger, fmt.Sprintf("Event object for job %s/%s", namespace, jobName), event.Object)
 
 		if event.Type == watch.Deleted {
 			return false, fmt.Errorf(
@@ -152,61 +198,142 @@ func waitForJobTermination(
 
 		logger.Debugf("Status for job %s/%s: active=%v, ready=%v, succeeded=%v, failed=%v", namespace, jobName, job.Status.Active, ptr.Deref(job.Status.Ready, 0), job.Status.Succeeded, job.Status.Failed)
 
-		if job.Status.Succeeded == 1 || job.Status.Failed == 1 {
+		// Failed pods are retried until the job reaches its backoff limit, so
+		// only the job conditions indicate whether the job has terminated
+		if isJobTerminating(job) {
 			return true, nil
 		}
 
+		if job.Status.Failed > failed {
+			failed = job.Status.Failed
+			logger.Warnf("Chaincode pod failed for job %s/%s, retrying (%d failed pods)", namespace, jobName, failed)
+		}
+
 		return false, nil
 	}
 
 	return waitForJob(ctx, client, jobName, namespace, jobTerminationCondition, 0)
 }
 
+// WaitForChaincodeJob waits for the chaincode job to start and then terminate,
+// recording events for the chaincode job as it does so. The termination reason
+// is recorded as the event reason.
 func WaitForChaincodeJob(
 	ctx context.Context,
 	logger *log.CmdLogger,
 	client cache.Getter,
+	recorder record.EventRecorder,
 	job *batchv1.Job,
 	chaincodeID string,
 	chaincodeStartTimeout time.Duration,
 ) error {
+	jobMetrics := metrics.FromContext(ctx)
+
 	logger.Debugf("Waiting for job %s/%s to start for chaincode ID %s", job.Namespace, job.Name, chaincodeID)
 
+	startTime := time.Now()
+
 	_, err := waitForJobStart(ctx, logger, client, job.Name, job.Namespace, chaincodeStartTimeout)
 	if err != nil {
-		return fmt.Errorf(
+		reason := getWaitErrorReason(ctx, err)
+		jobMetrics.JobStarted(reason, time.Since(startTime))
+		jobMetrics.JobTerminated(reason)
+
+		err = fmt.Errorf(
 			"error waiting for chaincode job %s/%s to start for chaincode ID %s: %w",
 			job.Namespace,
 			job.Name,
 			chaincodeID,
 			err,
 		)
+		recordJobTerminatedEvent(recorder, job, reason, err.Error())
+
+		return err
 	}
 
+	jobMetrics.JobStarted(metrics.ResultSuccess, time.Since(startTime))
+	jobMetrics.JobRunning(true)
+	defer jobMetrics.JobRunning(false)
+
+	recorder.Eventf(job, apiv1.EventTypeNormal, EventReasonChaincodeReady, "Chaincode pod ready for chaincode ID %s", chaincodeID)
+
 	logger.Debugf("Waiting for job %s/%s to terminate for chaincode ID %s", job.Namespace, job.Name, chaincodeID)
 
 	jobStatus, err := waitForJobTermination(ctx, logger, client, job.Name, job.Namespace)
 	if err != nil {
-		return fmt.Errorf(
+		reason := getWaitErrorReason(ctx, err)
+		jobMetrics.JobTerminated(reason)
+
+		err = fmt.Errorf(
 			"error waiting for chaincode job %s/%s to terminate for chaincode ID %s: %w",
 			job.Namespace,
 			job.Name,
 			chaincodeID,
 			err,
 		)
+		recordJobTerminatedEvent(recorder, job, reason, err.Error())
+
+		return err
 	}
 
 	for _, c := range jobStatus.Conditions {
 		logger.Debugf("Status condition for job %s/%s: type=%v, status=%v, reason=%v, message=%v", job.Namespace, job.Name, c.Type, c.Status, c.Reason, c.Message)
 
 		if (c.Type == batchv1.JobFailed || c.Type == batchv1.JobFailureTarget) && c.Status == apiv1.ConditionTrue {
-			return fmt.Errorf("chaincode job %s/%s for chaincode ID %s terminated for reason %s: %s", job.Namespace, job.Name, chaincodeID, c.Reason, c.Message)
+			jobMetrics.JobTerminated(c.Reason)
+
+			err = fmt.Errorf("chaincode job %s/%s for chaincode ID %s terminated for reason %s: %s", job.Namespace, job.Name, chaincodeID, c.Reason, c.Message)
+			recordJobTerminatedEvent(recorder, job, c.Reason, err.Error())
+
+			return err
 		}
 	}
 
+	jobMetrics.JobTerminated(JobReasonCompleted)
+	recordJobTerminatedEvent(recorder, job, JobReasonCompleted, fmt.Sprintf("Chaincode completed for chaincode ID %s", chaincodeID))
+
 	return nil
 }
 
+// recordJobTerminatedEvent records a warning event for the chaincode job unless
+// it completed, or was stopped by the peer.
+func recordJobTerminatedEvent(recorder record.EventRecorder, job *batchv1.Job, reason, message string) {
+	eventType := apiv1.EventTypeWarning
+	if reason == JobReasonCompleted || reason == JobReasonStopped {
+		eventType = apiv1.EventTypeNormal
+	}
+
+	recorder.Event(job, eventType, reason, message)
+}
+
+// getWaitErrorReason returns the reason waiting for a chaincode job failed.
+func getWaitErrorReason(ctx context.Context, err error) string {
+	switch {
+	case ctx.Err() != nil:
+		return JobReasonStopped
+	case wait.Interrupted(err):
+		return JobReasonStartTimeout
+	default:
+		return JobReasonError
+	}
+}
+
+// traceObject logs the JSON definition of a Kubernetes object at trace level.
+func traceObject(logger *log.CmdLogger, description string, object any) {
+	if !logger.TraceEnabled() {
+		return
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		logger.Tracef("%s could not be encoded: %v", description, err)
+
+		return
+	}
+
+	logger.Tracef("%s: %s", description, data)
+}
+
 // GetKubeClientset returns a client object for a provided kubeconfig filepath
 // if one is provided, or which uses the service account kubernetes gives to
 // pods otherwise.
@@ -253,18 +380,18 @@ func getLabels(chaincodeData *ChaincodeJSON) (map[string]string, error) {
 		"app.kubernetes.io/name":       "hyperledger-fabric",
 		"app.kubernetes.io/component":  "chaincode",
 		"app.kubernetes.io/created-by": fabricBuilderK8s,
-		"app.kubernetes.io/managed-by": fabricBuilderK8s,
-		"fabric-builder-k8s-cclabel":   packageID.Label,
-		"fabric-builder-k8s-cchash":    encodedPackageHash,
+		managedByLabel:                 fabricBuilderK8s,
+		packageLabelLabel:              packageID.Label,
+		packageHashLabel:               encodedPackageHash,
 	}, nil
 }
 
 func getAnnotations(peerID string, chaincodeData *ChaincodeJSON) map[string]string {
 	return map[string]string{
-		"fabric-builder-k8s-ccid":        chaincodeData.ChaincodeID,
-		"fabric-builder-k8s-mspid":       chaincodeData.MspID,
-		"fabric-builder-k8s-peeraddress": chaincodeData.PeerAddress,
-		"fabric-builder-k8s-peerid":      peerID,
+		chaincodeIDAnnotation: chaincodeData.ChaincodeID,
+		mspIDAnnotation:       chaincodeData.MspID,
+		peerAddressAnnotation: chaincodeData.PeerAddress,
+		peerIDAnnotation:      peerID,
 	}
 }
 
@@ -272,9 +399,9 @@ func getChaincodeJobSpec(
 	imageData *ImageJSON,
 	namespace, serviceAccount, objectName, peerID string,
 	chaincodeData *ChaincodeJSON,
+	resources apiv1.ResourceRequirements,
+	credentialStore CredentialStore,
 ) (*batchv1.Job, error) {
-	chaincodeImage := imageData.Name + "@" + imageData.Digest
-
 	jobName := objectName + "-" + rand.String(ObjectNameSuffixLength)
 
 	labels, err := getLabels(chaincodeData)
@@ -284,7 +411,10 @@ func getChaincodeJobSpec(
 
 	annotations := getAnnotations(peerID, chaincodeData)
 
-	return &batchv1.Job{
+	podTemplate := getChaincodePodTemplate(imageData, serviceAccount, labels, annotations, resources, getChaincodeEnv(chaincodeData))
+	podTemplate.Spec.RestartPolicy = apiv1.RestartPolicyNever
+
+	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        jobName,
 			Namespace:   namespace,
@@ -292,81 +422,124 @@ func getChaincodeJobSpec(
 			Annotations: annotations,
 		},
 		Spec: batchv1.JobSpec{
-			Template: apiv1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels:      labels,
-					Annotations: annotations,
-				},
-				Spec: apiv1.PodSpec{
-					ServiceAccountName: serviceAccount,
-					Containers: []apiv1.Container{
-						{
-							Name:  "chaincode",
-							Image: chaincodeImage,
-							VolumeMounts: []apiv1.VolumeMount{
-								{
-									Name:      "certs",
-									MountPath: "/etc/hyperledger/fabric",
-									ReadOnly:  true,
-								},
-							},
-							Env: []apiv1.EnvVar{
-								{
-									Name:  "CORE_CHAINCODE_ID_NAME",
-									Value: chaincodeData.ChaincodeID,
-								},
-								{
-									Name:  "CORE_PEER_ADDRESS",
-									Value: chaincodeData.PeerAddress,
-								},
-								{
-									Name:  "CORE_PEER_TLS_ENABLED",
-									Value: "true", // TODO only if there are certs?
-								},
-								{
-									Name:  "CORE_PEER_TLS_ROOTCERT_FILE",
-									Value: TLSClientRootCertFile,
-								},
-								{
-									Name:  "CORE_TLS_CLIENT_KEY_PATH",
-									Value: TLSClientKeyPath,
-								},
-								{
-									Name:  "CORE_TLS_CLIENT_CERT_PATH",
-									Value: TLSClientCertPath,
-								},
-								{
-									Name:  "CORE_TLS_CLIENT_KEY_FILE",
-									Value: TLSClientKeyFile,
-								},
-								{
-									Name:  "CORE_TLS_CLIENT_CERT_FILE",
-									Value: TLSClientCertFile,
-								},
-								{
-									Name:  "CORE_PEER_LOCALMSPID",
-									Value: chaincodeData.MspID,
-								},
-							},
-						},
-					},
-					RestartPolicy: apiv1.RestartPolicyNever,
-					Volumes: []apiv1.Volume{
-						{
-							Name: "certs",
-							VolumeSource: apiv1.VolumeSource{
-								Secret: &apiv1.SecretVolumeSource{
-									SecretName: objectName,
-								},
-							},
-						},
-					},
-				},
-			},
+			Template:                podTemplate,
 			BackoffLimit:            ptr.To[int32](0),
 			TTLSecondsAfterFinished: ptr.To[int32](int32(jobTTL / time.Second)),
 		},
-	}, nil
+	}
+
+	if chaincodeData.TLSEnabled() {
+		setChaincodeCredentials(&job.Spec.Template, credentialStore, objectName)
+	}
+
+	return job, nil
+}
+
+// getChaincodePodTemplate returns the chaincode pod template which is shared by
+// chaincode jobs and chaincode deployments.
+func getChaincodePodTemplate(
+	imageData *ImageJSON,
+	serviceAccount string,
+	labels, annotations map[string]string,
+	resources apiv1.ResourceRequirements,
+	env []apiv1.EnvVar,
+) apiv1.PodTemplateSpec {
+	return apiv1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: apiv1.PodSpec{
+			ServiceAccountName: serviceAccount,
+			Containers: []apiv1.Container{
+				{
+					Name:      chaincodeContainerName,
+					Image:     imageData.Name + "@" + imageData.Digest,
+					Resources: resources,
+					Env:       env,
+				},
+			},
+		},
+	}
+}
+
+// getChaincodeEnv returns the environment variables the chaincode needs to
+// connect to the peer, including the TLS certificate paths if the peer uses
+// TLS.
+func getChaincodeEnv(chaincodeData *ChaincodeJSON) []apiv1.EnvVar {
+	env := []apiv1.EnvVar{
+		{
+			Name:  "CORE_CHAINCODE_ID_NAME",
+			Value: chaincodeData.ChaincodeID,
+		},
+		{
+			Name:  "CORE_PEER_ADDRESS",
+			Value: chaincodeData.PeerAddress,
+		},
+		{
+			Name:  "CORE_PEER_TLS_ENABLED",
+			Value: strconv.FormatBool(chaincodeData.TLSEnabled()),
+		},
+	}
+
+	if chaincodeData.TLSEnabled() {
+		env = append(env,
+			apiv1.EnvVar{
+				Name:  "CORE_PEER_TLS_ROOTCERT_FILE",
+				Value: TLSClientRootCertFile,
+			},
+			apiv1.EnvVar{
+				Name:  "CORE_TLS_CLIENT_KEY_PATH",
+				Value: TLSClientKeyPath,
+			},
+			apiv1.EnvVar{
+				Name:  "CORE_TLS_CLIENT_CERT_PATH",
+				Value: TLSClientCertPath,
+			},
+			apiv1.EnvVar{
+				Name:  "CORE_TLS_CLIENT_KEY_FILE",
+				Value: TLSClientKeyFile,
+			},
+			apiv1.EnvVar{
+				Name:  "CORE_TLS_CLIENT_CERT_FILE",
+				Value: TLSClientCertFile,
+			},
+		)
+	}
+
+	return append(env, apiv1.EnvVar{
+		Name:  "CORE_PEER_LOCALMSPID",
+		Value: chaincodeData.MspID,
+	})
+}
+
+// setTraceContext passes the trace context to the chaincode, using pod
+// annotations and TRACEPARENT and TRACESTATE environment variables, so that
+// chaincode can continue the trace.
+func setTraceContext(ctx context.Context, podTemplate *apiv1.PodTemplateSpec) {
+	traceContext := tracing.GetTraceContext(ctx)
+	if len(traceContext) == 0 {
+		return
+	}
+
+	// The pod template annotations are shared with the job
+	podTemplate.Annotations = maps.Clone(podTemplate.Annotations)
+	if podTemplate.Annotations == nil {
+		podTemplate.Annotations = make(map[string]string)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(traceContext)) {
+		podTemplate.Annotations[traceContextAnnotationPrefix+strings.ToLower(name)] = traceContext[name]
+
+		for i := range podTemplate.Spec.Containers {
+			if podTemplate.Spec.Containers[i].Name == chaincodeContainerName {
+				podTemplate.Spec.Containers[i].Env = append(podTemplate.Spec.Containers[i].Env, apiv1.EnvVar{
+					Name:  name,
+					Value: traceContext[name],
+				})
+			}
+		}
+	}
 }
 
 func getChaincodeSecretApplyConfiguration(
@@ -380,19 +553,11 @@ func getChaincodeSecretApplyConfiguration(
 
 	annotations := getAnnotations(peerID, chaincodeData)
 
-	data := map[string]string{
-		"peer.crt":       chaincodeData.RootCert,
-		"client_pem.crt": chaincodeData.ClientCert,
-		"client_pem.key": chaincodeData.ClientKey,
-		"client.crt":     base64.StdEncoding.EncodeToString([]byte(chaincodeData.ClientCert)),
-		"client.key":     base64.StdEncoding.EncodeToString([]byte(chaincodeData.ClientKey)),
-	}
-
 	return applycorev1.
 		Secret(secretName, namespace).
 		WithAnnotations(annotations).
 		WithLabels(labels).
-		WithStringData(data).
+		WithStringData(getChaincodeCredentials(chaincodeData)).
 		WithType(apiv1.SecretTypeOpaque), nil
 }
 
@@ -408,15 +573,29 @@ func ApplyChaincodeSecrets(
 		return fmt.Errorf("error getting chaincode secret definition for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
 	}
 
+	// Never log the secret data, which includes the chaincode client key
+	redactedSecret := *secret
+	redactedSecret.StringData = nil
+	traceObject(logger, "Chaincode secret definition without data for chaincode ID "+chaincodeData.ChaincodeID, &redactedSecret)
+
+	startTime := time.Now()
+
+	spanCtx, span := tracing.Start(ctx, "k8s.secrets.apply", tracing.NamespaceKey.String(namespace), tracing.ObjectNameKey.String(secretName))
 	result, err := secretsClient.Apply(
-		ctx,
+		spanCtx,
 		secret,
 		metav1.ApplyOptions{FieldManager: fabricBuilderK8s},
 	)
+	tracing.End(span, err)
+
 	if err != nil {
+		metrics.FromContext(ctx).SecretApplied(metrics.ResultError, time.Since(startTime))
+
 		return fmt.Errorf("error applying chaincode secret definition for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
 	}
 
+	metrics.FromContext(ctx).SecretApplied(metrics.ResultSuccess, time.Since(startTime))
+
 	logger.Debugf(
 		"Applied secrets for chaincode ID %s: %s/%s",
 		chaincodeData.ChaincodeID,
@@ -434,6 +613,10 @@ func CreateChaincodeJob(
 	objectName, namespace, serviceAccount, nodeRole, peerID string,
 	chaincodeData *ChaincodeJSON,
 	imageData *ImageJSON,
+	resources apiv1.ResourceRequirements,
+	podOptions PodOptions,
+	retryPolicy RetryPolicy,
+	jobTemplate []byte,
 ) (*batchv1.Job, error) {
 	jobDefinition, err := getChaincodeJobSpec(
 		imageData,
@@ -442,11 +625,19 @@ func CreateChaincodeJob(
 		objectName,
 		peerID,
 		chaincodeData,
+		resources,
+		podOptions.CredentialStore,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting chaincode job definition for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
 	}
 
+	setPodOptions(&jobDefinition.Spec.Template, podOptions)
+
+	if err := setRetryPolicy(jobDefinition, retryPolicy); err != nil {
+		return nil, fmt.Errorf("error setting retry policy for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
 	if nodeRole != "" {
 		logger.Debugf(
 			"Adding node affinity and toleration to job definition for chaincode ID %s: %s",
@@ -454,36 +645,24 @@ func CreateChaincodeJob(
 			nodeRole,
 		)
 
-		jobDefinition.Spec.Template.Spec.Affinity = &apiv1.Affinity{
-			NodeAffinity: &apiv1.NodeAffinity{
-				RequiredDuringSchedulingIgnoredDuringExecution: &apiv1.NodeSelector{
-					NodeSelectorTerms: []apiv1.NodeSelectorTerm{
-						{
-							MatchExpressions: []apiv1.NodeSelectorRequirement{
-								{
-									Key:      "fabric-builder-k8s-role",
-									Operator: apiv1.NodeSelectorOpIn,
-									Values:   []string{nodeRole},
-								},
-							},
-						},
-					},
-				},
-			},
-		}
+		setNodeRole(&jobDefinition.Spec.Template.Spec, nodeRole)
+	}
 
-		jobDefinition.Spec.Template.Spec.Tolerations = []apiv1.Toleration{
-			{
-				Key:      "fabric-builder-k8s-role",
-				Operator: apiv1.TolerationOpEqual,
-				Value:    nodeRole,
-				Effect:   apiv1.TaintEffectNoSchedule,
-			},
+	setTraceContext(ctx, &jobDefinition.Spec.Template)
+
+	if len(jobTemplate) > 0 {
+		logger.Debugf("Applying job template to job definition for chaincode ID %s", chaincodeData.ChaincodeID)
+
+		jobDefinition, err = applyChaincodeJobTemplate(jobDefinition, jobTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("error applying job template for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
 		}
 	}
 
 	jobName := jobDefinition.Name
 
+	traceObject(logger, "Chaincode job definition for chaincode ID "+chaincodeData.ChaincodeID, jobDefinition)
+
 	logger.Debugf(
 		"Creating chaincode job for chaincode ID %s: %s/%s",
 		chaincodeData.ChaincodeID,
@@ -491,8 +670,13 @@ func CreateChaincodeJob(
 		jobName,
 	)
 
-	job, err := jobsClient.Create(ctx, jobDefinition, metav1.CreateOptions{})
+	spanCtx, span := tracing.Start(ctx, "k8s.jobs.create", tracing.NamespaceKey.String(namespace), tracing.JobNameKey.String(jobName))
+	job, err := jobsClient.Create(spanCtx, jobDefinition, metav1.CreateOptions{})
+	tracing.End(span, err)
+
 	if err != nil {
+		metrics.FromContext(ctx).JobCreated(metrics.ResultError)
+
 		return nil, fmt.Errorf(
 			"error creating chaincode job %s/%s for chaincode ID %s: %w",
 			namespace,
@@ -502,6 +686,8 @@ func CreateChaincodeJob(
 		)
 	}
 
+	metrics.FromContext(ctx).JobCreated(metrics.ResultSuccess)
+
 	logger.Debugf(
 		"Created chaincode job for chaincode ID %s: %s/%s",
 		chaincodeData.ChaincodeID,
@@ -512,6 +698,78 @@ func CreateChaincodeJob(
 	return job, nil
 }
 
+// DeleteChaincodeJob deletes the chaincode job using foreground propagation,
+// and waits for the chaincode pods to terminate until the context is done.
+func DeleteChaincodeJob(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	jobsClient typedBatchv1.JobInterface,
+	podsClient v1.PodInterface,
+	job *batchv1.Job,
+) error {
+	logger.Debugf("Deleting chaincode job %s/%s", job.Namespace, job.Name)
+
+	spanCtx, span := tracing.Start(ctx, "k8s.jobs.delete", tracing.NamespaceKey.String(job.Namespace), tracing.JobNameKey.String(job.Name))
+
+	err := jobsClient.Delete(spanCtx, job.Name, metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationForeground),
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		tracing.End(span, err)
+
+		return fmt.Errorf("error deleting chaincode job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+
+	tracing.End(span, nil)
+
+	selector := labels.SelectorFromSet(labels.Set{batchv1.JobNameLabel: job.Name})
+
+	spanCtx, span = tracing.Start(ctx, "k8s.pods.list", tracing.NamespaceKey.String(job.Namespace), tracing.JobNameKey.String(job.Name))
+
+	err = wait.PollUntilContextCancel(spanCtx, podDeletionPollInterval, true, func(ctx context.Context) (bool, error) {
+		pods, err := podsClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, fmt.Errorf("error listing pods for chaincode job %s/%s: %w", job.Namespace, job.Name, err)
+		}
+
+		logger.Debugf("Waiting for %d pods to terminate for chaincode job %s/%s", len(pods.Items), job.Namespace, job.Name)
+
+		return len(pods.Items) == 0, nil
+	})
+	if err != nil {
+		tracing.End(span, err)
+
+		return fmt.Errorf("error waiting for chaincode job %s/%s pods to terminate: %w", job.Namespace, job.Name, err)
+	}
+
+	tracing.End(span, nil)
+
+	return nil
+}
+
+// DeleteChaincodeSecrets deletes the chaincode secret.
+func DeleteChaincodeSecrets(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	secretsClient v1.SecretInterface,
+	secretName, namespace string,
+) error {
+	logger.Debugf("Deleting chaincode secret %s/%s", namespace, secretName)
+
+	spanCtx, span := tracing.Start(ctx, "k8s.secrets.delete", tracing.NamespaceKey.String(namespace), tracing.ObjectNameKey.String(secretName))
+
+	err := secretsClient.Delete(spanCtx, secretName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		tracing.End(span, err)
+
+		return fmt.Errorf("error deleting chaincode secret %s/%s: %w", namespace, secretName, err)
+	}
+
+	tracing.End(span, nil)
+
+	return nil
+}
+
 // GetValidRfc1035LabelName returns a valid RFC 1035 label name with the format
 // <prefix>-<truncated_chaincode_label>-<chaincode_run_hash> and space for a suffix if required.
 func GetValidRfc1035LabelName(prefix, peerID string, chaincodeData *ChaincodeJSON, suffixLen int) string {
@@ -1,12 +1,348 @@
 package util_test
 
 import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
 	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("K8s", func() {
+	Describe("CreateChaincodeJob", func() {
+		var (
+			ctx           context.Context
+			logger        *log.CmdLogger
+			clientset     *fake.Clientset
+			chaincodeData *util.ChaincodeJSON
+			imageData     *util.ImageJSON
+		)
+
+		BeforeEach(func() {
+			ctx = log.NewCmdContext(context.Background(), false)
+			logger = log.New(ctx)
+			clientset = fake.NewClientset()
+			chaincodeData = &util.ChaincodeJSON{
+				ChaincodeID: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b",
+				PeerAddress: "peer0.org1.example.com",
+				MspID:       "CongaOrg",
+			}
+			imageData = &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+			}
+		})
+
+		It("should create a chaincode job without resource requirements by default", func() {
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx@sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"))
+			Expect(job.Spec.Template.Spec.Containers[0].Resources.Requests).To(BeEmpty())
+			Expect(job.Spec.Template.Spec.Containers[0].Resources.Limits).To(BeEmpty())
+		})
+
+		It("should create a chaincode job which connects to the peer using TLS if chaincode.json contains certificates", func() {
+			chaincodeData.ClientCert = "CLIENT_CERT"
+			chaincodeData.ClientKey = "CLIENT_KEY"
+			chaincodeData.RootCert = "ROOT_CERT"
+
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElements(
+				apiv1.EnvVar{Name: "CORE_PEER_TLS_ENABLED", Value: "true"},
+				apiv1.EnvVar{Name: "CORE_PEER_TLS_ROOTCERT_FILE", Value: util.TLSClientRootCertFile},
+				apiv1.EnvVar{Name: "CORE_TLS_CLIENT_KEY_PATH", Value: util.TLSClientKeyPath},
+				apiv1.EnvVar{Name: "CORE_TLS_CLIENT_CERT_PATH", Value: util.TLSClientCertPath},
+				apiv1.EnvVar{Name: "CORE_TLS_CLIENT_KEY_FILE", Value: util.TLSClientKeyFile},
+				apiv1.EnvVar{Name: "CORE_TLS_CLIENT_CERT_FILE", Value: util.TLSClientCertFile},
+			))
+			Expect(container.VolumeMounts).To(ContainElement(apiv1.VolumeMount{Name: "certs", MountPath: "/etc/hyperledger/fabric", ReadOnly: true}))
+			Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(apiv1.Volume{
+				Name:         "certs",
+				VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{SecretName: "hlfcc-fabcar-s6pwkq6bepi2e"}},
+			}))
+		})
+
+		It("should create a chaincode job which connects to the peer using plain text if chaincode.json does not contain certificates", func() {
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElement(apiv1.EnvVar{Name: "CORE_PEER_TLS_ENABLED", Value: "false"}))
+			Expect(container.Env).NotTo(ContainElement(HaveField("Name", HavePrefix("CORE_TLS_CLIENT_"))))
+			Expect(container.Env).NotTo(ContainElement(HaveField("Name", "CORE_PEER_TLS_ROOTCERT_FILE")))
+			Expect(container.VolumeMounts).NotTo(ContainElement(HaveField("Name", "certs")))
+			Expect(job.Spec.Template.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "certs")))
+		})
+
+		It("should create a chaincode job with the specified resource requirements", func() {
+			resources := apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   apiv1.ResourceList{apiv1.ResourceMemory: resource.MustParse("256Mi")},
+			}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, resources, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Spec.Containers[0].Resources).To(Equal(resources))
+		})
+
+		It("should create a chaincode job with the specified image pull secrets", func() {
+			podOptions := util.PodOptions{
+				ImagePullSecrets: []apiv1.LocalObjectReference{{Name: "registry-a"}, {Name: "registry-b"}},
+			}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, podOptions, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Spec.ImagePullSecrets).To(Equal(podOptions.ImagePullSecrets))
+		})
+
+		It("should create a chaincode job with a restricted security context by default", func() {
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			securityContext := job.Spec.Template.Spec.Containers[0].SecurityContext
+			Expect(securityContext).NotTo(BeNil())
+			Expect(securityContext.RunAsNonRoot).To(Equal(ptr.To(true)))
+			Expect(securityContext.RunAsUser).To(BeNil())
+			Expect(securityContext.AllowPrivilegeEscalation).To(Equal(ptr.To(false)))
+			Expect(securityContext.Capabilities).To(Equal(&apiv1.Capabilities{Drop: []apiv1.Capability{"ALL"}}))
+			Expect(securityContext.SeccompProfile).To(Equal(&apiv1.SeccompProfile{Type: apiv1.SeccompProfileTypeRuntimeDefault}))
+			Expect(securityContext.ReadOnlyRootFilesystem).To(Equal(ptr.To(true)))
+			Expect(job.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(apiv1.VolumeMount{Name: "tmp", MountPath: "/tmp"}))
+			Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(apiv1.Volume{Name: "tmp", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}}))
+		})
+
+		It("should create a chaincode job with a relaxed security context", func() {
+			podOptions := util.PodOptions{
+				SecurityPolicy: util.SecurityPolicy{
+					AllowRunAsRoot:           true,
+					RunAsUser:                ptr.To[int64](0),
+					AllowPrivilegeEscalation: true,
+					AddCapabilities:          []apiv1.Capability{"NET_BIND_SERVICE"},
+					SeccompProfile:           apiv1.SeccompProfileTypeUnconfined,
+					WritableRootFilesystem:   true,
+				},
+			}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, podOptions, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			securityContext := job.Spec.Template.Spec.Containers[0].SecurityContext
+			Expect(securityContext).NotTo(BeNil())
+			Expect(securityContext.RunAsNonRoot).To(BeNil())
+			Expect(securityContext.RunAsUser).To(Equal(ptr.To[int64](0)))
+			Expect(securityContext.AllowPrivilegeEscalation).To(Equal(ptr.To(true)))
+			Expect(securityContext.Capabilities).To(Equal(&apiv1.Capabilities{Drop: []apiv1.Capability{"ALL"}, Add: []apiv1.Capability{"NET_BIND_SERVICE"}}))
+			Expect(securityContext.SeccompProfile).To(Equal(&apiv1.SeccompProfile{Type: apiv1.SeccompProfileTypeUnconfined}))
+			Expect(securityContext.ReadOnlyRootFilesystem).To(Equal(ptr.To(false)))
+			Expect(job.Spec.Template.Spec.Volumes).NotTo(ContainElement(HaveField("Name", "tmp")))
+		})
+
+		It("should create a chaincode job with the specified probes", func() {
+			podOptions := util.PodOptions{
+				Probes: util.ChaincodeProbes{
+					Readiness: &apiv1.Probe{ProbeHandler: apiv1.ProbeHandler{Exec: &apiv1.ExecAction{Command: []string{"/bin/healthcheck"}}}},
+					Liveness:  &apiv1.Probe{ProbeHandler: apiv1.ProbeHandler{GRPC: &apiv1.GRPCAction{Port: 9999}}},
+				},
+			}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, podOptions, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Spec.Containers[0].ReadinessProbe).To(Equal(podOptions.Probes.Readiness))
+			Expect(job.Spec.Template.Spec.Containers[0].LivenessProbe).To(Equal(podOptions.Probes.Liveness))
+		})
+
+		It("should create a chaincode job with the specified chaincode configuration", func() {
+			podOptions := util.PodOptions{
+				Config: util.ChaincodeConfig{
+					Env:         map[string]string{"LOG_LEVEL": "debug", "FEATURE_X": "true"},
+					EnvFrom:     []util.ConfigSource{{ConfigMap: "chaincode-env"}, {Secret: "chaincode-credentials"}},
+					ConfigFiles: []util.ConfigFile{{ConfigSource: util.ConfigSource{Secret: "chaincode-tls"}, MountPath: "/etc/chaincode/tls"}},
+				},
+			}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, podOptions, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			container := job.Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElements(
+				apiv1.EnvVar{Name: "CORE_PEER_ADDRESS", Value: "peer0.org1.example.com"},
+				apiv1.EnvVar{Name: "FEATURE_X", Value: "true"},
+				apiv1.EnvVar{Name: "LOG_LEVEL", Value: "debug"},
+			))
+			Expect(container.EnvFrom).To(Equal([]apiv1.EnvFromSource{
+				{ConfigMapRef: &apiv1.ConfigMapEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "chaincode-env"}}},
+				{SecretRef: &apiv1.SecretEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "chaincode-credentials"}}},
+			}))
+			Expect(container.VolumeMounts).To(ContainElement(apiv1.VolumeMount{Name: "config-0", MountPath: "/etc/chaincode/tls", ReadOnly: true}))
+			Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(apiv1.Volume{
+				Name:         "config-0",
+				VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{SecretName: "chaincode-tls"}},
+			}))
+		})
+
+		It("should create a chaincode job with the specified scheduling policy and node role", func() {
+			podOptions := util.PodOptions{
+				SchedulingPolicy: util.SchedulingPolicy{
+					NodeRequirements: []apiv1.NodeSelectorRequirement{{Key: "disktype", Operator: apiv1.NodeSelectorOpIn, Values: []string{"ssd"}}},
+					Tolerations:      []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoSchedule}},
+					TopologySpread:   []util.TopologySpread{{TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: apiv1.ScheduleAnyway}},
+					PodAntiAffinity:  &util.PodAntiAffinity{Required: true, TopologyKey: "kubernetes.io/hostname"},
+				},
+			}
+
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "chaincode", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, podOptions, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			podSpec := job.Spec.Template.Spec
+			chaincodeSelector := &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"fabric-builder-k8s-cclabel": "fabcar",
+					"fabric-builder-k8s-cchash":  job.Spec.Template.Labels["fabric-builder-k8s-cchash"],
+				},
+			}
+
+			Expect(podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(Equal([]apiv1.NodeSelectorTerm{
+				{
+					MatchExpressions: []apiv1.NodeSelectorRequirement{
+						{Key: "disktype", Operator: apiv1.NodeSelectorOpIn, Values: []string{"ssd"}},
+						{Key: "fabric-builder-k8s-role", Operator: apiv1.NodeSelectorOpIn, Values: []string{"chaincode"}},
+					},
+				},
+			}))
+			Expect(podSpec.Tolerations).To(Equal([]apiv1.Toleration{
+				{Key: "dedicated", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoSchedule},
+				{Key: "fabric-builder-k8s-role", Operator: apiv1.TolerationOpEqual, Value: "chaincode", Effect: apiv1.TaintEffectNoSchedule},
+			}))
+			Expect(podSpec.TopologySpreadConstraints).To(Equal([]apiv1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: apiv1.ScheduleAnyway, LabelSelector: chaincodeSelector},
+			}))
+			Expect(podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal([]apiv1.PodAffinityTerm{
+				{LabelSelector: chaincodeSelector, TopologyKey: "kubernetes.io/hostname"},
+			}))
+		})
+
+		It("should not add trace context to the chaincode job without a span", func() {
+			job, err := util.CreateChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Annotations).NotTo(HaveKey("fabric-builder-k8s-traceparent"))
+			Expect(job.Spec.Template.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "TRACEPARENT")))
+		})
+
+		It("should trace the chaincode job creation and pass the trace context to the chaincode", func() {
+			exporter := tracetest.NewInMemoryExporter()
+			previousProvider := otel.GetTracerProvider()
+			otel.SetTracerProvider(tracing.NewTracerProvider("run", sdktrace.WithSyncer(exporter)))
+			DeferCleanup(func() {
+				otel.SetTracerProvider(previousProvider)
+			})
+
+			spanCtx, span := tracing.Start(ctx, "run")
+			job, err := util.CreateChaincodeJob(spanCtx, logger, clientset.BatchV1().Jobs("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, nil)
+			span.End()
+			Expect(err).NotTo(HaveOccurred())
+
+			traceparent := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+			Expect(job.Spec.Template.Annotations).To(HaveKeyWithValue("fabric-builder-k8s-traceparent", traceparent))
+			Expect(job.Annotations).NotTo(HaveKey("fabric-builder-k8s-traceparent"))
+			Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(apiv1.EnvVar{Name: "TRACEPARENT", Value: traceparent}))
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name).To(Equal("k8s.jobs.create"))
+			Expect(spans[0].Parent.SpanID()).To(Equal(span.SpanContext().SpanID()))
+			Expect(spans[0].Attributes).To(ContainElements(
+				tracing.NamespaceKey.String("chaincode"),
+				tracing.JobNameKey.String(job.Name),
+			))
+		})
+	})
+
+	Describe("DeleteChaincodeJob", func() {
+		var (
+			ctx       context.Context
+			logger    *log.CmdLogger
+			clientset *fake.Clientset
+			job       *batchv1.Job
+		)
+
+		BeforeEach(func() {
+			ctx = log.NewCmdContext(context.Background(), false)
+			logger = log.New(ctx)
+			job = &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "hlfcc-fabcar-s6pwkq6bepi2e-abcde", Namespace: "chaincode"},
+			}
+			clientset = fake.NewClientset(job)
+		})
+
+		It("should delete the chaincode job", func() {
+			err := util.DeleteChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), clientset.CoreV1().Pods("chaincode"), job)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = clientset.BatchV1().Jobs("chaincode").Get(ctx, job.Name, metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should not return an error if the chaincode job does not exist", func() {
+			clientset = fake.NewClientset()
+
+			err := util.DeleteChaincodeJob(ctx, logger, clientset.BatchV1().Jobs("chaincode"), clientset.CoreV1().Pods("chaincode"), job)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return an error if the chaincode pods do not terminate before the context is done", func() {
+			pod := &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hlfcc-fabcar-s6pwkq6bepi2e-abcde-xyz12",
+					Namespace: "chaincode",
+					Labels:    map[string]string{batchv1.JobNameLabel: job.Name},
+				},
+			}
+			clientset = fake.NewClientset(job, pod)
+
+			timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+
+			err := util.DeleteChaincodeJob(timeoutCtx, logger, clientset.BatchV1().Jobs("chaincode"), clientset.CoreV1().Pods("chaincode"), job)
+			Expect(err).To(MatchError(ContainSubstring("error waiting for chaincode job chaincode/hlfcc-fabcar-s6pwkq6bepi2e-abcde pods to terminate")))
+		})
+	})
+
+	Describe("DeleteChaincodeSecrets", func() {
+		It("should delete the chaincode secret and ignore secrets which do not exist", func() {
+			ctx := log.NewCmdContext(context.Background(), false)
+			logger := log.New(ctx)
+			secret := &apiv1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "hlfcc-fabcar-s6pwkq6bepi2e", Namespace: "chaincode"},
+			}
+			clientset := fake.NewClientset(secret)
+			secretsClient := clientset.CoreV1().Secrets("chaincode")
+
+			Expect(util.DeleteChaincodeSecrets(ctx, logger, secretsClient, secret.Name, "chaincode")).To(Succeed())
+
+			_, err := secretsClient.Get(ctx, secret.Name, metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			Expect(util.DeleteChaincodeSecrets(ctx, logger, secretsClient, secret.Name, "chaincode")).To(Succeed())
+		})
+	})
+
 	Describe("GetValidRfc1035LabelName", func() {
 		It("should return names with a maximum of 63 characters", func() {
 			chaincodeData := &util.ChaincodeJSON{
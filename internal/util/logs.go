@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	typedBatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	DefaultLogTailLines string = "20"
+
+	podPollInterval = 2 * time.Second
+)
+
+var (
+	errNoChaincodePod      = errors.New("no chaincode pod found")
+	errChaincodeJobStopped = errors.New("chaincode job is not running")
+)
+
+// getChaincodePod returns the most recently created pod for the provided job.
+func getChaincodePod(ctx context.Context, podsClient v1.PodInterface, job *batchv1.Job) (*apiv1.Pod, error) {
+	selector := labels.SelectorFromSet(labels.Set{batchv1.JobNameLabel: job.Name})
+
+	pods, err := podsClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods for chaincode job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("%w for chaincode job %s/%s", errNoChaincodePod, job.Namespace, job.Name)
+	}
+
+	pod := slices.MaxFunc(pods.Items, func(a, b apiv1.Pod) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+
+	return &pod, nil
+}
+
+func getChaincodeContainerStatus(pod *apiv1.Pod) *apiv1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == chaincodeContainerName {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+
+	return nil
+}
+
+// chaincodeContainerInstance identifies a run of the chaincode container, which
+// changes when the container restarts or the job creates a new pod.
+type chaincodeContainerInstance struct {
+	podUID       types.UID
+	restartCount int32
+}
+
+// waitForChaincodeContainer waits until a chaincode container, other than the
+// previous container instance, has started in a pod for the provided job, so
+// that its log is available. Returns errChaincodeJobStopped if the job stops
+// running first.
+func waitForChaincodeContainer(
+	ctx context.Context,
+	jobsClient typedBatchv1.JobInterface,
+	podsClient v1.PodInterface,
+	job *batchv1.Job,
+	previous *chaincodeContainerInstance,
+) (*apiv1.Pod, error) {
+	var pod *apiv1.Pod
+
+	err := wait.PollUntilContextCancel(ctx, podPollInterval, true, func(ctx context.Context) (bool, error) {
+		var err error
+
+		pod, err = getChaincodePod(ctx, podsClient, job)
+		if err != nil && !errors.Is(err, errNoChaincodePod) {
+			return false, err
+		}
+
+		if err == nil {
+			status := getChaincodeContainerStatus(pod)
+			started := status != nil && (status.State.Running != nil || status.State.Terminated != nil)
+
+			if started && (previous == nil || *previous != getChaincodeContainerInstance(pod, status)) {
+				return true, nil
+			}
+		}
+
+		running, err := IsChaincodeJobRunning(ctx, jobsClient, job)
+		if err != nil {
+			return false, err
+		}
+
+		if !running {
+			return false, errChaincodeJobStopped
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for chaincode container for job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+
+	return pod, nil
+}
+
+func getChaincodeContainerInstance(pod *apiv1.Pod, status *apiv1.ContainerStatus) chaincodeContainerInstance {
+	return chaincodeContainerInstance{
+		podUID:       pod.UID,
+		restartCount: status.RestartCount,
+	}
+}
+
+// FollowChaincodeLogs relays the chaincode container log for the provided job
+// to the logger, with each line prefixed by the job name, until the job stops
+// running or the context is done. When the chaincode container restarts, or the
+// job replaces the chaincode pod, the log for the new container is followed.
+func FollowChaincodeLogs(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	jobsClient typedBatchv1.JobInterface,
+	podsClient v1.PodInterface,
+	job *batchv1.Job,
+) error {
+	var previous *chaincodeContainerInstance
+
+	for {
+		pod, err := waitForChaincodeContainer(ctx, jobsClient, podsClient, job, previous)
+		if errors.Is(err, errChaincodeJobStopped) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		instance := getChaincodeContainerInstance(pod, getChaincodeContainerStatus(pod))
+		previous = &instance
+
+		if err := followChaincodeContainerLog(ctx, logger, podsClient, job, pod); err != nil {
+			return err
+		}
+	}
+}
+
+// followChaincodeContainerLog relays the log for the current chaincode
+// container in the provided pod until the container stops.
+func followChaincodeContainerLog(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	podsClient v1.PodInterface,
+	job *batchv1.Job,
+	pod *apiv1.Pod,
+) error {
+	logger.Debugf("Following chaincode log for pod %s/%s", pod.Namespace, pod.Name)
+
+	stream, err := podsClient.GetLogs(pod.Name, &apiv1.PodLogOptions{
+		Container: chaincodeContainerName,
+		Follow:    true,
+	}).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("error following chaincode log for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	defer stream.Close()
+
+	err = ReadLogLines(stream, func(line string) {
+		logger.Printf("[%s] %s", job.Name, line)
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("error reading chaincode log for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	return nil
+}
+
+// ReadLogLines calls handleLine for each line read from the provided log,
+// without the line ending, until the end of the log. Unlike bufio.Scanner,
+// there is no limit on the length of a line.
+func ReadLogLines(logReader io.Reader, handleLine func(line string)) error {
+	reader := bufio.NewReader(logReader)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			handleLine(strings.TrimRight(line, "\r\n"))
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("error reading log: %w", err)
+		}
+	}
+}
+
+// GetChaincodePodDiagnostics returns a description of the chaincode container
+// termination state, and the last lines of the chaincode container log, for the
+// provided job. Any errors getting the details are logged and an empty string
+// is returned.
+func GetChaincodePodDiagnostics(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	podsClient v1.PodInterface,
+	job *batchv1.Job,
+	tailLines int64,
+) string {
+	pod, err := getChaincodePod(ctx, podsClient, job)
+	if err != nil {
+		logger.Debugf("Unable to get chaincode pod diagnostics: %v", err)
+
+		return ""
+	}
+
+	var diagnostics strings.Builder
+
+	if status := getChaincodeContainerStatus(pod); status != nil {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			terminated = status.LastTerminationState.Terminated
+		}
+
+		if terminated != nil {
+			fmt.Fprintf(
+				&diagnostics,
+				"chaincode container in pod %s/%s terminated with exit code %d, reason %s: %s",
+				pod.Namespace,
+				pod.Name,
+				terminated.ExitCode,
+				terminated.Reason,
+				terminated.Message,
+			)
+		} else if status.State.Waiting != nil {
+			fmt.Fprintf(
+				&diagnostics,
+				"chaincode container in pod %s/%s waiting, reason %s: %s",
+				pod.Namespace,
+				pod.Name,
+				status.State.Waiting.Reason,
+				status.State.Waiting.Message,
+			)
+		}
+	}
+
+	if tailLines <= 0 {
+		return diagnostics.String()
+	}
+
+	chaincodeLog, err := getChaincodeLogTail(ctx, podsClient, pod, tailLines)
+	if err != nil {
+		logger.Debugf("Unable to get chaincode log: %v", err)
+
+		return diagnostics.String()
+	}
+
+	if chaincodeLog != "" {
+		if diagnostics.Len() > 0 {
+			diagnostics.WriteString("\n")
+		}
+
+		fmt.Fprintf(&diagnostics, "last %d lines of chaincode log for pod %s/%s:\n%s", tailLines, pod.Namespace, pod.Name, chaincodeLog)
+	}
+
+	return diagnostics.String()
+}
+
+func getChaincodeLogTail(ctx context.Context, podsClient v1.PodInterface, pod *apiv1.Pod, tailLines int64) (string, error) {
+	stream, err := podsClient.GetLogs(pod.Name, &apiv1.PodLogOptions{
+		Container: chaincodeContainerName,
+		TailLines: ptr.To(tailLines),
+	}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error getting chaincode log for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	defer stream.Close()
+
+	chaincodeLog, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("error reading chaincode log for pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	return strings.TrimRight(string(chaincodeLog), "\n"), nil
+}
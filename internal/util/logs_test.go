@@ -0,0 +1,180 @@
+package util_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Logs", func() {
+	var (
+		ctx    context.Context
+		logger *log.CmdLogger
+		job    *batchv1.Job
+	)
+
+	newPod := func(containerState apiv1.ContainerState) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hlfcc-fabcar-s6pwkq6bepi2e-abcde-fghij",
+				Namespace: "chaincode",
+				Labels:    map[string]string{batchv1.JobNameLabel: job.Name},
+			},
+			Status: apiv1.PodStatus{
+				ContainerStatuses: []apiv1.ContainerStatus{
+					{
+						Name:  "chaincode",
+						State: containerState,
+					},
+				},
+			},
+		}
+	}
+
+	logRequests := func(clientset *fake.Clientset) int {
+		count := 0
+
+		for _, action := range clientset.Actions() {
+			if action.GetSubresource() == "log" {
+				count++
+			}
+		}
+
+		return count
+	}
+
+	BeforeEach(func() {
+		ctx = log.NewCmdContext(context.Background(), false)
+		logger = log.New(ctx)
+		job = &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hlfcc-fabcar-s6pwkq6bepi2e-abcde",
+				Namespace: "chaincode",
+			},
+		}
+	})
+
+	Describe("ReadLogLines", func() {
+		It("should read every line without the line endings", func() {
+			var lines []string
+
+			err := util.ReadLogLines(strings.NewReader("first\r\nsecond\n\nlast"), func(line string) {
+				lines = append(lines, line)
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lines).To(Equal([]string{"first", "second", "", "last"}))
+		})
+
+		It("should read lines longer than 64KB", func() {
+			longLine := strings.Repeat("x", 256*1024)
+
+			var lines []string
+
+			err := util.ReadLogLines(strings.NewReader(longLine+"\nnext\n"), func(line string) {
+				lines = append(lines, line)
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lines).To(Equal([]string{longLine, "next"}))
+		})
+	})
+
+	Describe("FollowChaincodeLogs", func() {
+		finishedJob := func() *batchv1.Job {
+			finished := job.DeepCopy()
+			finished.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: apiv1.ConditionTrue}}
+
+			return finished
+		}
+
+		It("should follow the chaincode log once the container is running until the job finishes", func() {
+			pod := newPod(apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}})
+			clientset := fake.NewClientset(finishedJob(), pod)
+
+			err := util.FollowChaincodeLogs(ctx, logger, clientset.BatchV1().Jobs("chaincode"), clientset.CoreV1().Pods("chaincode"), job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logRequests(clientset)).To(Equal(1))
+		})
+
+		It("should follow the chaincode log for a replacement pod while the job is running", func() {
+			pod := newPod(apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 1}})
+			pod.UID = "first"
+			pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+			clientset := fake.NewClientset(job, pod)
+			jobsClient := clientset.BatchV1().Jobs("chaincode")
+			podsClient := clientset.CoreV1().Pods("chaincode")
+
+			done := make(chan error)
+
+			go func() {
+				done <- util.FollowChaincodeLogs(ctx, logger, jobsClient, podsClient, job)
+			}()
+
+			Eventually(func() int { return logRequests(clientset) }).Should(Equal(1))
+			Consistently(func() int { return logRequests(clientset) }, "3s").Should(Equal(1))
+
+			replacementPod := newPod(apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}})
+			replacementPod.Name = "hlfcc-fabcar-s6pwkq6bepi2e-abcde-klmno"
+			replacementPod.UID = "replacement"
+			replacementPod.CreationTimestamp = metav1.Now()
+			_, err := podsClient.Create(ctx, replacementPod, metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() int { return logRequests(clientset) }, "5s").Should(Equal(2))
+
+			_, err = jobsClient.UpdateStatus(ctx, finishedJob(), metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(done, "5s").Should(Receive(BeNil()))
+			Expect(logRequests(clientset)).To(Equal(2))
+		})
+
+		It("should stop waiting for the chaincode container when the context is done", func() {
+			clientset := fake.NewClientset(job)
+
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			err := util.FollowChaincodeLogs(cancelledCtx, logger, clientset.BatchV1().Jobs("chaincode"), clientset.CoreV1().Pods("chaincode"), job)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+
+	Describe("GetChaincodePodDiagnostics", func() {
+		It("should include the container termination state and log", func() {
+			pod := newPod(apiv1.ContainerState{
+				Terminated: &apiv1.ContainerStateTerminated{ExitCode: 2, Reason: "Error", Message: "panic"},
+			})
+			clientset := fake.NewClientset(pod)
+
+			diagnostics := util.GetChaincodePodDiagnostics(ctx, logger, clientset.CoreV1().Pods("chaincode"), job, 20)
+			Expect(diagnostics).To(ContainSubstring("chaincode container in pod chaincode/hlfcc-fabcar-s6pwkq6bepi2e-abcde-fghij terminated with exit code 2, reason Error: panic"))
+			Expect(diagnostics).To(ContainSubstring("last 20 lines of chaincode log for pod chaincode/hlfcc-fabcar-s6pwkq6bepi2e-abcde-fghij:\nfake logs"))
+		})
+
+		It("should include the container waiting state", func() {
+			pod := newPod(apiv1.ContainerState{
+				Waiting: &apiv1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"},
+			})
+			clientset := fake.NewClientset(pod)
+
+			diagnostics := util.GetChaincodePodDiagnostics(ctx, logger, clientset.CoreV1().Pods("chaincode"), job, 0)
+			Expect(diagnostics).To(Equal("chaincode container in pod chaincode/hlfcc-fabcar-s6pwkq6bepi2e-abcde-fghij waiting, reason ImagePullBackOff: Back-off pulling image"))
+		})
+
+		It("should return an empty string when there is no chaincode pod", func() {
+			clientset := fake.NewClientset()
+
+			diagnostics := util.GetChaincodePodDiagnostics(ctx, logger, clientset.CoreV1().Pods("chaincode"), job, 20)
+			Expect(diagnostics).To(BeEmpty())
+		})
+	})
+})
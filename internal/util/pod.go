@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	tmpVolumeName = "tmp"
+	tmpMountPath  = "/tmp"
+)
+
+// PodOptions configures the chaincode pod, in addition to the settings which
+// are always required to run chaincode.
+type PodOptions struct {
+	// ImagePullSecrets are the names of secrets in the chaincode namespace
+	// used to pull the chaincode image, in addition to any image pull secrets
+	// for the service account.
+	ImagePullSecrets []apiv1.LocalObjectReference
+
+	// SecurityPolicy relaxes the default chaincode container security context.
+	SecurityPolicy SecurityPolicy
+
+	// Probes are the chaincode container readiness and liveness probes.
+	Probes ChaincodeProbes
+
+	// Config is additional configuration for the chaincode container.
+	Config ChaincodeConfig
+
+	// CredentialStore provides the peer TLS credentials to chaincode pods.
+	// Defaults to the chaincode Kubernetes secret.
+	CredentialStore CredentialStore
+
+	// SchedulingPolicy configures which nodes chaincode pods are scheduled on.
+	SchedulingPolicy SchedulingPolicy
+}
+
+// SecurityPolicy relaxes the security context which is applied to chaincode
+// containers by default. The zero value is compliant with the Pod Security
+// Standards restricted profile: chaincode must run as a non-root user, without
+// privilege escalation, with all capabilities dropped, using the RuntimeDefault
+// seccomp profile, and with a read-only root filesystem.
+type SecurityPolicy struct {
+	// AllowRunAsRoot allows chaincode images which run as the root user.
+	AllowRunAsRoot bool
+
+	// RunAsUser overrides the user ID specified by the chaincode image.
+	RunAsUser *int64
+
+	// AllowPrivilegeEscalation allows chaincode processes to gain more
+	// privileges than their parent process.
+	AllowPrivilegeEscalation bool
+
+	// AddCapabilities are added after all other capabilities are dropped.
+	AddCapabilities []apiv1.Capability
+
+	// SeccompProfile is the seccomp profile type, either RuntimeDefault or
+	// Unconfined. Defaults to RuntimeDefault.
+	SeccompProfile apiv1.SeccompProfileType
+
+	// WritableRootFilesystem allows chaincode to write to the container root
+	// filesystem. Otherwise only /tmp is writable.
+	WritableRootFilesystem bool
+}
+
+// getSeccompProfileType returns the seccomp profile type, defaulting to
+// RuntimeDefault.
+func (p SecurityPolicy) getSeccompProfileType() apiv1.SeccompProfileType {
+	if p.SeccompProfile == "" {
+		return apiv1.SeccompProfileTypeRuntimeDefault
+	}
+
+	return p.SeccompProfile
+}
+
+// getSecurityContext returns the chaincode container security context.
+func (p SecurityPolicy) getSecurityContext() *apiv1.SecurityContext {
+	securityContext := &apiv1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.To(p.AllowPrivilegeEscalation),
+		Capabilities: &apiv1.Capabilities{
+			Drop: []apiv1.Capability{"ALL"},
+			Add:  p.AddCapabilities,
+		},
+		ReadOnlyRootFilesystem: ptr.To(!p.WritableRootFilesystem),
+		RunAsUser:              p.RunAsUser,
+		SeccompProfile: &apiv1.SeccompProfile{
+			Type: p.getSeccompProfileType(),
+		},
+	}
+
+	if !p.AllowRunAsRoot {
+		securityContext.RunAsNonRoot = ptr.To(true)
+	}
+
+	return securityContext
+}
+
+// setPodOptions configures the pod template with the provided pod options.
+func setPodOptions(podTemplate *apiv1.PodTemplateSpec, options PodOptions) {
+	podSpec := &podTemplate.Spec
+
+	if len(options.ImagePullSecrets) > 0 {
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, options.ImagePullSecrets...)
+	}
+
+	if container := findContainer(podSpec.Containers, chaincodeContainerName); container != nil {
+		container.ReadinessProbe = options.Probes.Readiness
+		container.LivenessProbe = options.Probes.Liveness
+	}
+
+	setChaincodeConfig(podSpec, options.Config)
+	setSecurityPolicy(podSpec, options.SecurityPolicy)
+	setSchedulingPolicy(podTemplate, options.SchedulingPolicy)
+}
+
+// setSecurityPolicy configures the chaincode container security context, and
+// adds a writable /tmp volume if the root filesystem is read-only.
+func setSecurityPolicy(podSpec *apiv1.PodSpec, securityPolicy SecurityPolicy) {
+	container := findContainer(podSpec.Containers, chaincodeContainerName)
+	if container == nil {
+		return
+	}
+
+	container.SecurityContext = securityPolicy.getSecurityContext()
+
+	if securityPolicy.WritableRootFilesystem {
+		return
+	}
+
+	container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+		Name:      tmpVolumeName,
+		MountPath: tmpMountPath,
+	})
+
+	podSpec.Volumes = append(podSpec.Volumes, apiv1.Volume{
+		Name: tmpVolumeName,
+		VolumeSource: apiv1.VolumeSource{
+			EmptyDir: &apiv1.EmptyDirVolumeSource{},
+		},
+	})
+}
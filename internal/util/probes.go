@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// Probe handler prefixes for probe environment variables.
+	ProbeHandlerExec = "exec"
+	ProbeHandlerTCP  = "tcp"
+	ProbeHandlerGRPC = "grpc"
+
+	maximumPort = 65535
+)
+
+var (
+	errProbeHandler = errors.New("probe must have exactly one of exec, tcpPort, or grpcPort")
+	errProbeTiming  = errors.New("probe timings must be zero or positive")
+	errProbePort    = errors.New("probe port must be between 1 and 65535")
+	errProbeService = errors.New("probe grpcService requires grpcPort")
+)
+
+// ChaincodeProbes are the optional readiness and liveness probes for the
+// chaincode container.
+type ChaincodeProbes struct {
+	// Readiness controls when the chaincode is considered to have started.
+	Readiness *apiv1.Probe
+
+	// Liveness restarts chaincode which stops responding.
+	Liveness *apiv1.Probe
+}
+
+// ParseProbe returns a Kubernetes probe for a probe environment variable
+// value, either exec:<command>, tcp:<port>, or grpc:<port>[:<service>].
+// Probe timings use the Kubernetes defaults.
+func ParseProbe(value string) (*apiv1.Probe, error) {
+	handler, target, found := strings.Cut(value, ":")
+	if !found || strings.TrimSpace(target) == "" {
+		return nil, fmt.Errorf("probe '%s' must be exec:<command>, tcp:<port>, or grpc:<port>", value)
+	}
+
+	probeData := &ProbeJSON{}
+
+	switch handler {
+	case ProbeHandlerExec:
+		probeData.Exec = strings.Fields(target)
+	case ProbeHandlerTCP:
+		port, err := parseProbePort(target)
+		if err != nil {
+			return nil, err
+		}
+
+		probeData.TCPPort = port
+	case ProbeHandlerGRPC:
+		portValue, service, _ := strings.Cut(target, ":")
+
+		port, err := parseProbePort(portValue)
+		if err != nil {
+			return nil, err
+		}
+
+		probeData.GRPCPort = port
+		probeData.GRPCService = service
+	default:
+		return nil, fmt.Errorf("unsupported probe type '%s', must be '%s', '%s', or '%s'", handler, ProbeHandlerExec, ProbeHandlerTCP, ProbeHandlerGRPC)
+	}
+
+	return probeData.toProbe()
+}
+
+// ParseProbes returns the chaincode probes in the image.json file.
+func ParseProbes(probes *ProbesJSON) (ChaincodeProbes, error) {
+	var chaincodeProbes ChaincodeProbes
+
+	if probes == nil {
+		return chaincodeProbes, nil
+	}
+
+	var err error
+
+	if probes.Readiness != nil {
+		chaincodeProbes.Readiness, err = probes.Readiness.toProbe()
+		if err != nil {
+			return chaincodeProbes, fmt.Errorf("invalid readiness probe: %w", err)
+		}
+	}
+
+	if probes.Liveness != nil {
+		chaincodeProbes.Liveness, err = probes.Liveness.toProbe()
+		if err != nil {
+			return chaincodeProbes, fmt.Errorf("invalid liveness probe: %w", err)
+		}
+	}
+
+	return chaincodeProbes, nil
+}
+
+// GetChaincodeProbes returns the probes for the chaincode container, using any
+// probes in the image.json file in preference to the builder defaults.
+func GetChaincodeProbes(defaults ChaincodeProbes, imageData *ImageJSON) (ChaincodeProbes, error) {
+	overrides, err := ParseProbes(imageData.Probes)
+	if err != nil {
+		return ChaincodeProbes{}, err
+	}
+
+	probes := defaults
+
+	if overrides.Readiness != nil {
+		probes.Readiness = overrides.Readiness
+	}
+
+	if overrides.Liveness != nil {
+		probes.Liveness = overrides.Liveness
+	}
+
+	return probes, nil
+}
+
+// toProbe returns the Kubernetes probe for the image.json probe.
+func (p *ProbeJSON) toProbe() (*apiv1.Probe, error) {
+	probe := &apiv1.Probe{
+		InitialDelaySeconds: p.InitialDelaySeconds,
+		PeriodSeconds:       p.PeriodSeconds,
+		TimeoutSeconds:      p.TimeoutSeconds,
+		FailureThreshold:    p.FailureThreshold,
+	}
+
+	if p.InitialDelaySeconds < 0 || p.PeriodSeconds < 0 || p.TimeoutSeconds < 0 || p.FailureThreshold < 0 {
+		return nil, errProbeTiming
+	}
+
+	handlers := 0
+
+	if len(p.Exec) > 0 {
+		handlers++
+		probe.Exec = &apiv1.ExecAction{Command: p.Exec}
+	}
+
+	if p.TCPPort != 0 {
+		handlers++
+		probe.TCPSocket = &apiv1.TCPSocketAction{Port: intstr.FromInt32(p.TCPPort)}
+	}
+
+	if p.GRPCPort != 0 {
+		handlers++
+		probe.GRPC = &apiv1.GRPCAction{Port: p.GRPCPort}
+
+		if p.GRPCService != "" {
+			probe.GRPC.Service = ptr.To(p.GRPCService)
+		}
+	}
+
+	if handlers != 1 {
+		return nil, errProbeHandler
+	}
+
+	if p.GRPCService != "" && p.GRPCPort == 0 {
+		return nil, errProbeService
+	}
+
+	if (probe.TCPSocket != nil && !isValidPort(p.TCPPort)) || (probe.GRPC != nil && !isValidPort(p.GRPCPort)) {
+		return nil, errProbePort
+	}
+
+	return probe, nil
+}
+
+func parseProbePort(value string) (int32, error) {
+	port, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || !isValidPort(int32(port)) {
+		return 0, fmt.Errorf("invalid probe port '%s', must be between 1 and 65535", value)
+	}
+
+	return int32(port), nil
+}
+
+func isValidPort(port int32) bool {
+	return port > 0 && port <= maximumPort
+}
@@ -0,0 +1,112 @@
+package util_test
+
+import (
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Probes", func() {
+	Describe("ParseProbe", func() {
+		It("should return an exec probe", func() {
+			probe, err := util.ParseProbe("exec:/bin/healthcheck --ready")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(probe.Exec).To(Equal(&apiv1.ExecAction{Command: []string{"/bin/healthcheck", "--ready"}}))
+			Expect(probe.TCPSocket).To(BeNil())
+			Expect(probe.GRPC).To(BeNil())
+		})
+
+		It("should return a TCP probe", func() {
+			probe, err := util.ParseProbe("tcp:9999")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(probe.TCPSocket).To(Equal(&apiv1.TCPSocketAction{Port: intstr.FromInt32(9999)}))
+		})
+
+		It("should return a gRPC probe", func() {
+			probe, err := util.ParseProbe("grpc:9999")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(probe.GRPC).To(Equal(&apiv1.GRPCAction{Port: 9999}))
+		})
+
+		It("should return a gRPC probe for a service", func() {
+			probe, err := util.ParseProbe("grpc:9999:chaincode")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(probe.GRPC).To(Equal(&apiv1.GRPCAction{Port: 9999, Service: ptr.To("chaincode")}))
+		})
+
+		DescribeTable("should return an error for invalid probes",
+			func(value, expectedError string) {
+				_, err := util.ParseProbe(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the probe has no type", "9999", "probe '9999' must be exec:<command>, tcp:<port>, or grpc:<port>"),
+			Entry("When the probe has no command or port", "exec: ", "probe 'exec: ' must be exec:<command>, tcp:<port>, or grpc:<port>"),
+			Entry("When the probe type is not supported", "http:8080", "unsupported probe type 'http', must be 'exec', 'tcp', or 'grpc'"),
+			Entry("When the TCP port is not a number", "tcp:chaincode", "invalid probe port 'chaincode', must be between 1 and 65535"),
+			Entry("When the gRPC port is out of range", "grpc:70000", "invalid probe port '70000', must be between 1 and 65535"),
+		)
+	})
+
+	Describe("GetChaincodeProbes", func() {
+		var defaults util.ChaincodeProbes
+
+		BeforeEach(func() {
+			defaults = util.ChaincodeProbes{
+				Readiness: &apiv1.Probe{ProbeHandler: apiv1.ProbeHandler{TCPSocket: &apiv1.TCPSocketAction{Port: intstr.FromInt32(9999)}}},
+				Liveness:  &apiv1.Probe{ProbeHandler: apiv1.ProbeHandler{TCPSocket: &apiv1.TCPSocketAction{Port: intstr.FromInt32(9999)}}},
+			}
+		})
+
+		It("should return the builder defaults when image.json does not contain probes", func() {
+			imageData := &util.ImageJSON{Name: "nginx", Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"}
+
+			probes, err := util.GetChaincodeProbes(defaults, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(probes).To(Equal(defaults))
+		})
+
+		It("should use probes in image.json in preference to the builder defaults", func() {
+			imageData := &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+				Probes: &util.ProbesJSON{
+					Readiness: &util.ProbeJSON{
+						Exec:                []string{"/bin/healthcheck"},
+						InitialDelaySeconds: 5,
+						PeriodSeconds:       2,
+						TimeoutSeconds:      1,
+						FailureThreshold:    30,
+					},
+				},
+			}
+
+			probes, err := util.GetChaincodeProbes(defaults, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(probes.Readiness).To(Equal(&apiv1.Probe{
+				ProbeHandler:        apiv1.ProbeHandler{Exec: &apiv1.ExecAction{Command: []string{"/bin/healthcheck"}}},
+				InitialDelaySeconds: 5,
+				PeriodSeconds:       2,
+				TimeoutSeconds:      1,
+				FailureThreshold:    30,
+			}))
+			Expect(probes.Liveness).To(Equal(defaults.Liveness))
+		})
+
+		DescribeTable("should return an error for invalid probes in image.json",
+			func(probes *util.ProbesJSON, expectedError string) {
+				imageData := &util.ImageJSON{Name: "nginx", Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf", Probes: probes}
+
+				_, err := util.GetChaincodeProbes(defaults, imageData)
+				Expect(err).To(MatchError(expectedError))
+			},
+			Entry("When the probe has no handler", &util.ProbesJSON{Readiness: &util.ProbeJSON{PeriodSeconds: 5}}, "invalid readiness probe: probe must have exactly one of exec, tcpPort, or grpcPort"),
+			Entry("When the probe has more than one handler", &util.ProbesJSON{Liveness: &util.ProbeJSON{TCPPort: 9999, GRPCPort: 9999}}, "invalid liveness probe: probe must have exactly one of exec, tcpPort, or grpcPort"),
+			Entry("When the probe port is out of range", &util.ProbesJSON{Liveness: &util.ProbeJSON{TCPPort: 99999}}, "invalid liveness probe: probe port must be between 1 and 65535"),
+			Entry("When the probe has a gRPC service without a gRPC port", &util.ProbesJSON{Liveness: &util.ProbeJSON{TCPPort: 9999, GRPCService: "chaincode"}}, "invalid liveness probe: probe grpcService requires grpcPort"),
+			Entry("When the probe has a negative timing", &util.ProbesJSON{Readiness: &util.ProbeJSON{TCPPort: 9999, FailureThreshold: -1}}, "invalid readiness probe: probe timings must be zero or positive"),
+		)
+	})
+})
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
+)
+
+const registryTimeout = 30 * time.Second
+
+// GetImageReference returns the registry reference for the chaincode image
+// in the image.json file.
+func GetImageReference(imageData *ImageJSON) (name.Digest, error) {
+	ref, err := name.NewDigest(imageData.Name + "@" + imageData.Digest)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("invalid chaincode image %s@%s: %w", imageData.Name, imageData.Digest, err)
+	}
+
+	return ref, nil
+}
+
+// VerifyImageDigest checks that the chaincode image manifest exists in the
+// registry, so that a missing image or mistyped digest is reported when the
+// chaincode is installed rather than when the chaincode pod fails to start.
+// Registry credentials are loaded from the Docker config file, if there is one.
+func VerifyImageDigest(ctx context.Context, logger *log.CmdLogger, imageData *ImageJSON) error {
+	ref, err := GetImageReference(imageData)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("Verifying chaincode image %s exists in registry %s", ref.String(), ref.RegistryStr())
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+
+	spanCtx, span := tracing.Start(ctx, "registry.manifests.head", tracing.ImageKey.String(ref.String()))
+	descriptor, err := remote.Head(ref, remote.WithContext(spanCtx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	tracing.End(span, err)
+
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("chaincode image %s does not exist in registry %s", ref.String(), ref.RegistryStr())
+		}
+
+		return fmt.Errorf("unable to verify chaincode image %s in registry %s: %w", ref.String(), ref.RegistryStr(), err)
+	}
+
+	if descriptor.Digest.String() != ref.DigestStr() {
+		return fmt.Errorf("chaincode image %s has unexpected digest %s in registry %s", ref.String(), descriptor.Digest.String(), ref.RegistryStr())
+	}
+
+	logger.Debugf("Verified chaincode image %s: %s, %d bytes", ref.String(), descriptor.MediaType, descriptor.Size)
+
+	return nil
+}
+
+// ResolveImageJSON copies the chaincode image file, resolving the image tag to
+// a digest if the image.json file contains a tag instead of a digest, so that
+// chaincode is always run using an immutable image reference.
+func ResolveImageJSON(ctx context.Context, logger *log.CmdLogger, src, dest string, imagePolicy *ImagePolicy) error {
+	imageSrcPath := filepath.Join(src, ImageFile)
+
+	imageData, err := readImageJSONFile(logger, imageSrcPath)
+	if err != nil {
+		return err
+	}
+
+	if imageData.Digest != "" || imageData.Tag == "" {
+		return CopyImageJSON(logger, src, dest, imagePolicy)
+	}
+
+	if err := validateImageJSON(imageSrcPath, imageData); err != nil {
+		return err
+	}
+
+	// Check the image policy before connecting to the registry
+	if err := imagePolicy.CheckImage(imageData); err != nil {
+		return err
+	}
+
+	imageData.Digest, err = resolveImageTag(ctx, logger, imageData)
+	if err != nil {
+		return err
+	}
+
+	imageDestPath := filepath.Join(dest, ImageFile)
+	logger.Debugf("Writing chaincode image file %s", imageDestPath)
+
+	imageJSONContents, err := json.MarshalIndent(imageData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", imageDestPath, err)
+	}
+
+	if err := os.WriteFile(imageDestPath, imageJSONContents, 0o600); err != nil {
+		return fmt.Errorf("unable to write %s: %w", imageDestPath, err)
+	}
+
+	_, err = ReadImageJSON(logger, dest, imagePolicy)
+
+	return err
+}
+
+// resolveImageTag returns the digest of the chaincode image tag in the
+// registry.
+func resolveImageTag(ctx context.Context, logger *log.CmdLogger, imageData *ImageJSON) (string, error) {
+	ref, err := name.NewTag(imageData.Name + ":" + imageData.Tag)
+	if err != nil {
+		return "", fmt.Errorf("invalid chaincode image %s:%s: %w", imageData.Name, imageData.Tag, err)
+	}
+
+	logger.Debugf("Resolving chaincode image %s using registry %s", ref.String(), ref.RegistryStr())
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+
+	spanCtx, span := tracing.Start(ctx, "registry.manifests.head", tracing.ImageKey.String(ref.String()))
+	descriptor, err := remote.Head(ref, remote.WithContext(spanCtx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	tracing.End(span, err)
+
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return "", fmt.Errorf("chaincode image %s does not exist in registry %s", ref.String(), ref.RegistryStr())
+		}
+
+		return "", fmt.Errorf("unable to resolve chaincode image %s using registry %s: %w", ref.String(), ref.RegistryStr(), err)
+	}
+
+	logger.Printf("Resolved chaincode image %s to digest %s", ref.String(), descriptor.Digest.String())
+
+	return descriptor.Digest.String(), nil
+}
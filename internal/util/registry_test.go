@@ -0,0 +1,176 @@
+package util_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	Describe("VerifyImageDigest", func() {
+		var (
+			ctx          context.Context
+			logger       *log.CmdLogger
+			registryHost string
+			imageName    string
+			imageDigest  string
+		)
+
+		BeforeEach(func() {
+			ctx = log.NewCmdContext(context.Background(), false)
+			logger = log.New(ctx)
+
+			server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+			DeferCleanup(server.Close)
+
+			registryHost = strings.TrimPrefix(server.URL, "http://")
+			imageName = registryHost + "/hyperledger/asset-transfer-basic"
+
+			image, err := random.Image(1024, 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			ref, err := name.ParseReference(imageName + ":latest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, image)).To(Succeed())
+
+			digest, err := image.Digest()
+			Expect(err).NotTo(HaveOccurred())
+
+			imageDigest = digest.String()
+		})
+
+		It("should verify a chaincode image which exists in the registry", func() {
+			imageData := &util.ImageJSON{Name: imageName, Digest: imageDigest}
+
+			Expect(util.VerifyImageDigest(ctx, logger, imageData)).To(Succeed())
+		})
+
+		It("should return an error if the chaincode image digest does not exist in the registry", func() {
+			imageData := &util.ImageJSON{
+				Name:   imageName,
+				Digest: "sha256:b35962f000d26ad046d4102f22d70a1351692fc69a9ddead89dfa13aefb942a7",
+			}
+
+			err := util.VerifyImageDigest(ctx, logger, imageData)
+			Expect(err).To(MatchError(
+				"chaincode image " + imageName + "@sha256:b35962f000d26ad046d4102f22d70a1351692fc69a9ddead89dfa13aefb942a7 does not exist in registry " + registryHost,
+			))
+		})
+
+		It("should return an error if the chaincode image repository does not exist in the registry", func() {
+			imageData := &util.ImageJSON{Name: registryHost + "/hyperledger/missing", Digest: imageDigest}
+
+			err := util.VerifyImageDigest(ctx, logger, imageData)
+			Expect(err).To(MatchError(ContainSubstring("does not exist in registry " + registryHost)))
+		})
+
+		It("should return an error if the chaincode image name is invalid", func() {
+			imageData := &util.ImageJSON{Name: "Invalid Image", Digest: imageDigest}
+
+			err := util.VerifyImageDigest(ctx, logger, imageData)
+			Expect(err).To(MatchError(ContainSubstring("invalid chaincode image Invalid Image@" + imageDigest)))
+		})
+	})
+
+	Describe("ResolveImageJSON", func() {
+		var (
+			ctx         context.Context
+			logger      *log.CmdLogger
+			imageName   string
+			imageDigest string
+			srcDir      string
+			destDir     string
+		)
+
+		writeImageJSON := func(contents string) {
+			Expect(os.WriteFile(filepath.Join(srcDir, util.ImageFile), []byte(contents), 0o600)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			ctx = log.NewCmdContext(context.Background(), false)
+			logger = log.New(ctx)
+
+			server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+			DeferCleanup(server.Close)
+
+			imageName = strings.TrimPrefix(server.URL, "http://") + "/hyperledger/asset-transfer-basic"
+
+			image, err := random.Image(1024, 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			ref, err := name.ParseReference(imageName + ":v1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, image)).To(Succeed())
+
+			digest, err := image.Digest()
+			Expect(err).NotTo(HaveOccurred())
+
+			imageDigest = digest.String()
+			srcDir = GinkgoT().TempDir()
+			destDir = GinkgoT().TempDir()
+		})
+
+		It("should write the resolved digest for a chaincode image tag", func() {
+			writeImageJSON(fmt.Sprintf(`{"name": %q, "tag": "v1.0.0"}`, imageName))
+
+			Expect(util.ResolveImageJSON(ctx, logger, srcDir, destDir, nil)).To(Succeed())
+
+			imageData, err := util.ReadImageJSON(logger, destDir, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imageData.Name).To(Equal(imageName))
+			Expect(imageData.Tag).To(Equal("v1.0.0"))
+			Expect(imageData.Digest).To(Equal(imageDigest))
+		})
+
+		It("should copy a chaincode image file which already contains a digest", func() {
+			writeImageJSON(fmt.Sprintf(`{"name": %q, "tag": "v2.0.0", "digest": %q}`, imageName, imageDigest))
+
+			Expect(util.ResolveImageJSON(ctx, logger, srcDir, destDir, nil)).To(Succeed())
+
+			imageData, err := util.ReadImageJSON(logger, destDir, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imageData.Tag).To(Equal("v2.0.0"))
+			Expect(imageData.Digest).To(Equal(imageDigest))
+		})
+
+		It("should return an error if the chaincode image tag does not exist in the registry", func() {
+			writeImageJSON(fmt.Sprintf(`{"name": %q, "tag": "v9.9.9"}`, imageName))
+
+			err := util.ResolveImageJSON(ctx, logger, srcDir, destDir, nil)
+			Expect(err).To(MatchError(ContainSubstring("chaincode image " + imageName + ":v9.9.9 does not exist in registry")))
+			Expect(filepath.Join(destDir, util.ImageFile)).NotTo(BeAnExistingFile())
+		})
+
+		It("should return an error if the chaincode image file contains an invalid tag", func() {
+			writeImageJSON(fmt.Sprintf(`{"name": %q, "tag": "not a tag"}`, imageName))
+
+			err := util.ResolveImageJSON(ctx, logger, srcDir, destDir, nil)
+			Expect(err).To(MatchError(ContainSubstring("file contains invalid 'tag'")))
+		})
+
+		It("should check the image policy before resolving the chaincode image tag", func() {
+			writeImageJSON(fmt.Sprintf(`{"name": %q, "tag": "v1.0.0"}`, imageName))
+
+			imagePolicy, err := util.NewImagePolicy(nil, []string{"*/hyperledger/*"})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = util.ResolveImageJSON(ctx, logger, srcDir, destDir, imagePolicy)
+			Expect(err).To(MatchError(ContainSubstring("is not allowed by image denylist rule '*/hyperledger/*'")))
+			Expect(filepath.Join(destDir, util.ImageFile)).NotTo(BeAnExistingFile())
+		})
+	})
+})
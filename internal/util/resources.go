@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var errNegativeQuantity = errors.New("quantity must not be negative")
+
+// ParseResourceQuantity returns the Kubernetes quantity for the provided value,
+// which must not be negative.
+func ParseResourceQuantity(value string) (resource.Quantity, error) {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return quantity, err
+	}
+
+	if quantity.Sign() < 0 {
+		return quantity, errNegativeQuantity
+	}
+
+	return quantity, nil
+}
+
+// ParseResourceList returns a Kubernetes resource list for the provided map of
+// resource names to quantities. Only cpu and memory resources are supported.
+func ParseResourceList(resources map[string]string) (apiv1.ResourceList, error) {
+	resourceList := apiv1.ResourceList{}
+
+	for _, name := range slices.Sorted(maps.Keys(resources)) {
+		resourceName := apiv1.ResourceName(name)
+		if resourceName != apiv1.ResourceCPU && resourceName != apiv1.ResourceMemory {
+			return nil, fmt.Errorf("unsupported resource '%s', must be '%s' or '%s'", name, apiv1.ResourceCPU, apiv1.ResourceMemory)
+		}
+
+		quantity, err := ParseResourceQuantity(resources[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s quantity '%s': %w", name, resources[name], err)
+		}
+
+		resourceList[resourceName] = quantity
+	}
+
+	return resourceList, nil
+}
+
+// ParseResourceRequirements returns Kubernetes resource requirements for the
+// provided resources in the image.json file.
+func ParseResourceRequirements(resources *ResourcesJSON) (apiv1.ResourceRequirements, error) {
+	var requirements apiv1.ResourceRequirements
+
+	if resources == nil {
+		return requirements, nil
+	}
+
+	requests, err := ParseResourceList(resources.Requests)
+	if err != nil {
+		return requirements, fmt.Errorf("invalid resource requests: %w", err)
+	}
+
+	limits, err := ParseResourceList(resources.Limits)
+	if err != nil {
+		return requirements, fmt.Errorf("invalid resource limits: %w", err)
+	}
+
+	if len(requests) > 0 {
+		requirements.Requests = requests
+	}
+
+	if len(limits) > 0 {
+		requirements.Limits = limits
+	}
+
+	return requirements, nil
+}
+
+// GetChaincodeResources returns the resource requirements for the chaincode
+// container, using any requests or limits in the image.json file in preference
+// to the builder defaults.
+func GetChaincodeResources(defaults apiv1.ResourceRequirements, imageData *ImageJSON) (apiv1.ResourceRequirements, error) {
+	overrides, err := ParseResourceRequirements(imageData.Resources)
+	if err != nil {
+		return apiv1.ResourceRequirements{}, err
+	}
+
+	requirements := apiv1.ResourceRequirements{
+		Requests: mergeResourceLists(defaults.Requests, overrides.Requests),
+		Limits:   mergeResourceLists(defaults.Limits, overrides.Limits),
+	}
+
+	for name, request := range requirements.Requests {
+		if limit, ok := requirements.Limits[name]; ok && request.Cmp(limit) > 0 {
+			return apiv1.ResourceRequirements{}, fmt.Errorf(
+				"%s request %s must be less than or equal to %s limit %s",
+				name,
+				request.String(),
+				name,
+				limit.String(),
+			)
+		}
+	}
+
+	return requirements, nil
+}
+
+func mergeResourceLists(defaults, overrides apiv1.ResourceList) apiv1.ResourceList {
+	if len(defaults) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	merged := apiv1.ResourceList{}
+	maps.Copy(merged, defaults)
+	maps.Copy(merged, overrides)
+
+	return merged
+}
@@ -0,0 +1,110 @@
+package util_test
+
+import (
+	"fmt"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("Resources", func() {
+	Describe("ParseResourceList", func() {
+		It("should return a resource list for valid cpu and memory quantities", func() {
+			resourceList, err := util.ParseResourceList(map[string]string{"cpu": "250m", "memory": "64Mi"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resourceList).To(HaveLen(2))
+			Expect(resourceList.Cpu().String()).To(Equal("250m"))
+			Expect(resourceList.Memory().String()).To(Equal("64Mi"))
+		})
+
+		It("should return an error for an invalid quantity", func() {
+			_, err := util.ParseResourceList(map[string]string{"cpu": "lots"})
+			Expect(err).To(MatchError(ContainSubstring("invalid cpu quantity 'lots'")))
+		})
+
+		DescribeTable("should return an error for a negative quantity",
+			func(name, value string) {
+				_, err := util.ParseResourceList(map[string]string{name: value})
+				Expect(err).To(MatchError(fmt.Sprintf("invalid %s quantity '%s': quantity must not be negative", name, value)))
+			},
+			Entry("When the cpu quantity is negative", "cpu", "-500m"),
+			Entry("When the memory quantity is negative", "memory", "-1"),
+		)
+
+		It("should return an error for an unsupported resource", func() {
+			_, err := util.ParseResourceList(map[string]string{"nvidia.com/gpu": "1"})
+			Expect(err).To(MatchError(ContainSubstring("unsupported resource 'nvidia.com/gpu'")))
+		})
+	})
+
+	Describe("GetChaincodeResources", func() {
+		var defaults apiv1.ResourceRequirements
+
+		BeforeEach(func() {
+			defaults = apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{
+					apiv1.ResourceCPU:    resource.MustParse("100m"),
+					apiv1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+				Limits: apiv1.ResourceList{
+					apiv1.ResourceCPU:    resource.MustParse("500m"),
+					apiv1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+			}
+		})
+
+		It("should return the builder defaults when image.json does not contain resources", func() {
+			imageData := &util.ImageJSON{Name: "nginx", Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"}
+
+			resources, err := util.GetChaincodeResources(defaults, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources.Requests.Cpu().String()).To(Equal("100m"))
+			Expect(resources.Requests.Memory().String()).To(Equal("128Mi"))
+			Expect(resources.Limits.Cpu().String()).To(Equal("500m"))
+			Expect(resources.Limits.Memory().String()).To(Equal("256Mi"))
+		})
+
+		It("should return no resource requirements when there are no defaults or overrides", func() {
+			imageData := &util.ImageJSON{Name: "nginx", Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"}
+
+			resources, err := util.GetChaincodeResources(apiv1.ResourceRequirements{}, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources.Requests).To(BeNil())
+			Expect(resources.Limits).To(BeNil())
+		})
+
+		It("should override individual builder defaults with image.json resources", func() {
+			imageData := &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+				Resources: &util.ResourcesJSON{
+					Requests: map[string]string{"memory": "200Mi"},
+					Limits:   map[string]string{"memory": "1Gi"},
+				},
+			}
+
+			resources, err := util.GetChaincodeResources(defaults, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources.Requests.Cpu().String()).To(Equal("100m"))
+			Expect(resources.Requests.Memory().String()).To(Equal("200Mi"))
+			Expect(resources.Limits.Cpu().String()).To(Equal("500m"))
+			Expect(resources.Limits.Memory().String()).To(Equal("1Gi"))
+		})
+
+		It("should return an error when a request is greater than the limit", func() {
+			imageData := &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+				Resources: &util.ResourcesJSON{
+					Requests: map[string]string{"cpu": "2"},
+				},
+			}
+
+			_, err := util.GetChaincodeResources(defaults, imageData)
+			Expect(err).To(MatchError("cpu request 2 must be less than or equal to cpu limit 500m"))
+		})
+	})
+})
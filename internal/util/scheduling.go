@@ -0,0 +1,375 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	nodeRoleLabel = "fabric-builder-k8s-role"
+
+	// Pod anti-affinity types.
+	PodAntiAffinityPreferred = "preferred"
+	PodAntiAffinityRequired  = "required"
+
+	// DefaultTopologyKey is the topology key used for pod anti-affinity if
+	// one is not specified, which spreads chaincode pods across nodes.
+	DefaultTopologyKey = apiv1.LabelHostname
+
+	topologySpreadMaxSkew         = 1
+	preferredAntiAffinityWeight   = 100
+	defaultTopologySpreadBehavior = apiv1.ScheduleAnyway
+)
+
+// SchedulingPolicy configures where chaincode pods are scheduled, in addition
+// to the optional node role. Topology spread constraints and pod anti-affinity
+// apply to pods running the same chaincode package, identified by the
+// fabric-builder-k8s-cclabel and fabric-builder-k8s-cchash labels, so that
+// chaincode for several peers does not all run on a single node or zone.
+type SchedulingPolicy struct {
+	// NodeRequirements are node label requirements which must all be met.
+	NodeRequirements []apiv1.NodeSelectorRequirement
+
+	// Tolerations allow chaincode pods to be scheduled on tainted nodes.
+	Tolerations []apiv1.Toleration
+
+	// TopologySpread spreads chaincode pods evenly across each topology.
+	TopologySpread []TopologySpread
+
+	// PodAntiAffinity keeps chaincode pods apart, if it is set.
+	PodAntiAffinity *PodAntiAffinity
+}
+
+// TopologySpread spreads chaincode pods across the topology domains, e.g.
+// nodes or zones, identified by a node label.
+type TopologySpread struct {
+	// TopologyKey is the node label which identifies the topology domain.
+	TopologyKey string
+
+	// WhenUnsatisfiable is either ScheduleAnyway or DoNotSchedule. Defaults
+	// to ScheduleAnyway.
+	WhenUnsatisfiable apiv1.UnsatisfiableConstraintAction
+}
+
+// PodAntiAffinity prevents, or discourages, chaincode pods from running in the
+// same topology domain.
+type PodAntiAffinity struct {
+	// Required prevents chaincode pods being scheduled in the same topology
+	// domain, rather than only preferring different topology domains.
+	Required bool
+
+	// TopologyKey is the node label which identifies the topology domain.
+	TopologyKey string
+}
+
+// ParseNodeSelector returns node requirements for a Kubernetes label selector,
+// e.g. disktype=ssd,topology.kubernetes.io/zone in (zone-a,zone-b).
+func ParseNodeSelector(value string) ([]apiv1.NodeSelectorRequirement, error) {
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector '%s': %w", value, err)
+	}
+
+	selectorRequirements, _ := selector.Requirements()
+	requirements := make([]apiv1.NodeSelectorRequirement, 0, len(selectorRequirements))
+
+	for _, requirement := range selectorRequirements {
+		operator, err := getNodeSelectorOperator(requirement.Operator())
+		if err != nil {
+			return nil, fmt.Errorf("invalid node selector '%s': %w", value, err)
+		}
+
+		requirements = append(requirements, apiv1.NodeSelectorRequirement{
+			Key:      requirement.Key(),
+			Operator: operator,
+			Values:   requirement.ValuesUnsorted(),
+		})
+	}
+
+	return requirements, nil
+}
+
+func getNodeSelectorOperator(operator selection.Operator) (apiv1.NodeSelectorOperator, error) {
+	switch operator {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		return apiv1.NodeSelectorOpIn, nil
+	case selection.NotEquals, selection.NotIn:
+		return apiv1.NodeSelectorOpNotIn, nil
+	case selection.Exists:
+		return apiv1.NodeSelectorOpExists, nil
+	case selection.DoesNotExist:
+		return apiv1.NodeSelectorOpDoesNotExist, nil
+	case selection.GreaterThan:
+		return apiv1.NodeSelectorOpGt, nil
+	case selection.LessThan:
+		return apiv1.NodeSelectorOpLt, nil
+	default:
+		return "", fmt.Errorf("unsupported operator '%s'", operator)
+	}
+}
+
+// ParseTolerations returns tolerations for a comma separated list of taints
+// using the kubectl taint syntax, key[=value][:effect]. Tolerations without a
+// value tolerate any value, and tolerations without an effect tolerate all
+// effects.
+func ParseTolerations(value string) ([]apiv1.Toleration, error) {
+	tolerations := []apiv1.Toleration{}
+
+	for _, taint := range strings.Split(value, ",") {
+		taint = strings.TrimSpace(taint)
+		if taint == "" {
+			continue
+		}
+
+		toleration, err := parseToleration(taint)
+		if err != nil {
+			return nil, err
+		}
+
+		tolerations = append(tolerations, toleration)
+	}
+
+	return tolerations, nil
+}
+
+func parseToleration(taint string) (apiv1.Toleration, error) {
+	keyValue, effect, _ := strings.Cut(taint, ":")
+	key, value, hasValue := strings.Cut(keyValue, "=")
+
+	if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+		return apiv1.Toleration{}, fmt.Errorf("toleration '%s' has an invalid key: %s", taint, msgs[0])
+	}
+
+	toleration := apiv1.Toleration{
+		Key:      key,
+		Operator: apiv1.TolerationOpExists,
+		Effect:   apiv1.TaintEffect(effect),
+	}
+
+	if hasValue {
+		if msgs := validation.IsValidLabelValue(value); len(msgs) > 0 {
+			return apiv1.Toleration{}, fmt.Errorf("toleration '%s' has an invalid value: %s", taint, msgs[0])
+		}
+
+		toleration.Operator = apiv1.TolerationOpEqual
+		toleration.Value = value
+	}
+
+	switch toleration.Effect {
+	case "", apiv1.TaintEffectNoSchedule, apiv1.TaintEffectPreferNoSchedule, apiv1.TaintEffectNoExecute:
+		return toleration, nil
+	default:
+		return apiv1.Toleration{}, fmt.Errorf(
+			"toleration '%s' has an unsupported effect '%s', must be '%s', '%s', or '%s'",
+			taint,
+			effect,
+			apiv1.TaintEffectNoSchedule,
+			apiv1.TaintEffectPreferNoSchedule,
+			apiv1.TaintEffectNoExecute,
+		)
+	}
+}
+
+// ParseTopologySpread returns topology spread settings for a comma separated
+// list of topology keys, each with an optional when unsatisfiable action, e.g.
+// topology.kubernetes.io/zone,kubernetes.io/hostname:DoNotSchedule.
+func ParseTopologySpread(value string) ([]TopologySpread, error) {
+	topologySpread := []TopologySpread{}
+
+	for _, spread := range strings.Split(value, ",") {
+		spread = strings.TrimSpace(spread)
+		if spread == "" {
+			continue
+		}
+
+		topologyKey, action, _ := strings.Cut(spread, ":")
+
+		if err := validateTopologyKey(topologyKey); err != nil {
+			return nil, fmt.Errorf("invalid topology spread '%s': %w", spread, err)
+		}
+
+		whenUnsatisfiable := apiv1.UnsatisfiableConstraintAction(action)
+
+		switch whenUnsatisfiable {
+		case "":
+			whenUnsatisfiable = defaultTopologySpreadBehavior
+		case apiv1.ScheduleAnyway, apiv1.DoNotSchedule:
+		default:
+			return nil, fmt.Errorf(
+				"invalid topology spread '%s': unsupported action '%s', must be '%s' or '%s'",
+				spread,
+				action,
+				apiv1.ScheduleAnyway,
+				apiv1.DoNotSchedule,
+			)
+		}
+
+		topologySpread = append(topologySpread, TopologySpread{
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: whenUnsatisfiable,
+		})
+	}
+
+	return topologySpread, nil
+}
+
+// ParsePodAntiAffinity returns pod anti-affinity settings for a value of
+// preferred[:<topology_key>] or required[:<topology_key>]. The topology key
+// defaults to kubernetes.io/hostname.
+func ParsePodAntiAffinity(value string) (*PodAntiAffinity, error) {
+	antiAffinityType, topologyKey, _ := strings.Cut(value, ":")
+
+	if topologyKey == "" {
+		topologyKey = DefaultTopologyKey
+	}
+
+	if err := validateTopologyKey(topologyKey); err != nil {
+		return nil, fmt.Errorf("invalid pod anti-affinity '%s': %w", value, err)
+	}
+
+	switch antiAffinityType {
+	case PodAntiAffinityPreferred, PodAntiAffinityRequired:
+		return &PodAntiAffinity{
+			Required:    antiAffinityType == PodAntiAffinityRequired,
+			TopologyKey: topologyKey,
+		}, nil
+	default:
+		return nil, fmt.Errorf(
+			"unsupported pod anti-affinity '%s', must be '%s' or '%s'",
+			antiAffinityType,
+			PodAntiAffinityPreferred,
+			PodAntiAffinityRequired,
+		)
+	}
+}
+
+func validateTopologyKey(topologyKey string) error {
+	if msgs := validation.IsQualifiedName(topologyKey); len(msgs) > 0 {
+		return fmt.Errorf("invalid topology key '%s': %s", topologyKey, msgs[0])
+	}
+
+	return nil
+}
+
+// setSchedulingPolicy configures the pod template with the node requirements,
+// tolerations, topology spread constraints, and pod anti-affinity in the
+// scheduling policy, in addition to any existing scheduling settings.
+func setSchedulingPolicy(podTemplate *apiv1.PodTemplateSpec, policy SchedulingPolicy) {
+	podSpec := &podTemplate.Spec
+
+	addNodeRequirements(podSpec, policy.NodeRequirements...)
+	podSpec.Tolerations = append(podSpec.Tolerations, policy.Tolerations...)
+
+	chaincodeSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			packageLabelLabel: podTemplate.Labels[packageLabelLabel],
+			packageHashLabel:  podTemplate.Labels[packageHashLabel],
+		},
+	}
+
+	for _, spread := range policy.TopologySpread {
+		podSpec.TopologySpreadConstraints = append(podSpec.TopologySpreadConstraints, apiv1.TopologySpreadConstraint{
+			MaxSkew:           topologySpreadMaxSkew,
+			TopologyKey:       spread.TopologyKey,
+			WhenUnsatisfiable: spread.WhenUnsatisfiable,
+			LabelSelector:     chaincodeSelector,
+		})
+	}
+
+	if policy.PodAntiAffinity != nil {
+		setPodAntiAffinity(podSpec, *policy.PodAntiAffinity, chaincodeSelector)
+	}
+}
+
+func setPodAntiAffinity(podSpec *apiv1.PodSpec, antiAffinity PodAntiAffinity, selector *metav1.LabelSelector) {
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &apiv1.Affinity{}
+	}
+
+	if podSpec.Affinity.PodAntiAffinity == nil {
+		podSpec.Affinity.PodAntiAffinity = &apiv1.PodAntiAffinity{}
+	}
+
+	term := apiv1.PodAffinityTerm{
+		LabelSelector: selector,
+		TopologyKey:   antiAffinity.TopologyKey,
+	}
+
+	podAntiAffinity := podSpec.Affinity.PodAntiAffinity
+
+	if antiAffinity.Required {
+		podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+			podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			term,
+		)
+
+		return
+	}
+
+	podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		apiv1.WeightedPodAffinityTerm{
+			Weight:          preferredAntiAffinityWeight,
+			PodAffinityTerm: term,
+		},
+	)
+}
+
+// setNodeRole configures the pod spec with an affinity for, and a toleration
+// of, nodes with the fabric-builder-k8s-role label and taint.
+func setNodeRole(podSpec *apiv1.PodSpec, nodeRole string) {
+	addNodeRequirements(podSpec, apiv1.NodeSelectorRequirement{
+		Key:      nodeRoleLabel,
+		Operator: apiv1.NodeSelectorOpIn,
+		Values:   []string{nodeRole},
+	})
+
+	podSpec.Tolerations = append(podSpec.Tolerations, apiv1.Toleration{
+		Key:      nodeRoleLabel,
+		Operator: apiv1.TolerationOpEqual,
+		Value:    nodeRole,
+		Effect:   apiv1.TaintEffectNoSchedule,
+	})
+}
+
+// addNodeRequirements adds the requirements to every required node affinity
+// term, so that nodes must meet the new requirements as well as any existing
+// requirements.
+func addNodeRequirements(podSpec *apiv1.PodSpec, requirements ...apiv1.NodeSelectorRequirement) {
+	if len(requirements) == 0 {
+		return
+	}
+
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &apiv1.Affinity{}
+	}
+
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &apiv1.NodeAffinity{}
+	}
+
+	if podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &apiv1.NodeSelector{}
+	}
+
+	nodeSelector := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []apiv1.NodeSelectorTerm{{}}
+	}
+
+	for i := range nodeSelector.NodeSelectorTerms {
+		nodeSelector.NodeSelectorTerms[i].MatchExpressions = append(
+			nodeSelector.NodeSelectorTerms[i].MatchExpressions,
+			requirements...,
+		)
+	}
+}
@@ -0,0 +1,95 @@
+package util_test
+
+import (
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Scheduling", func() {
+	Describe("ParseNodeSelector", func() {
+		It("should return node requirements for a label selector", func() {
+			requirements, err := util.ParseNodeSelector("disktype=ssd,topology.kubernetes.io/zone in (zone-a,zone-b),gpu notin (none),!spot,cores>4")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requirements).To(ConsistOf(
+				apiv1.NodeSelectorRequirement{Key: "disktype", Operator: apiv1.NodeSelectorOpIn, Values: []string{"ssd"}},
+				apiv1.NodeSelectorRequirement{Key: "topology.kubernetes.io/zone", Operator: apiv1.NodeSelectorOpIn, Values: []string{"zone-a", "zone-b"}},
+				apiv1.NodeSelectorRequirement{Key: "gpu", Operator: apiv1.NodeSelectorOpNotIn, Values: []string{"none"}},
+				apiv1.NodeSelectorRequirement{Key: "spot", Operator: apiv1.NodeSelectorOpDoesNotExist, Values: []string{}},
+				apiv1.NodeSelectorRequirement{Key: "cores", Operator: apiv1.NodeSelectorOpGt, Values: []string{"4"}},
+			))
+		})
+
+		It("should return an error for an invalid label selector", func() {
+			_, err := util.ParseNodeSelector("disktype in ssd")
+			Expect(err).To(MatchError(ContainSubstring("invalid node selector 'disktype in ssd'")))
+		})
+	})
+
+	Describe("ParseTolerations", func() {
+		It("should return tolerations for a list of taints", func() {
+			tolerations, err := util.ParseTolerations("dedicated=chaincode:NoSchedule, gpu:NoExecute,spot=true,maintenance")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tolerations).To(Equal([]apiv1.Toleration{
+				{Key: "dedicated", Operator: apiv1.TolerationOpEqual, Value: "chaincode", Effect: apiv1.TaintEffectNoSchedule},
+				{Key: "gpu", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoExecute},
+				{Key: "spot", Operator: apiv1.TolerationOpEqual, Value: "true"},
+				{Key: "maintenance", Operator: apiv1.TolerationOpExists},
+			}))
+		})
+
+		DescribeTable("should return an error for invalid taints",
+			func(value, expectedError string) {
+				_, err := util.ParseTolerations(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the key is missing", "=chaincode:NoSchedule", "toleration '=chaincode:NoSchedule' has an invalid key"),
+			Entry("When the value is invalid", "dedicated=chain code", "toleration 'dedicated=chain code' has an invalid value"),
+			Entry("When the effect is not supported", "dedicated=chaincode:NoRun", "toleration 'dedicated=chaincode:NoRun' has an unsupported effect 'NoRun', must be 'NoSchedule', 'PreferNoSchedule', or 'NoExecute'"),
+		)
+	})
+
+	Describe("ParseTopologySpread", func() {
+		It("should return topology spread settings for a list of topology keys", func() {
+			topologySpread, err := util.ParseTopologySpread("topology.kubernetes.io/zone,kubernetes.io/hostname:DoNotSchedule")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(topologySpread).To(Equal([]util.TopologySpread{
+				{TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: apiv1.ScheduleAnyway},
+				{TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: apiv1.DoNotSchedule},
+			}))
+		})
+
+		DescribeTable("should return an error for invalid topology spread settings",
+			func(value, expectedError string) {
+				_, err := util.ParseTopologySpread(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the topology key is invalid", "zone/", "invalid topology spread 'zone/': invalid topology key 'zone/'"),
+			Entry("When the action is not supported", "kubernetes.io/hostname:Never", "unsupported action 'Never', must be 'ScheduleAnyway' or 'DoNotSchedule'"),
+		)
+	})
+
+	Describe("ParsePodAntiAffinity", func() {
+		It("should return preferred pod anti-affinity across nodes by default", func() {
+			antiAffinity, err := util.ParsePodAntiAffinity("preferred")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(antiAffinity).To(Equal(&util.PodAntiAffinity{Required: false, TopologyKey: "kubernetes.io/hostname"}))
+		})
+
+		It("should return required pod anti-affinity for the specified topology key", func() {
+			antiAffinity, err := util.ParsePodAntiAffinity("required:topology.kubernetes.io/zone")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(antiAffinity).To(Equal(&util.PodAntiAffinity{Required: true, TopologyKey: "topology.kubernetes.io/zone"}))
+		})
+
+		DescribeTable("should return an error for invalid pod anti-affinity",
+			func(value, expectedError string) {
+				_, err := util.ParsePodAntiAffinity(value)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the type is not supported", "always", "unsupported pod anti-affinity 'always', must be 'preferred' or 'required'"),
+			Entry("When the topology key is invalid", "required:zone/", "invalid pod anti-affinity 'required:zone/': invalid topology key 'zone/'"),
+		)
+	})
+})
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	typedBatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// orphanedSecretMinimumAge avoids deleting secrets which have just been
+// applied for a chaincode job which has not been created yet.
+const orphanedSecretMinimumAge = time.Minute
+
+// SetChaincodeSecretOwner makes the chaincode job the only owner of the
+// chaincode secret, so that Kubernetes deletes the secret when the job is
+// deleted. The secret is reused by later runs of the same chaincode, so any
+// owner references to earlier jobs are replaced, using a JSON merge patch,
+// otherwise deleting an earlier job would also delete the secret which the
+// current job needs.
+func SetChaincodeSecretOwner(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	secretsClient v1.SecretInterface,
+	secretName string,
+	job *batchv1.Job,
+) error {
+	ownerReferences := []metav1.OwnerReference{
+		{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+			Name:       job.Name,
+			UID:        job.UID,
+		},
+	}
+
+	err := patchChaincodeSecretOwners(ctx, secretsClient, secretName, ownerReferences)
+	if err != nil {
+		return fmt.Errorf("error setting owner of chaincode secret %s/%s to job %s: %w", job.Namespace, secretName, job.Name, err)
+	}
+
+	logger.Debugf("Set job %s as the owner of chaincode secret %s/%s", job.Name, job.Namespace, secretName)
+
+	return nil
+}
+
+// RemoveChaincodeSecretOwners removes all owner references from the chaincode
+// secret, if it exists, so that it is not deleted with earlier chaincode jobs
+// before it is reused for a new job.
+func RemoveChaincodeSecretOwners(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	secretsClient v1.SecretInterface,
+	secretName, namespace string,
+) error {
+	err := patchChaincodeSecretOwners(ctx, secretsClient, secretName, nil)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error removing owners of chaincode secret %s/%s: %w", namespace, secretName, err)
+	}
+
+	logger.Debugf("Removed owners of chaincode secret %s/%s", namespace, secretName)
+
+	return nil
+}
+
+// patchChaincodeSecretOwners replaces the owner references of the chaincode
+// secret, or removes them if there are none.
+func patchChaincodeSecretOwners(
+	ctx context.Context,
+	secretsClient v1.SecretInterface,
+	secretName string,
+	ownerReferences []metav1.OwnerReference,
+) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"ownerReferences": ownerReferences,
+		},
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("error encoding owner references: %w", err)
+	}
+
+	_, err = secretsClient.Patch(ctx, secretName, types.MergePatchType, data, metav1.PatchOptions{FieldManager: fabricBuilderK8s})
+
+	return err
+}
+
+// DeleteOrphanedChaincodeSecrets deletes chaincode secrets in the namespace
+// which are not used by any chaincode jobs that are still running.
+func DeleteOrphanedChaincodeSecrets(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	secretsClient v1.SecretInterface,
+	jobsClient typedBatchv1.JobInterface,
+	namespace string,
+) error {
+	selector, err := getChaincodeObjectSelector()
+	if err != nil {
+		return err
+	}
+
+	secrets, err := secretsClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return fmt.Errorf("error listing chaincode secrets in namespace %s: %w", namespace, err)
+	}
+
+	if len(secrets.Items) == 0 {
+		return nil
+	}
+
+	jobs, err := jobsClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return fmt.Errorf("error listing chaincode jobs in namespace %s: %w", namespace, err)
+	}
+
+	runningJobs := make(map[string]bool)
+
+	for i := range jobs.Items {
+		if !isJobFinished(&jobs.Items[i]) {
+			runningJobs[getChaincodeObjectKey(&jobs.Items[i].ObjectMeta)] = true
+		}
+	}
+
+	var errs []error
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		if runningJobs[getChaincodeObjectKey(&secret.ObjectMeta)] {
+			continue
+		}
+
+		if time.Since(getLastUpdated(&secret.ObjectMeta)) < orphanedSecretMinimumAge {
+			logger.Debugf("Skipping recently updated chaincode secret %s/%s", secret.Namespace, secret.Name)
+
+			continue
+		}
+
+		logger.Debugf("Deleting orphaned chaincode secret %s/%s", secret.Namespace, secret.Name)
+
+		err := secretsClient.Delete(ctx, secret.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: ptr.To(secret.UID)},
+		})
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			errs = append(errs, fmt.Errorf("error deleting orphaned chaincode secret %s/%s: %w", secret.Namespace, secret.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetImagePullSecrets returns the image pull secrets for the chaincode pod,
+// including the builder image pull secrets and any image pull secret named in
+// the image.json file. Each secret must exist in the chaincode namespace, since
+// Kubernetes would otherwise only report image pull failures after the
+// chaincode pod has been created.
+func GetImagePullSecrets(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	secretsClient v1.SecretInterface,
+	namespace string,
+	secretNames []string,
+	imageData *ImageJSON,
+) ([]apiv1.LocalObjectReference, error) {
+	names := slices.Clone(secretNames)
+	if imageData.ImagePullSecret != "" && !slices.Contains(names, imageData.ImagePullSecret) {
+		names = append(names, imageData.ImagePullSecret)
+	}
+
+	imagePullSecrets := make([]apiv1.LocalObjectReference, 0, len(names))
+
+	for _, name := range names {
+		secret, err := secretsClient.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("image pull secret %s/%s does not exist", namespace, name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error getting image pull secret %s/%s: %w", namespace, name, err)
+		}
+
+		if secret.Type != apiv1.SecretTypeDockerConfigJson && secret.Type != apiv1.SecretTypeDockercfg {
+			return nil, fmt.Errorf(
+				"image pull secret %s/%s must be of type %s or %s, not %s",
+				namespace,
+				name,
+				apiv1.SecretTypeDockerConfigJson,
+				apiv1.SecretTypeDockercfg,
+				secret.Type,
+			)
+		}
+
+		logger.Debugf("Using image pull secret %s/%s", namespace, name)
+
+		imagePullSecrets = append(imagePullSecrets, apiv1.LocalObjectReference{Name: name})
+	}
+
+	return imagePullSecrets, nil
+}
+
+// getChaincodeObjectSelector returns a label selector which matches the
+// Kubernetes objects the k8s builder creates for chaincode.
+func getChaincodeObjectSelector() (labels.Selector, error) {
+	managedBy, err := labels.NewRequirement(managedByLabel, selection.Equals, []string{fabricBuilderK8s})
+	if err != nil {
+		return nil, fmt.Errorf("error creating chaincode label selector: %w", err)
+	}
+
+	packageLabel, err := labels.NewRequirement(packageLabelLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating chaincode label selector: %w", err)
+	}
+
+	packageHash, err := labels.NewRequirement(packageHashLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating chaincode label selector: %w", err)
+	}
+
+	return labels.NewSelector().Add(*managedBy, *packageLabel, *packageHash), nil
+}
+
+// getChaincodeObjectKey identifies the peer and chaincode package a Kubernetes
+// object was created for.
+func getChaincodeObjectKey(objectMeta *metav1.ObjectMeta) string {
+	return objectMeta.Labels[packageLabelLabel] + "/" +
+		objectMeta.Labels[packageHashLabel] + "/" +
+		objectMeta.Annotations[peerIDAnnotation]
+}
+
+// getLastUpdated returns the most recent time a Kubernetes object was created
+// or updated by any field manager.
+func getLastUpdated(objectMeta *metav1.ObjectMeta) time.Time {
+	lastUpdated := objectMeta.CreationTimestamp.Time
+
+	for _, managedFields := range objectMeta.ManagedFields {
+		if managedFields.Time != nil && managedFields.Time.After(lastUpdated) {
+			lastUpdated = managedFields.Time.Time
+		}
+	}
+
+	return lastUpdated
+}
@@ -0,0 +1,234 @@
+package util_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Secrets", func() {
+	var (
+		ctx    context.Context
+		logger *log.CmdLogger
+	)
+
+	chaincodeObjectMeta := func(name, packageLabel string, created time.Time) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "chaincode",
+			CreationTimestamp: metav1.NewTime(created),
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "fabric-builder-k8s",
+				"fabric-builder-k8s-cclabel":   packageLabel,
+				"fabric-builder-k8s-cchash":    "Z7SVTNKG2XGQ2GWDP3CPK2NZ2IJ5IMUJLSDHSJP2ZWZS5QDCBCBA",
+			},
+			Annotations: map[string]string{
+				"fabric-builder-k8s-peerid": "CongaOrgPeer0",
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = log.NewCmdContext(context.Background(), false)
+		logger = log.New(ctx)
+	})
+
+	Describe("SetChaincodeSecretOwner", func() {
+		It("should add an owner reference for the chaincode job to the chaincode secret", func() {
+			secret := &apiv1.Secret{ObjectMeta: chaincodeObjectMeta("hlfcc-fabcar-s6pwkq6bepi2e", "fabcar", time.Now())}
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:      "hlfcc-fabcar-s6pwkq6bepi2e-abcde",
+				Namespace: "chaincode",
+				UID:       types.UID("a5b6c7d8-1234-5678-9abc-def012345678"),
+			}}
+			clientset := fake.NewClientset(secret, job)
+			secretsClient := clientset.CoreV1().Secrets("chaincode")
+
+			Expect(util.SetChaincodeSecretOwner(ctx, logger, secretsClient, secret.Name, job)).To(Succeed())
+
+			result, err := secretsClient.Get(ctx, secret.Name, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+				APIVersion: "batch/v1",
+				Kind:       "Job",
+				Name:       "hlfcc-fabcar-s6pwkq6bepi2e-abcde",
+				UID:        types.UID("a5b6c7d8-1234-5678-9abc-def012345678"),
+			}))
+		})
+
+		It("should replace the existing owner references of the chaincode secret", func() {
+			secret := &apiv1.Secret{ObjectMeta: chaincodeObjectMeta("hlfcc-fabcar-s6pwkq6bepi2e", "fabcar", time.Now())}
+			firstJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:      "hlfcc-fabcar-s6pwkq6bepi2e-abcde",
+				Namespace: "chaincode",
+				UID:       types.UID("a5b6c7d8-1234-5678-9abc-def012345678"),
+			}}
+			secondJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:      "hlfcc-fabcar-s6pwkq6bepi2e-fghij",
+				Namespace: "chaincode",
+				UID:       types.UID("b6c7d8e9-2345-6789-abcd-ef0123456789"),
+			}}
+			clientset := fake.NewClientset(secret, firstJob, secondJob)
+			secretsClient := clientset.CoreV1().Secrets("chaincode")
+
+			Expect(util.SetChaincodeSecretOwner(ctx, logger, secretsClient, secret.Name, firstJob)).To(Succeed())
+			Expect(util.SetChaincodeSecretOwner(ctx, logger, secretsClient, secret.Name, secondJob)).To(Succeed())
+
+			result, err := secretsClient.Get(ctx, secret.Name, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.OwnerReferences).To(ConsistOf(
+				HaveField("UID", types.UID("b6c7d8e9-2345-6789-abcd-ef0123456789")),
+			))
+		})
+
+		It("should return an error if the chaincode secret does not exist", func() {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "hlfcc-fabcar-s6pwkq6bepi2e-abcde", Namespace: "chaincode"}}
+			clientset := fake.NewClientset(job)
+
+			err := util.SetChaincodeSecretOwner(ctx, logger, clientset.CoreV1().Secrets("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", job)
+			Expect(err).To(MatchError(ContainSubstring("error setting owner of chaincode secret chaincode/hlfcc-fabcar-s6pwkq6bepi2e")))
+		})
+	})
+
+	Describe("RemoveChaincodeSecretOwners", func() {
+		It("should remove the owner references from the chaincode secret", func() {
+			secret := &apiv1.Secret{ObjectMeta: chaincodeObjectMeta("hlfcc-fabcar-s6pwkq6bepi2e", "fabcar", time.Now())}
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:      "hlfcc-fabcar-s6pwkq6bepi2e-abcde",
+				Namespace: "chaincode",
+				UID:       types.UID("a5b6c7d8-1234-5678-9abc-def012345678"),
+			}}
+			clientset := fake.NewClientset(secret, job)
+			secretsClient := clientset.CoreV1().Secrets("chaincode")
+
+			Expect(util.SetChaincodeSecretOwner(ctx, logger, secretsClient, secret.Name, job)).To(Succeed())
+			Expect(util.RemoveChaincodeSecretOwners(ctx, logger, secretsClient, secret.Name, "chaincode")).To(Succeed())
+
+			result, err := secretsClient.Get(ctx, secret.Name, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.OwnerReferences).To(BeEmpty())
+		})
+
+		It("should not return an error if the chaincode secret does not exist", func() {
+			clientset := fake.NewClientset()
+
+			err := util.RemoveChaincodeSecretOwners(ctx, logger, clientset.CoreV1().Secrets("chaincode"), "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("DeleteOrphanedChaincodeSecrets", func() {
+		It("should only delete old chaincode secrets which are not used by a running chaincode job", func() {
+			old := time.Now().Add(-time.Hour)
+
+			runningJob := &batchv1.Job{ObjectMeta: chaincodeObjectMeta("hlfcc-running-abcde", "running", old)}
+			finishedJob := &batchv1.Job{
+				ObjectMeta: chaincodeObjectMeta("hlfcc-finished-abcde", "finished", old),
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: apiv1.ConditionTrue}},
+				},
+			}
+			otherSecret := &apiv1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "chaincode", CreationTimestamp: metav1.NewTime(old)}}
+
+			clientset := fake.NewClientset(
+				runningJob,
+				finishedJob,
+				otherSecret,
+				&apiv1.Secret{ObjectMeta: chaincodeObjectMeta("hlfcc-running", "running", old)},
+				&apiv1.Secret{ObjectMeta: chaincodeObjectMeta("hlfcc-finished", "finished", old)},
+				&apiv1.Secret{ObjectMeta: chaincodeObjectMeta("hlfcc-deleted", "deleted", old)},
+				&apiv1.Secret{ObjectMeta: chaincodeObjectMeta("hlfcc-new", "new", time.Now())},
+			)
+			secretsClient := clientset.CoreV1().Secrets("chaincode")
+
+			err := util.DeleteOrphanedChaincodeSecrets(ctx, logger, secretsClient, clientset.BatchV1().Jobs("chaincode"), "chaincode")
+			Expect(err).NotTo(HaveOccurred())
+
+			secrets, err := secretsClient.List(ctx, metav1.ListOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			secretNames := make([]string, 0, len(secrets.Items))
+			for _, secret := range secrets.Items {
+				secretNames = append(secretNames, secret.Name)
+			}
+
+			Expect(secretNames).To(ConsistOf("other", "hlfcc-running", "hlfcc-new"))
+		})
+	})
+
+	Describe("GetImagePullSecrets", func() {
+		var imageData *util.ImageJSON
+
+		pullSecret := func(name string, secretType apiv1.SecretType) *apiv1.Secret {
+			return &apiv1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "chaincode"},
+				Type:       secretType,
+			}
+		}
+
+		BeforeEach(func() {
+			imageData = &util.ImageJSON{
+				Name:   "nginx",
+				Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+			}
+		})
+
+		It("should return no image pull secrets by default", func() {
+			secretsClient := fake.NewClientset().CoreV1().Secrets("chaincode")
+
+			imagePullSecrets, err := util.GetImagePullSecrets(ctx, logger, secretsClient, "chaincode", nil, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePullSecrets).To(BeEmpty())
+		})
+
+		It("should return the builder and chaincode package image pull secrets", func() {
+			imageData.ImagePullSecret = "registry-c"
+			secretsClient := fake.NewClientset(
+				pullSecret("registry-a", apiv1.SecretTypeDockerConfigJson),
+				pullSecret("registry-b", apiv1.SecretTypeDockercfg),
+				pullSecret("registry-c", apiv1.SecretTypeDockerConfigJson),
+			).CoreV1().Secrets("chaincode")
+
+			imagePullSecrets, err := util.GetImagePullSecrets(ctx, logger, secretsClient, "chaincode", []string{"registry-a", "registry-b"}, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePullSecrets).To(Equal([]apiv1.LocalObjectReference{
+				{Name: "registry-a"},
+				{Name: "registry-b"},
+				{Name: "registry-c"},
+			}))
+		})
+
+		It("should not duplicate an image pull secret in both the builder settings and the image.json file", func() {
+			imageData.ImagePullSecret = "registry-a"
+			secretsClient := fake.NewClientset(pullSecret("registry-a", apiv1.SecretTypeDockerConfigJson)).CoreV1().Secrets("chaincode")
+
+			imagePullSecrets, err := util.GetImagePullSecrets(ctx, logger, secretsClient, "chaincode", []string{"registry-a"}, imageData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePullSecrets).To(Equal([]apiv1.LocalObjectReference{{Name: "registry-a"}}))
+		})
+
+		It("should return an error if an image pull secret does not exist", func() {
+			imageData.ImagePullSecret = "registry-b"
+			secretsClient := fake.NewClientset(pullSecret("registry-a", apiv1.SecretTypeDockerConfigJson)).CoreV1().Secrets("chaincode")
+
+			_, err := util.GetImagePullSecrets(ctx, logger, secretsClient, "chaincode", []string{"registry-a"}, imageData)
+			Expect(err).To(MatchError("image pull secret chaincode/registry-b does not exist"))
+		})
+
+		It("should return an error if an image pull secret is not a registry credentials secret", func() {
+			secretsClient := fake.NewClientset(pullSecret("registry-a", apiv1.SecretTypeOpaque)).CoreV1().Secrets("chaincode")
+
+			_, err := util.GetImagePullSecrets(ctx, logger, secretsClient, "chaincode", []string{"registry-a"}, imageData)
+			Expect(err).To(MatchError("image pull secret chaincode/registry-a must be of type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg, not Opaque"))
+		})
+	})
+})
@@ -0,0 +1,312 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net"
+	"strconv"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	typedAppsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// Run modes.
+	RunModeJob     string = "job"
+	RunModeService string = "service"
+
+	DefaultRunMode         string = RunModeJob
+	DefaultServiceReplicas string = "1"
+
+	// ChaincodeServerPort is the port chaincode listens on when run as a service.
+	ChaincodeServerPort int32 = 9999
+
+	chaincodeServerPortName = "chaincode"
+	instanceLabel           = "app.kubernetes.io/instance"
+)
+
+func getServiceLabels(objectName string, chaincodeData *ChaincodeJSON) (map[string]string, error) {
+	labels, err := getLabels(chaincodeData)
+	if err != nil {
+		return nil, err
+	}
+
+	labels[instanceLabel] = objectName
+
+	return labels, nil
+}
+
+func getChaincodeDeploymentSpec(
+	imageData *ImageJSON,
+	namespace, serviceAccount, objectName, peerID string,
+	chaincodeData *ChaincodeJSON,
+	resources apiv1.ResourceRequirements,
+	replicas int32,
+) (*appsv1.Deployment, error) {
+	labels, err := getServiceLabels(objectName, chaincodeData)
+	if err != nil {
+		return nil, fmt.Errorf("error getting chaincode deployment labels for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	annotations := getAnnotations(peerID, chaincodeData)
+
+	chaincodeServerAddress := net.JoinHostPort("0.0.0.0", strconv.Itoa(int(ChaincodeServerPort)))
+
+	podTemplate := getChaincodePodTemplate(
+		imageData,
+		serviceAccount,
+		maps.Clone(labels),
+		maps.Clone(annotations),
+		resources,
+		[]apiv1.EnvVar{
+			{
+				Name:  "CHAINCODE_ID",
+				Value: chaincodeData.ChaincodeID,
+			},
+			{
+				Name:  "CORE_CHAINCODE_ID_NAME",
+				Value: chaincodeData.ChaincodeID,
+			},
+			{
+				Name:  "CHAINCODE_SERVER_ADDRESS",
+				Value: chaincodeServerAddress,
+			},
+		},
+	)
+	podTemplate.Spec.Containers[0].Ports = []apiv1.ContainerPort{
+		{
+			Name:          chaincodeServerPortName,
+			ContainerPort: ChaincodeServerPort,
+			Protocol:      apiv1.ProtocolTCP,
+		},
+	}
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        objectName,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					instanceLabel: objectName,
+				},
+			},
+			Template: podTemplate,
+		},
+	}, nil
+}
+
+func getChaincodeServiceSpec(
+	namespace, objectName, peerID string,
+	chaincodeData *ChaincodeJSON,
+) (*apiv1.Service, error) {
+	labels, err := getServiceLabels(objectName, chaincodeData)
+	if err != nil {
+		return nil, fmt.Errorf("error getting chaincode service labels for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	annotations := getAnnotations(peerID, chaincodeData)
+
+	return &apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        objectName,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: apiv1.ServiceSpec{
+			Type: apiv1.ServiceTypeClusterIP,
+			Selector: map[string]string{
+				instanceLabel: objectName,
+			},
+			Ports: []apiv1.ServicePort{
+				{
+					Name:       chaincodeServerPortName,
+					Port:       ChaincodeServerPort,
+					TargetPort: intstr.FromString(chaincodeServerPortName),
+					Protocol:   apiv1.ProtocolTCP,
+				},
+			},
+		},
+	}, nil
+}
+
+// GetChaincodeServerAddress returns the address of the chaincode service with
+// the provided name and namespace.
+func GetChaincodeServerAddress(objectName, namespace string) string {
+	return net.JoinHostPort(objectName+"."+namespace+".svc", strconv.Itoa(int(ChaincodeServerPort)))
+}
+
+// ApplyChaincodeDeployment creates or updates a deployment to run chaincode as
+// a service.
+func ApplyChaincodeDeployment(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	deploymentsClient typedAppsv1.DeploymentInterface,
+	objectName, namespace, serviceAccount, nodeRole, peerID string,
+	chaincodeData *ChaincodeJSON,
+	imageData *ImageJSON,
+	resources apiv1.ResourceRequirements,
+	podOptions PodOptions,
+	replicas int32,
+	jobTemplate []byte,
+) (*appsv1.Deployment, error) {
+	deploymentDefinition, err := getChaincodeDeploymentSpec(
+		imageData,
+		namespace,
+		serviceAccount,
+		objectName,
+		peerID,
+		chaincodeData,
+		resources,
+		replicas,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting chaincode deployment definition for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	setPodOptions(&deploymentDefinition.Spec.Template, podOptions)
+
+	if nodeRole != "" {
+		logger.Debugf(
+			"Adding node affinity and toleration to deployment definition for chaincode ID %s: %s",
+			chaincodeData.ChaincodeID,
+			nodeRole,
+		)
+
+		setNodeRole(&deploymentDefinition.Spec.Template.Spec, nodeRole)
+	}
+
+	if len(jobTemplate) > 0 {
+		logger.Debugf("Applying job template to deployment definition for chaincode ID %s", chaincodeData.ChaincodeID)
+
+		deploymentDefinition.Spec.Template, err = applyChaincodePodTemplate(deploymentDefinition.Spec.Template, jobTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("error applying job template for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+		}
+	}
+
+	traceObject(logger, "Chaincode deployment definition for chaincode ID "+chaincodeData.ChaincodeID, deploymentDefinition)
+
+	data, err := json.Marshal(deploymentDefinition)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding chaincode deployment definition for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	deployment, err := deploymentsClient.Patch(
+		ctx,
+		objectName,
+		types.ApplyPatchType,
+		data,
+		metav1.PatchOptions{FieldManager: fabricBuilderK8s, Force: ptr.To(true)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error applying chaincode deployment %s/%s for chaincode ID %s: %w",
+			namespace,
+			objectName,
+			chaincodeData.ChaincodeID,
+			err,
+		)
+	}
+
+	logger.Debugf(
+		"Applied chaincode deployment for chaincode ID %s: %s/%s",
+		chaincodeData.ChaincodeID,
+		deployment.Namespace,
+		deployment.Name,
+	)
+
+	return deployment, nil
+}
+
+// DeleteChaincodeDeployment deletes a chaincode deployment, for example if the
+// chaincode service could not be applied.
+func DeleteChaincodeDeployment(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	deploymentsClient typedAppsv1.DeploymentInterface,
+	deployment *appsv1.Deployment,
+) error {
+	logger.Debugf("Deleting chaincode deployment %s/%s", deployment.Namespace, deployment.Name)
+
+	err := deploymentsClient.Delete(ctx, deployment.Name, metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting chaincode deployment %s/%s: %w", deployment.Namespace, deployment.Name, err)
+	}
+
+	return nil
+}
+
+// ApplyChaincodeService creates or updates a ClusterIP service for chaincode
+// which is run as a service.
+func ApplyChaincodeService(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	servicesClient v1.ServiceInterface,
+	objectName, namespace, peerID string,
+	chaincodeData *ChaincodeJSON,
+) (*apiv1.Service, error) {
+	serviceDefinition, err := getChaincodeServiceSpec(namespace, objectName, peerID, chaincodeData)
+	if err != nil {
+		return nil, fmt.Errorf("error getting chaincode service definition for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	traceObject(logger, "Chaincode service definition for chaincode ID "+chaincodeData.ChaincodeID, serviceDefinition)
+
+	data, err := json.Marshal(serviceDefinition)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding chaincode service definition for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	service, err := servicesClient.Patch(
+		ctx,
+		objectName,
+		types.ApplyPatchType,
+		data,
+		metav1.PatchOptions{FieldManager: fabricBuilderK8s, Force: ptr.To(true)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error applying chaincode service %s/%s for chaincode ID %s: %w",
+			namespace,
+			objectName,
+			chaincodeData.ChaincodeID,
+			err,
+		)
+	}
+
+	logger.Debugf(
+		"Applied chaincode service for chaincode ID %s: %s/%s",
+		chaincodeData.ChaincodeID,
+		service.Namespace,
+		service.Name,
+	)
+
+	return service, nil
+}
@@ -0,0 +1,148 @@
+package util_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		ctx           context.Context
+		logger        *log.CmdLogger
+		clientset     *fake.Clientset
+		chaincodeData *util.ChaincodeJSON
+		imageData     *util.ImageJSON
+	)
+
+	BeforeEach(func() {
+		ctx = log.NewCmdContext(context.Background(), false)
+		logger = log.New(ctx)
+		clientset = fake.NewClientset()
+		chaincodeData = &util.ChaincodeJSON{
+			ChaincodeID: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b",
+		}
+		imageData = &util.ImageJSON{
+			Name:   "nginx",
+			Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+		}
+	})
+
+	Describe("ApplyChaincodeDeployment", func() {
+		It("should apply a chaincode server deployment", func() {
+			resources := apiv1.ResourceRequirements{
+				Limits: apiv1.ResourceList{apiv1.ResourceMemory: resource.MustParse("256Mi")},
+			}
+
+			deployment, err := util.ApplyChaincodeDeployment(ctx, logger, clientset.AppsV1().Deployments("chaincode"), "hlfcc-fabcar-k5ljhqxqnkhue", "chaincode", "chaincode-sa", "chaincode", "CongaOrgPeer0", chaincodeData, imageData, resources, util.PodOptions{}, 3, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deployment.Name).To(Equal("hlfcc-fabcar-k5ljhqxqnkhue"))
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(3)))
+			Expect(deployment.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app.kubernetes.io/instance": "hlfcc-fabcar-k5ljhqxqnkhue"}))
+			Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("app.kubernetes.io/instance", "hlfcc-fabcar-k5ljhqxqnkhue"))
+			Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("fabric-builder-k8s-cclabel", "fabcar"))
+			Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("chaincode-sa"))
+			Expect(deployment.Spec.Template.Spec.Tolerations).To(HaveLen(1))
+
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Image).To(Equal("nginx@sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"))
+			Expect(container.Resources).To(Equal(resources))
+			Expect(container.Env).To(ContainElements(
+				apiv1.EnvVar{Name: "CHAINCODE_ID", Value: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b"},
+				apiv1.EnvVar{Name: "CHAINCODE_SERVER_ADDRESS", Value: "0.0.0.0:9999"},
+			))
+			Expect(container.SecurityContext).NotTo(BeNil())
+			Expect(*container.SecurityContext.RunAsNonRoot).To(BeTrue())
+			Expect(*container.SecurityContext.ReadOnlyRootFilesystem).To(BeTrue())
+			Expect(container.VolumeMounts).To(ContainElement(apiv1.VolumeMount{Name: "tmp", MountPath: "/tmp"}))
+		})
+
+		It("should update an existing chaincode server deployment", func() {
+			deploymentsClient := clientset.AppsV1().Deployments("chaincode")
+
+			_, err := util.ApplyChaincodeDeployment(ctx, logger, deploymentsClient, "hlfcc-fabcar-k5ljhqxqnkhue", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, 1, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment, err := util.ApplyChaincodeDeployment(ctx, logger, deploymentsClient, "hlfcc-fabcar-k5ljhqxqnkhue", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, 2, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+		})
+	})
+
+	Describe("ApplyChaincodeDeployment with a job template", func() {
+		It("should apply the job template pod settings to the deployment", func() {
+			jobTemplate := []byte(`{"spec":{"activeDeadlineSeconds":60,"template":{"spec":{"priorityClassName":"chaincode"}}}}`)
+
+			deployment, err := util.ApplyChaincodeDeployment(ctx, logger, clientset.AppsV1().Deployments("chaincode"), "hlfcc-fabcar-k5ljhqxqnkhue", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, 1, jobTemplate)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deployment.Spec.Template.Spec.PriorityClassName).To(Equal("chaincode"))
+			Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("app.kubernetes.io/instance", "hlfcc-fabcar-k5ljhqxqnkhue"))
+		})
+
+		It("should return an error if the job template overrides the chaincode image", func() {
+			jobTemplate := []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"chaincode","image":"busybox"}]}}}}`)
+
+			_, err := util.ApplyChaincodeDeployment(ctx, logger, clientset.AppsV1().Deployments("chaincode"), "hlfcc-fabcar-k5ljhqxqnkhue", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, 1, jobTemplate)
+			Expect(err).To(MatchError(ContainSubstring("job template must not override fields managed by the k8s builder: chaincode container image")))
+		})
+	})
+
+	Describe("DeleteChaincodeDeployment", func() {
+		It("should delete the chaincode deployment", func() {
+			deploymentsClient := clientset.AppsV1().Deployments("chaincode")
+
+			deployment, err := util.ApplyChaincodeDeployment(ctx, logger, deploymentsClient, "hlfcc-fabcar-k5ljhqxqnkhue", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, 1, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(util.DeleteChaincodeDeployment(ctx, logger, deploymentsClient, deployment)).To(Succeed())
+
+			_, err = deploymentsClient.Get(ctx, "hlfcc-fabcar-k5ljhqxqnkhue", metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("ApplyChaincodeService", func() {
+		It("should apply a ClusterIP service for the chaincode server", func() {
+			service, err := util.ApplyChaincodeService(ctx, logger, clientset.CoreV1().Services("chaincode"), "hlfcc-fabcar-k5ljhqxqnkhue", "chaincode", "CongaOrgPeer0", chaincodeData)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(service.Spec.Type).To(Equal(apiv1.ServiceTypeClusterIP))
+			Expect(service.Spec.Selector).To(Equal(map[string]string{"app.kubernetes.io/instance": "hlfcc-fabcar-k5ljhqxqnkhue"}))
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].Port).To(Equal(util.ChaincodeServerPort))
+		})
+	})
+
+	Describe("GetChaincodeServerAddress", func() {
+		It("should return the service address", func() {
+			Expect(util.GetChaincodeServerAddress("hlfcc-fabcar-k5ljhqxqnkhue", "chaincode")).To(Equal("hlfcc-fabcar-k5ljhqxqnkhue.chaincode.svc:9999"))
+		})
+	})
+
+	Describe("WriteConnectionJSON", func() {
+		It("should write a connection.json file to the chaincode server directory", func() {
+			tempDir := GinkgoT().TempDir()
+
+			err := util.WriteConnectionJSON(logger, tempDir, "hlfcc-fabcar-k5ljhqxqnkhue.chaincode.svc:9999")
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(filepath.Join(tempDir, "chaincode", "server", "connection.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var connectionData util.ConnectionJSON
+			Expect(json.Unmarshal(contents, &connectionData)).To(Succeed())
+			Expect(connectionData.Address).To(Equal("hlfcc-fabcar-k5ljhqxqnkhue.chaincode.svc:9999"))
+			Expect(connectionData.TLSRequired).To(BeFalse())
+		})
+	})
+})
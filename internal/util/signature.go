@@ -0,0 +1,385 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	registryv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CosignSignatureAnnotation is the signature layer annotation containing
+	// the base64 encoded signature of the layer payload.
+	CosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	cosignSignatureTagSuffix = ".sig"
+	maximumSignaturePayload  = 1 << 20
+)
+
+var errImageNotSigned = errors.New("chaincode image has not been signed")
+
+// SignatureVerificationError is returned when a chaincode image does not have
+// a valid signature from a key trusted by the signature policy.
+type SignatureVerificationError struct {
+	Image  string
+	Policy string
+	Reason string
+}
+
+func (e *SignatureVerificationError) Error() string {
+	if e.Policy == "" {
+		return fmt.Sprintf("signature verification failed for chaincode image %s: %s", e.Image, e.Reason)
+	}
+
+	return fmt.Sprintf("signature verification failed for chaincode image %s using signature policy %s: %s", e.Image, e.Policy, e.Reason)
+}
+
+// SignaturePolicy defines which public keys are trusted to sign chaincode
+// images. The first rule which matches the chaincode image repository and
+// package label is used, and images which do not match any rule are rejected.
+type SignaturePolicy struct {
+	Policies []SignaturePolicyRule `json:"policies"`
+}
+
+// SignaturePolicyRule trusts the listed public keys to sign chaincode images
+// in repositories matching the image patterns, for chaincode packages with
+// labels matching the label patterns.
+type SignaturePolicyRule struct {
+	// Name identifies the rule in verification errors.
+	Name string `json:"name"`
+
+	// Images are repository patterns, e.g. ghcr.io/hyperledger-labs/*, using
+	// path.Match syntax.
+	Images []string `json:"images"`
+
+	// Labels are chaincode package label patterns, using path.Match syntax.
+	// Defaults to all labels.
+	Labels []string `json:"labels,omitempty"`
+
+	// Keys are paths to PEM encoded public keys, relative to the policy file.
+	Keys []string `json:"keys"`
+
+	publicKeys []crypto.PublicKey
+}
+
+// simpleSigningPayload is the part of a cosign signature payload which
+// identifies the signed image.
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// ReadSignaturePolicy reads a YAML or JSON signature policy file, and loads
+// the trusted public keys.
+func ReadSignaturePolicy(logger *log.CmdLogger, policyPath string) (*SignaturePolicy, error) {
+	logger.Debugf("Reading %s...", policyPath)
+
+	policyContents, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", policyPath, err)
+	}
+
+	var policy SignaturePolicy
+	if err := yaml.UnmarshalStrict(policyContents, &policy); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", policyPath, err)
+	}
+
+	if len(policy.Policies) == 0 {
+		return nil, fmt.Errorf("%s file must contain at least one policy", policyPath)
+	}
+
+	for i := range policy.Policies {
+		rule := &policy.Policies[i]
+
+		if err := rule.load(filepath.Dir(policyPath)); err != nil {
+			return nil, fmt.Errorf("%s file contains invalid policy %d: %w", policyPath, i, err)
+		}
+
+		logger.Debugf("Signature policy %s: images %v, labels %v, %d keys", rule.Name, rule.Images, rule.Labels, len(rule.publicKeys))
+	}
+
+	return &policy, nil
+}
+
+// load validates the rule and reads the public keys.
+func (r *SignaturePolicyRule) load(policyDir string) error {
+	if r.Name == "" {
+		return errors.New("policy must have a 'name'")
+	}
+
+	if len(r.Images) == 0 || len(r.Keys) == 0 {
+		return fmt.Errorf("policy %s must contain 'images' and 'keys'", r.Name)
+	}
+
+	for _, pattern := range slices.Concat(r.Images, r.Labels) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("policy %s contains invalid pattern %s: %w", r.Name, pattern, err)
+		}
+	}
+
+	for _, keyPath := range r.Keys {
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(policyDir, keyPath)
+		}
+
+		publicKey, err := readPublicKey(keyPath)
+		if err != nil {
+			return fmt.Errorf("policy %s: %w", r.Name, err)
+		}
+
+		r.publicKeys = append(r.publicKeys, publicKey)
+	}
+
+	return nil
+}
+
+// matches returns true if the rule applies to the chaincode image repository
+// and package label.
+func (r *SignaturePolicyRule) matches(repository, label string) bool {
+	return matchesAny(r.Images, repository) && (len(r.Labels) == 0 || matchesAny(r.Labels, label))
+}
+
+// VerifyImageSignature checks that the chaincode image has a cosign signature
+// from one of the keys trusted by the first signature policy rule which
+// matches the image repository and chaincode package label. Registry
+// credentials are loaded from the Docker config file, if there is one.
+func VerifyImageSignature(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	policy *SignaturePolicy,
+	imageData *ImageJSON,
+	label string,
+) error {
+	ref, err := GetImageReference(imageData)
+	if err != nil {
+		return err
+	}
+
+	_, repository := getImageRepository(ref.Context())
+
+	var rule *SignaturePolicyRule
+
+	for i := range policy.Policies {
+		if policy.Policies[i].matches(repository, label) {
+			rule = &policy.Policies[i]
+
+			break
+		}
+	}
+
+	if rule == nil {
+		return &SignatureVerificationError{
+			Image:  ref.String(),
+			Reason: fmt.Sprintf("no signature policy matches repository %s and chaincode label %s", repository, label),
+		}
+	}
+
+	logger.Debugf("Verifying chaincode image %s signature using signature policy %s", ref.String(), rule.Name)
+
+	signatures, err := getImageSignatures(ctx, ref)
+	if errors.Is(err, errImageNotSigned) || (err == nil && len(signatures) == 0) {
+		return &SignatureVerificationError{Image: ref.String(), Policy: rule.Name, Reason: "no signatures found"}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, signature := range signatures {
+		if signature.verify(rule.publicKeys, ref) {
+			logger.Printf("Verified chaincode image %s signature using signature policy %s", ref.String(), rule.Name)
+
+			return nil
+		}
+	}
+
+	return &SignatureVerificationError{
+		Image:  ref.String(),
+		Policy: rule.Name,
+		Reason: fmt.Sprintf("none of the %d signatures were signed by a trusted key for repository %s and digest %s", len(signatures), repository, ref.DigestStr()),
+	}
+}
+
+// imageSignature is a signature payload and the signature of the payload.
+type imageSignature struct {
+	payload   []byte
+	signature []byte
+}
+
+// verify returns true if the signature was created by one of the public keys,
+// and the payload identifies the image repository and digest.
+func (s imageSignature) verify(publicKeys []crypto.PublicKey, ref name.Digest) bool {
+	for _, publicKey := range publicKeys {
+		if !verifySignature(publicKey, s.payload, s.signature) {
+			continue
+		}
+
+		var payload simpleSigningPayload
+		if err := json.Unmarshal(s.payload, &payload); err != nil {
+			return false
+		}
+
+		return payload.Critical.Image.DockerManifestDigest == ref.DigestStr() &&
+			isSameRepository(payload.Critical.Identity.DockerReference, ref.Context())
+	}
+
+	return false
+}
+
+// isSameRepository returns true if the signed docker reference is in the
+// chaincode image repository. Signed references may include a tag or digest,
+// which are ignored since the manifest digest is checked separately.
+func isSameRepository(dockerReference string, repository name.Repository) bool {
+	signedRef, err := name.ParseReference(dockerReference)
+	if err != nil {
+		return false
+	}
+
+	return signedRef.Context().Name() == repository.Name()
+}
+
+// getImageSignatures returns the cosign signatures stored in the registry for
+// the chaincode image.
+func getImageSignatures(ctx context.Context, ref name.Digest) ([]imageSignature, error) {
+	signatureTag := ref.Context().Tag(strings.Replace(ref.DigestStr(), ":", "-", 1) + cosignSignatureTagSuffix)
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+
+	spanCtx, span := tracing.Start(ctx, "registry.signatures.get", tracing.ImageKey.String(ref.String()))
+	signatureImage, err := remote.Image(signatureTag, remote.WithContext(spanCtx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	tracing.End(span, err)
+
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return nil, errImageNotSigned
+		}
+
+		return nil, fmt.Errorf("unable to get signatures for chaincode image %s: %w", ref.String(), err)
+	}
+
+	manifest, err := signatureImage.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get signatures for chaincode image %s: %w", ref.String(), err)
+	}
+
+	signatures := make([]imageSignature, 0, len(manifest.Layers))
+
+	for _, layer := range manifest.Layers {
+		signature, err := base64.StdEncoding.DecodeString(layer.Annotations[CosignSignatureAnnotation])
+		if err != nil || len(signature) == 0 {
+			continue
+		}
+
+		payload, err := readSignaturePayload(signatureImage, layer.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get signatures for chaincode image %s: %w", ref.String(), err)
+		}
+
+		signatures = append(signatures, imageSignature{payload: payload, signature: signature})
+	}
+
+	return signatures, nil
+}
+
+func readSignaturePayload(signatureImage registryv1.Image, digest registryv1.Hash) ([]byte, error) {
+	layer, err := signatureImage.LayerByDigest(digest)
+	if err != nil {
+		return nil, fmt.Errorf("error getting signature payload %s: %w", digest, err)
+	}
+
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, fmt.Errorf("error reading signature payload %s: %w", digest, err)
+	}
+	defer reader.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(reader, maximumSignaturePayload))
+	if err != nil {
+		return nil, fmt.Errorf("error reading signature payload %s: %w", digest, err)
+	}
+
+	return payload, nil
+}
+
+func readPublicKey(keyPath string) (crypto.PublicKey, error) {
+	keyContents, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read public key %s: %w", keyPath, err)
+	}
+
+	block, _ := pem.Decode(keyContents)
+	if block == nil {
+		return nil, fmt.Errorf("public key %s must be PEM encoded", keyPath)
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse public key %s: %w", keyPath, err)
+	}
+
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("public key %s must be an ECDSA, RSA, or Ed25519 key", keyPath)
+	}
+}
+
+func verifySignature(publicKey crypto.PublicKey, payload, signature []byte) bool {
+	digest := sha256.Sum256(payload)
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	default:
+		return false
+	}
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,236 @@
+package util_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signature", func() {
+	var (
+		ctx        context.Context
+		logger     *log.CmdLogger
+		policyDir  string
+		imageName  string
+		imageData  *util.ImageJSON
+		trustedKey *ecdsa.PrivateKey
+	)
+
+	generateKey := func(keyFile string) *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		Expect(os.WriteFile(filepath.Join(policyDir, keyFile), keyPEM, 0o600)).To(Succeed())
+
+		return key
+	}
+
+	signImage := func(key *ecdsa.PrivateKey, signedReference, signedDigest string) {
+		payload := fmt.Appendf(nil,
+			`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`,
+			signedReference,
+			signedDigest,
+		)
+		payloadDigest := sha256.Sum256(payload)
+
+		signature, err := ecdsa.SignASN1(rand.Reader, key, payloadDigest[:])
+		Expect(err).NotTo(HaveOccurred())
+
+		signatureImage, err := mutate.Append(empty.Image, mutate.Addendum{
+			Layer:       static.NewLayer(payload, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")),
+			Annotations: map[string]string{util.CosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		signatureRef, err := name.ParseReference(imageName + ":" + strings.Replace(imageData.Digest, ":", "-", 1) + ".sig")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(signatureRef, signatureImage)).To(Succeed())
+	}
+
+	writePolicy := func(policy string) string {
+		policyPath := filepath.Join(policyDir, "policy.yaml")
+		Expect(os.WriteFile(policyPath, []byte(policy), 0o600)).To(Succeed())
+
+		return policyPath
+	}
+
+	readPolicy := func(policy string) *util.SignaturePolicy {
+		signaturePolicy, err := util.ReadSignaturePolicy(logger, writePolicy(policy))
+		Expect(err).NotTo(HaveOccurred())
+
+		return signaturePolicy
+	}
+
+	BeforeEach(func() {
+		ctx = log.NewCmdContext(context.Background(), false)
+		logger = log.New(ctx)
+		policyDir = GinkgoT().TempDir()
+
+		server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+		DeferCleanup(server.Close)
+
+		imageName = strings.TrimPrefix(server.URL, "http://") + "/hyperledger/asset-transfer-basic"
+
+		image, err := random.Image(1024, 1)
+		Expect(err).NotTo(HaveOccurred())
+
+		ref, err := name.ParseReference(imageName + ":latest")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remote.Write(ref, image)).To(Succeed())
+
+		digest, err := image.Digest()
+		Expect(err).NotTo(HaveOccurred())
+
+		imageData = &util.ImageJSON{Name: imageName, Digest: digest.String()}
+		trustedKey = generateKey("release.pub")
+	})
+
+	Describe("ReadSignaturePolicy", func() {
+		It("should return an error if the policy file does not exist", func() {
+			_, err := util.ReadSignaturePolicy(logger, filepath.Join(policyDir, "missing.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("unable to read")))
+		})
+
+		It("should return an error if the policy file contains unknown fields", func() {
+			_, err := util.ReadSignaturePolicy(logger, writePolicy("policies:\n  - name: release\n    image: ['*']\n"))
+			Expect(err).To(MatchError(ContainSubstring("unable to parse")))
+		})
+
+		It("should return an error if the policy file does not contain any policies", func() {
+			_, err := util.ReadSignaturePolicy(logger, writePolicy("policies: []\n"))
+			Expect(err).To(MatchError(ContainSubstring("file must contain at least one policy")))
+		})
+
+		It("should return an error if a policy does not contain any keys", func() {
+			_, err := util.ReadSignaturePolicy(logger, writePolicy("policies:\n  - name: release\n    images: ['*']\n"))
+			Expect(err).To(MatchError(ContainSubstring("policy release must contain 'images' and 'keys'")))
+		})
+
+		It("should return an error if a policy contains an invalid pattern", func() {
+			_, err := util.ReadSignaturePolicy(logger, writePolicy("policies:\n  - name: release\n    images: ['[']\n    keys: [release.pub]\n"))
+			Expect(err).To(MatchError(ContainSubstring("policy release contains invalid pattern [")))
+		})
+
+		It("should return an error if a public key is not PEM encoded", func() {
+			Expect(os.WriteFile(filepath.Join(policyDir, "invalid.pub"), []byte("not a key"), 0o600)).To(Succeed())
+
+			_, err := util.ReadSignaturePolicy(logger, writePolicy("policies:\n  - name: release\n    images: ['*']\n    keys: [invalid.pub]\n"))
+			Expect(err).To(MatchError(ContainSubstring("invalid.pub must be PEM encoded")))
+		})
+	})
+
+	Describe("VerifyImageSignature", func() {
+		It("should verify a chaincode image signed by a trusted key", func() {
+			signImage(trustedKey, imageName, imageData.Digest)
+			policy := readPolicy("policies:\n  - name: release\n    images: ['*/hyperledger/*']\n    keys: [release.pub]\n")
+
+			Expect(util.VerifyImageSignature(ctx, logger, policy, imageData, "basic")).To(Succeed())
+		})
+
+		It("should use the first policy which matches the chaincode label", func() {
+			otherKey := generateKey("other.pub")
+			signImage(otherKey, imageName, imageData.Digest)
+			policy := readPolicy(
+				"policies:\n" +
+					"  - name: release\n    images: ['*/hyperledger/*']\n    labels: ['basic*']\n    keys: [release.pub]\n" +
+					"  - name: other\n    images: ['*/hyperledger/*']\n    keys: [other.pub]\n",
+			)
+
+			Expect(util.VerifyImageSignature(ctx, logger, policy, imageData, "marbles")).To(Succeed())
+
+			err := util.VerifyImageSignature(ctx, logger, policy, imageData, "basic_1.0")
+			Expect(err).To(MatchError(ContainSubstring("using signature policy release: none of the 1 signatures were signed by a trusted key")))
+		})
+
+		It("should return a signature verification error if no policy matches the chaincode image", func() {
+			signImage(trustedKey, imageName, imageData.Digest)
+			policy := readPolicy("policies:\n  - name: release\n    images: ['ghcr.io/hyperledger-labs/*']\n    keys: [release.pub]\n")
+
+			err := util.VerifyImageSignature(ctx, logger, policy, imageData, "basic")
+
+			var verificationErr *util.SignatureVerificationError
+			Expect(err).To(BeAssignableToTypeOf(verificationErr))
+			Expect(err).To(MatchError(ContainSubstring("no signature policy matches repository " + imageName + " and chaincode label basic")))
+		})
+
+		It("should return a signature verification error if the chaincode image is not signed", func() {
+			policy := readPolicy("policies:\n  - name: release\n    images: ['*/hyperledger/*']\n    keys: [release.pub]\n")
+
+			err := util.VerifyImageSignature(ctx, logger, policy, imageData, "basic")
+
+			var verificationErr *util.SignatureVerificationError
+			Expect(err).To(BeAssignableToTypeOf(verificationErr))
+			Expect(err).To(MatchError("signature verification failed for chaincode image " + imageName + "@" + imageData.Digest + " using signature policy release: no signatures found"))
+		})
+
+		It("should return a signature verification error if the chaincode image is signed by an untrusted key", func() {
+			signImage(generateKey("untrusted.pub"), imageName, imageData.Digest)
+			policy := readPolicy("policies:\n  - name: release\n    images: ['*/hyperledger/*']\n    keys: [release.pub]\n")
+
+			err := util.VerifyImageSignature(ctx, logger, policy, imageData, "basic")
+
+			var verificationErr *util.SignatureVerificationError
+			Expect(err).To(BeAssignableToTypeOf(verificationErr))
+			Expect(err).To(MatchError(ContainSubstring("none of the 1 signatures were signed by a trusted key")))
+		})
+
+		It("should return a signature verification error if the signature is for a different image", func() {
+			signImage(trustedKey, imageName, "sha256:b35962f000d26ad046d4102f22d70a1351692fc69a9ddead89dfa13aefb942a7")
+			policy := readPolicy("policies:\n  - name: release\n    images: ['*/hyperledger/*']\n    keys: [release.pub]\n")
+
+			err := util.VerifyImageSignature(ctx, logger, policy, imageData, "basic")
+
+			var verificationErr *util.SignatureVerificationError
+			Expect(err).To(BeAssignableToTypeOf(verificationErr))
+		})
+
+		It("should verify a signature for a tagged docker reference in the chaincode image repository", func() {
+			signImage(trustedKey, imageName+":v1.0.0", imageData.Digest)
+			policy := readPolicy("policies:\n  - name: release\n    images: ['*/hyperledger/*']\n    keys: [release.pub]\n")
+
+			err := util.VerifyImageSignature(ctx, logger, policy, imageData, "basic")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return a signature verification error if the signature is for a different repository", func() {
+			signedRepository := strings.TrimSuffix(imageName, "/asset-transfer-basic") + "/untrusted-chaincode"
+			signImage(trustedKey, signedRepository, imageData.Digest)
+			policy := readPolicy("policies:\n  - name: release\n    images: ['*/hyperledger/*']\n    keys: [release.pub]\n")
+
+			err := util.VerifyImageSignature(ctx, logger, policy, imageData, "basic")
+
+			var verificationErr *util.SignatureVerificationError
+			Expect(err).To(BeAssignableToTypeOf(verificationErr))
+			Expect(err).To(MatchError(ContainSubstring("none of the 1 signatures were signed by a trusted key for repository " + imageName)))
+		})
+	})
+})
@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+var errJobTemplateOverride = errors.New("job template must not override fields managed by the k8s builder")
+
+// ReadJobTemplate reads a partial Job or PodTemplateSpec from the provided YAML
+// or JSON file, and returns it as a partial Job in JSON format suitable for
+// use as a strategic merge patch.
+func ReadJobTemplate(logger *log.CmdLogger, jobTemplatePath string) ([]byte, error) {
+	logger.Debugf("Reading %s...", jobTemplatePath)
+
+	jobTemplateContents, err := os.ReadFile(jobTemplatePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", jobTemplatePath, err)
+	}
+
+	jobTemplateJSON, err := yaml.YAMLToJSON(jobTemplateContents)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", jobTemplatePath, err)
+	}
+
+	var jobTemplate map[string]interface{}
+	if err := json.Unmarshal(jobTemplateJSON, &jobTemplate); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", jobTemplatePath, err)
+	}
+
+	jobTemplate, err = getPartialJob(jobTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job template %s: %w", jobTemplatePath, err)
+	}
+
+	partialJobJSON, err := json.Marshal(jobTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert job template %s: %w", jobTemplatePath, err)
+	}
+
+	// Make sure the template only contains valid Job fields
+	decoder := json.NewDecoder(bytes.NewReader(partialJobJSON))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&batchv1.Job{}); err != nil {
+		return nil, fmt.Errorf("invalid job template %s: %w", jobTemplatePath, err)
+	}
+
+	logger.Debugf("Job template: %s", partialJobJSON)
+
+	return partialJobJSON, nil
+}
+
+// getPartialJob returns a partial Job for the provided template, which may be a
+// Job, a PodTemplate, or a PodTemplateSpec.
+func getPartialJob(jobTemplate map[string]interface{}) (map[string]interface{}, error) {
+	kind, _ := jobTemplate["kind"].(string)
+
+	delete(jobTemplate, "apiVersion")
+	delete(jobTemplate, "kind")
+
+	switch kind {
+	case "Job":
+		return jobTemplate, nil
+
+	case "PodTemplate":
+		return map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": jobTemplate["template"],
+			},
+		}, nil
+
+	case "":
+		if spec, ok := jobTemplate["spec"].(map[string]interface{}); ok {
+			if _, ok := spec["template"]; ok {
+				return jobTemplate, nil
+			}
+		}
+
+		return map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": jobTemplate,
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported kind '%s', must be 'Job' or 'PodTemplate'", kind)
+	}
+}
+
+// applyChaincodeJobTemplate strategic merge patches the provided job template
+// onto the generated chaincode job definition.
+func applyChaincodeJobTemplate(jobDefinition *batchv1.Job, jobTemplate []byte) (*batchv1.Job, error) {
+	jobDefinitionJSON, err := json.Marshal(jobDefinition)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert job definition: %w", err)
+	}
+
+	patchedJobJSON, err := strategicpatch.StrategicMergePatch(jobDefinitionJSON, jobTemplate, batchv1.Job{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply job template: %w", err)
+	}
+
+	var patchedJob batchv1.Job
+	if err := json.Unmarshal(patchedJobJSON, &patchedJob); err != nil {
+		return nil, fmt.Errorf("unable to convert patched job definition: %w", err)
+	}
+
+	if err := validateChaincodeJobTemplate(jobDefinition, &patchedJob); err != nil {
+		return nil, err
+	}
+
+	return &patchedJob, nil
+}
+
+// applyChaincodePodTemplate strategic merge patches the pod template from the
+// provided job template onto a generated chaincode pod template, for chaincode
+// which is not run as a job. Job fields outside the pod template are ignored.
+func applyChaincodePodTemplate(podTemplate apiv1.PodTemplateSpec, jobTemplate []byte) (apiv1.PodTemplateSpec, error) {
+	patchedJob, err := applyChaincodeJobTemplate(&batchv1.Job{Spec: batchv1.JobSpec{Template: podTemplate}}, jobTemplate)
+	if err != nil {
+		return apiv1.PodTemplateSpec{}, err
+	}
+
+	return patchedJob.Spec.Template, nil
+}
+
+// validateChaincodeJobTemplate checks that a job template has not changed any
+// of the fields which the k8s builder relies on.
+func validateChaincodeJobTemplate(jobDefinition, patchedJob *batchv1.Job) error {
+	if patchedJob.Name != jobDefinition.Name || patchedJob.Namespace != jobDefinition.Namespace {
+		return fmt.Errorf("%w: job name and namespace", errJobTemplateOverride)
+	}
+
+	if !containsAll(patchedJob.Labels, jobDefinition.Labels) || !containsAll(patchedJob.Spec.Template.Labels, jobDefinition.Spec.Template.Labels) {
+		return fmt.Errorf("%w: labels", errJobTemplateOverride)
+	}
+
+	if !containsAll(patchedJob.Annotations, jobDefinition.Annotations) || !containsAll(patchedJob.Spec.Template.Annotations, jobDefinition.Spec.Template.Annotations) {
+		return fmt.Errorf("%w: annotations", errJobTemplateOverride)
+	}
+
+	container := findContainer(jobDefinition.Spec.Template.Spec.Containers, chaincodeContainerName)
+	patchedContainer := findContainer(patchedJob.Spec.Template.Spec.Containers, chaincodeContainerName)
+
+	if patchedContainer == nil {
+		return fmt.Errorf("%w: %s container", errJobTemplateOverride, chaincodeContainerName)
+	}
+
+	if patchedContainer.Image != container.Image {
+		return fmt.Errorf("%w: %s container image", errJobTemplateOverride, chaincodeContainerName)
+	}
+
+	for _, env := range container.Env {
+		patchedEnv := findEnvVar(patchedContainer.Env, env.Name)
+		if patchedEnv == nil || !equality.Semantic.DeepEqual(*patchedEnv, env) {
+			return fmt.Errorf("%w: %s environment variable", errJobTemplateOverride, env.Name)
+		}
+	}
+
+	for _, volumeMount := range container.VolumeMounts {
+		patchedVolumeMount := findVolumeMount(patchedContainer.VolumeMounts, volumeMount.Name)
+		if patchedVolumeMount == nil || !equality.Semantic.DeepEqual(*patchedVolumeMount, volumeMount) {
+			return fmt.Errorf("%w: %s volume mount", errJobTemplateOverride, volumeMount.Name)
+		}
+	}
+
+	for _, volume := range jobDefinition.Spec.Template.Spec.Volumes {
+		patchedVolume := findVolume(patchedJob.Spec.Template.Spec.Volumes, volume.Name)
+		if patchedVolume == nil || !equality.Semantic.DeepEqual(*patchedVolume, volume) {
+			return fmt.Errorf("%w: %s volume", errJobTemplateOverride, volume.Name)
+		}
+	}
+
+	return nil
+}
+
+func containsAll(actual, expected map[string]string) bool {
+	for k, v := range expected {
+		if actualValue, ok := actual[k]; !ok || actualValue != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func findContainer(containers []apiv1.Container, name string) *apiv1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+
+	return nil
+}
+
+func findEnvVar(envVars []apiv1.EnvVar, name string) *apiv1.EnvVar {
+	for i := range envVars {
+		if envVars[i].Name == name {
+			return &envVars[i]
+		}
+	}
+
+	return nil
+}
+
+func findVolumeMount(volumeMounts []apiv1.VolumeMount, name string) *apiv1.VolumeMount {
+	for i := range volumeMounts {
+		if volumeMounts[i].Name == name {
+			return &volumeMounts[i]
+		}
+	}
+
+	return nil
+}
+
+func findVolume(volumes []apiv1.Volume, name string) *apiv1.Volume {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return &volumes[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package util_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Template", func() {
+	var (
+		ctx           context.Context
+		logger        *log.CmdLogger
+		tempDir       string
+		chaincodeData *util.ChaincodeJSON
+		imageData     *util.ImageJSON
+	)
+
+	writeTemplate := func(contents string) string {
+		templatePath := filepath.Join(tempDir, "template.yaml")
+		Expect(os.WriteFile(templatePath, []byte(contents), 0o600)).To(Succeed())
+
+		return templatePath
+	}
+
+	createJob := func(templateContents string) (*apiv1.PodSpec, error) {
+		jobTemplate, err := util.ReadJobTemplate(logger, writeTemplate(templateContents))
+		Expect(err).NotTo(HaveOccurred())
+
+		jobsClient := fake.NewClientset().BatchV1().Jobs("chaincode")
+
+		job, err := util.CreateChaincodeJob(ctx, logger, jobsClient, "hlfcc-fabcar-s6pwkq6bepi2e", "chaincode", "default", "", "CongaOrgPeer0", chaincodeData, imageData, apiv1.ResourceRequirements{}, util.PodOptions{}, util.RetryPolicy{}, jobTemplate)
+		if err != nil {
+			return nil, err
+		}
+
+		return &job.Spec.Template.Spec, nil
+	}
+
+	BeforeEach(func() {
+		ctx = log.NewCmdContext(context.Background(), false)
+		logger = log.New(ctx)
+		tempDir = GinkgoT().TempDir()
+		chaincodeData = &util.ChaincodeJSON{
+			ChaincodeID: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b",
+			PeerAddress: "peer0.org1.example.com",
+			ClientCert:  "CLIENT_CERT",
+			ClientKey:   "CLIENT_KEY",
+			RootCert:    "ROOT_CERT",
+			MspID:       "CongaOrg",
+		}
+		imageData = &util.ImageJSON{
+			Name:   "nginx",
+			Digest: "sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf",
+		}
+	})
+
+	Describe("ReadJobTemplate", func() {
+		It("should return an error if the template file does not exist", func() {
+			_, err := util.ReadJobTemplate(logger, filepath.Join(tempDir, "missing.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("unable to read")))
+		})
+
+		It("should return an error for unsupported kinds", func() {
+			_, err := util.ReadJobTemplate(logger, writeTemplate("kind: Deployment\n"))
+			Expect(err).To(MatchError(ContainSubstring("unsupported kind 'Deployment', must be 'Job' or 'PodTemplate'")))
+		})
+
+		It("should return an error for unknown fields", func() {
+			_, err := util.ReadJobTemplate(logger, writeTemplate("spec:\n  tolerashuns: []\n"))
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "tolerashuns"`)))
+		})
+
+		It("should wrap a pod template spec in a partial job", func() {
+			jobTemplate, err := util.ReadJobTemplate(logger, writeTemplate("spec:\n  priorityClassName: chaincode\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobTemplate).To(MatchJSON(`{"spec":{"template":{"spec":{"priorityClassName":"chaincode"}}}}`))
+		})
+
+		It("should wrap a pod template in a partial job", func() {
+			jobTemplate, err := util.ReadJobTemplate(logger, writeTemplate("apiVersion: v1\nkind: PodTemplate\ntemplate:\n  spec:\n    priorityClassName: chaincode\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobTemplate).To(MatchJSON(`{"spec":{"template":{"spec":{"priorityClassName":"chaincode"}}}}`))
+		})
+
+		It("should return a partial job unchanged", func() {
+			jobTemplate, err := util.ReadJobTemplate(logger, writeTemplate(`{"apiVersion":"batch/v1","kind":"Job","spec":{"activeDeadlineSeconds":60,"template":{"spec":{"priorityClassName":"chaincode"}}}}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobTemplate).To(MatchJSON(`{"spec":{"activeDeadlineSeconds":60,"template":{"spec":{"priorityClassName":"chaincode"}}}}`))
+		})
+	})
+
+	Describe("CreateChaincodeJob with a job template", func() {
+		It("should merge the template with the generated job", func() {
+			podSpec, err := createJob(`
+metadata:
+  labels:
+    team: conga
+spec:
+  tolerations:
+    - key: dedicated
+      operator: Exists
+  containers:
+    - name: chaincode
+      securityContext:
+        runAsNonRoot: true
+      env:
+        - name: EXTRA
+          value: extra
+    - name: sidecar
+      image: busybox
+`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podSpec.Tolerations).To(HaveLen(1))
+			Expect(podSpec.Containers).To(HaveLen(2))
+			Expect(podSpec.Containers[0].Image).To(Equal("nginx@sha256:da3cc3053314be9ca3871307366f6e30ce2b11e1ea6a72e5957244d99b2515bf"))
+			Expect(*podSpec.Containers[0].SecurityContext.RunAsNonRoot).To(BeTrue())
+			Expect(podSpec.Containers[0].Env).To(ContainElement(apiv1.EnvVar{Name: "EXTRA", Value: "extra"}))
+			Expect(podSpec.Containers[0].Env).To(ContainElement(apiv1.EnvVar{Name: "CORE_PEER_ADDRESS", Value: "peer0.org1.example.com"}))
+			Expect(podSpec.Volumes).To(HaveLen(2))
+		})
+
+		DescribeTable("should reject templates which override builder fields",
+			func(templateContents, expectedError string) {
+				_, err := createJob(templateContents)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("When the template changes the chaincode image", `
+spec:
+  containers:
+    - name: chaincode
+      image: busybox
+`, "job template must not override fields managed by the k8s builder: chaincode container image"),
+			Entry("When the template changes a chaincode environment variable", `
+spec:
+  containers:
+    - name: chaincode
+      env:
+        - name: CORE_PEER_ADDRESS
+          value: peer1.org1.example.com
+`, "job template must not override fields managed by the k8s builder: CORE_PEER_ADDRESS environment variable"),
+			Entry("When the template changes the certs volume", `
+spec:
+  volumes:
+    - name: certs
+      secret:
+        secretName: other
+`, "job template must not override fields managed by the k8s builder: certs volume"),
+			Entry("When the template changes a builder label", `
+metadata:
+  labels:
+    fabric-builder-k8s-cclabel: other
+`, "job template must not override fields managed by the k8s builder: labels"),
+			Entry("When the template changes the job name", `
+kind: Job
+metadata:
+  name: other
+spec:
+  template: {}
+`, "job template must not override fields managed by the k8s builder: job name and namespace"),
+		)
+	})
+})
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+)
+
+// certificateExpiryWarningPeriod is how long before the chaincode client
+// certificate, or a root certificate, expires that the k8s builder starts
+// warning about it.
+const certificateExpiryWarningPeriod = 30 * 24 * time.Hour
+
+var errNoPEMCertificates = errors.New("must contain at least one PEM encoded certificate")
+
+// validateChaincodeCertificates checks that the peer TLS certificates in the
+// chaincode.json file can be used by the chaincode to connect to the peer, so
+// that problems are reported before any Kubernetes objects are created, rather
+// than as a TLS handshake failure in the chaincode pod.
+func validateChaincodeCertificates(logger *log.CmdLogger, chaincodeData *ChaincodeJSON, now time.Time) error {
+	clientCerts, err := parseCertificates(chaincodeData.ClientCert)
+	if err != nil {
+		return fmt.Errorf("contains an invalid 'client_cert': %w", err)
+	}
+
+	rootCerts, err := parseCertificates(chaincodeData.RootCert)
+	if err != nil {
+		return fmt.Errorf("contains an invalid 'root_cert': %w", err)
+	}
+
+	if block, _ := pem.Decode([]byte(chaincodeData.ClientKey)); block == nil {
+		return errors.New("contains an invalid 'client_key': must be a PEM encoded private key")
+	}
+
+	if _, err := tls.X509KeyPair([]byte(chaincodeData.ClientCert), []byte(chaincodeData.ClientKey)); err != nil {
+		return fmt.Errorf("contains a 'client_key' which does not match the 'client_cert': %w", err)
+	}
+
+	clientCert := clientCerts[0]
+	if err := checkCertificateValidity(logger, "client_cert", clientCert, now); err != nil {
+		return err
+	}
+
+	roots := x509.NewCertPool()
+
+	for _, rootCert := range rootCerts {
+		if err := checkCertificateValidity(logger, "root_cert", rootCert, now); err != nil {
+			return err
+		}
+
+		roots.AddCert(rootCert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, intermediateCert := range clientCerts[1:] {
+		intermediates.AddCert(intermediateCert)
+	}
+
+	_, err = clientCert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("contains a 'client_cert' which is not signed by the 'root_cert': %w", err)
+	}
+
+	return nil
+}
+
+// checkCertificateValidity returns an error if the certificate is not valid
+// yet, or has expired, and logs a warning if it expires soon.
+func checkCertificateValidity(logger *log.CmdLogger, field string, cert *x509.Certificate, now time.Time) error {
+	switch {
+	case now.Before(cert.NotBefore):
+		return fmt.Errorf("contains a '%s' which is not valid until %s", field, cert.NotBefore.UTC().Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		return fmt.Errorf("contains a '%s' which expired at %s", field, cert.NotAfter.UTC().Format(time.RFC3339))
+	case cert.NotAfter.Sub(now) < certificateExpiryWarningPeriod:
+		logger.Warnf("The chaincode.json '%s' certificate %s expires at %s", field, cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// parseCertificates parses all the PEM encoded certificates in the provided
+// string.
+func parseCertificates(pemCerts string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	rest := []byte(pemCerts)
+
+	for {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block type %s, must be CERTIFICATE", block.Type)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errNoPEMCertificates
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,192 @@
+package util_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+func newTestCertificate(commonName string, notBefore, notAfter time.Time, issuer *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	parent, signer := template, key
+	if issuer == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		parent, signer = issuer.cert, issuer.key
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	Expect(err).NotTo(HaveOccurred())
+
+	cert, err := x509.ParseCertificate(certDER)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+var _ = Describe("TLS", func() {
+	var (
+		logger     *log.CmdLogger
+		metadata   string
+		now        time.Time
+		rootCA     *testCertificate
+		clientCert *testCertificate
+	)
+
+	writeChaincodeJSON := func(clientCertPEM, clientKeyPEM, rootCertPEM string) {
+		contents, err := json.Marshal(&util.ChaincodeJSON{
+			ChaincodeID: "fabcar:cffa266294278404e5071cb91150d550dc0bf855149908a170b1169d6160004b",
+			PeerAddress: "peer0.org1.example.com",
+			ClientCert:  clientCertPEM,
+			ClientKey:   clientKeyPEM,
+			RootCert:    rootCertPEM,
+			MspID:       "CongaOrg",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(metadata, util.ChaincodeFile), contents, 0o600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx := log.NewCmdContext(context.Background(), false)
+		logger = log.New(ctx)
+		metadata = GinkgoT().TempDir()
+		now = time.Now()
+		rootCA = newTestCertificate("tlsca", now.Add(-time.Hour), now.AddDate(10, 0, 0), nil)
+		clientCert = newTestCertificate("fabcar", now.Add(-time.Hour), now.AddDate(1, 0, 0), rootCA)
+	})
+
+	It("should read chaincode.json with valid TLS certificates", func() {
+		writeChaincodeJSON(clientCert.certPEM, clientCert.keyPEM, rootCA.certPEM)
+
+		chaincodeData, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chaincodeData.TLSEnabled()).To(BeTrue())
+	})
+
+	It("should read chaincode.json without TLS certificates for a plain text peer connection", func() {
+		writeChaincodeJSON("", "", "")
+
+		chaincodeData, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chaincodeData.TLSEnabled()).To(BeFalse())
+	})
+
+	It("should read chaincode.json with a client certificate which expires soon", func() {
+		expiringCert := newTestCertificate("fabcar", now.Add(-time.Hour), now.Add(24*time.Hour), rootCA)
+		writeChaincodeJSON(expiringCert.certPEM, expiringCert.keyPEM, rootCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should return an error if chaincode.json contains some, but not all, TLS certificates", func() {
+		writeChaincodeJSON(clientCert.certPEM, "", rootCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("must contain all of 'client_cert', 'client_key', and 'root_cert' for a TLS peer connection, or none of them for a plain text peer connection: missing 'client_key'")))
+	})
+
+	It("should return an error if the client certificate is not PEM encoded", func() {
+		writeChaincodeJSON("CLIENT_CERT", clientCert.keyPEM, rootCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains an invalid 'client_cert': must contain at least one PEM encoded certificate")))
+	})
+
+	It("should return an error if the root certificate is not a certificate", func() {
+		writeChaincodeJSON(clientCert.certPEM, clientCert.keyPEM, rootCA.keyPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains an invalid 'root_cert': unexpected PEM block type PRIVATE KEY, must be CERTIFICATE")))
+	})
+
+	It("should return an error if the client key is not PEM encoded", func() {
+		writeChaincodeJSON(clientCert.certPEM, "CLIENT_KEY", rootCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains an invalid 'client_key': must be a PEM encoded private key")))
+	})
+
+	It("should return an error if the client key does not match the client certificate", func() {
+		otherCert := newTestCertificate("other", now.Add(-time.Hour), now.AddDate(1, 0, 0), rootCA)
+		writeChaincodeJSON(clientCert.certPEM, otherCert.keyPEM, rootCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains a 'client_key' which does not match the 'client_cert'")))
+	})
+
+	It("should return an error if the client certificate has expired", func() {
+		expiredCert := newTestCertificate("fabcar", now.AddDate(-1, 0, 0), now.Add(-time.Hour), rootCA)
+		writeChaincodeJSON(expiredCert.certPEM, expiredCert.keyPEM, rootCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains a 'client_cert' which expired at")))
+	})
+
+	It("should return an error if the client certificate is not valid yet", func() {
+		futureCert := newTestCertificate("fabcar", now.Add(time.Hour), now.AddDate(1, 0, 0), rootCA)
+		writeChaincodeJSON(futureCert.certPEM, futureCert.keyPEM, rootCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains a 'client_cert' which is not valid until")))
+	})
+
+	It("should return an error if the root certificate has expired", func() {
+		expiredCA := newTestCertificate("tlsca", now.AddDate(-1, 0, 0), now.Add(-time.Hour), nil)
+		writeChaincodeJSON(clientCert.certPEM, clientCert.keyPEM, expiredCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains a 'root_cert' which expired at")))
+	})
+
+	It("should return an error if the client certificate is not signed by the root certificate", func() {
+		otherCA := newTestCertificate("othertlsca", now.Add(-time.Hour), now.AddDate(10, 0, 0), nil)
+		writeChaincodeJSON(clientCert.certPEM, clientCert.keyPEM, otherCA.certPEM)
+
+		_, err := util.ReadChaincodeJSON(logger, metadata)
+		Expect(err).To(MatchError(ContainSubstring("contains a 'client_cert' which is not signed by the 'root_cert'")))
+	})
+})
@@ -0,0 +1,351 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/log"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/metrics"
+	"github.com/hyperledger-labs/fabric-builder-k8s/internal/tracing"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedBatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+)
+
+const (
+	// DefaultVaultKVMount is the default mount path of the Vault KV version 2
+	// secrets engine used to store chaincode credentials.
+	DefaultVaultKVMount = "secret"
+
+	vaultTimeout         = 10 * time.Second
+	vaultTokenHeader     = "X-Vault-Token"
+	vaultListMethod      = "LIST"
+	vaultPathPrefix      = fabricBuilderK8s
+	maximumVaultResponse = 1 << 16
+
+	// Vault Agent Injector annotations.
+	vaultAgentInjectAnnotation           = "vault.hashicorp.com/agent-inject"
+	vaultAgentPrePopulateOnlyAnnotation  = "vault.hashicorp.com/agent-pre-populate-only"
+	vaultRoleAnnotation                  = "vault.hashicorp.com/role"
+	vaultSecretVolumePathAnnotation      = "vault.hashicorp.com/secret-volume-path"
+	vaultAgentInjectSecretAnnotation     = "vault.hashicorp.com/agent-inject-secret-"
+	vaultAgentInjectTemplateAnnotation   = "vault.hashicorp.com/agent-inject-template-"
+	vaultAgentInjectTemplateFormatString = `{{- with secret "%s" -}}{{ index .Data.data "%s" }}{{- end -}}`
+)
+
+// VaultConfig configures a Vault KV version 2 secrets engine to store
+// chaincode credentials.
+type VaultConfig struct {
+	// Address is the Vault server URL, e.g. https://vault:8200.
+	Address string
+
+	// TokenFile is the path to a file containing the Vault token the k8s
+	// builder uses to write and delete chaincode credentials.
+	TokenFile string
+
+	// KVMount is the mount path of the KV version 2 secrets engine.
+	KVMount string
+
+	// Role is the Vault Kubernetes auth role the Vault Agent Injector uses
+	// to read chaincode credentials in chaincode pods.
+	Role string
+}
+
+// VaultCredentialStore stores chaincode credentials in a Vault KV version 2
+// secrets engine. Chaincode pods are annotated so that the Vault Agent
+// Injector adds an init container which writes the credentials to a shared
+// memory volume in the chaincode pod, so there is no Kubernetes secret
+// containing the chaincode client private key.
+type VaultCredentialStore struct {
+	config    VaultConfig
+	namespace string
+	client    *http.Client
+}
+
+// vaultKVRequest is the body of a Vault KV version 2 write request.
+type vaultKVRequest struct {
+	Data map[string]string `json:"data"`
+}
+
+// vaultListResponse is the body of a Vault KV version 2 list response.
+type vaultListResponse struct {
+	Data struct {
+		Keys []string `json:"keys"`
+	} `json:"data"`
+}
+
+// vaultMetadataResponse is the body of a Vault KV version 2 read metadata
+// response.
+type vaultMetadataResponse struct {
+	Data struct {
+		UpdatedTime time.Time `json:"updated_time"`
+	} `json:"data"`
+}
+
+// vaultErrorResponse is the body of a Vault error response.
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// NewVaultCredentialStore returns a credential store which stores chaincode
+// credentials for the provided namespace in Vault.
+func NewVaultCredentialStore(config VaultConfig, namespace string) *VaultCredentialStore {
+	if config.KVMount == "" {
+		config.KVMount = DefaultVaultKVMount
+	}
+
+	return &VaultCredentialStore{
+		config:    config,
+		namespace: namespace,
+		client:    &http.Client{Timeout: vaultTimeout},
+	}
+}
+
+// Apply writes the chaincode credentials to Vault.
+func (s *VaultCredentialStore) Apply(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	name, _ string,
+	chaincodeData *ChaincodeJSON,
+) error {
+	body, err := json.Marshal(&vaultKVRequest{Data: getChaincodeCredentials(chaincodeData)})
+	if err != nil {
+		return fmt.Errorf("error encoding chaincode credentials for chaincode ID %s: %w", chaincodeData.ChaincodeID, err)
+	}
+
+	startTime := time.Now()
+
+	spanCtx, span := tracing.Start(ctx, "vault.kv.put", tracing.NamespaceKey.String(s.namespace), tracing.ObjectNameKey.String(name))
+	err = s.request(spanCtx, http.MethodPost, s.dataPath(name), body, nil)
+	tracing.End(span, err)
+
+	if err != nil {
+		metrics.FromContext(ctx).SecretApplied(metrics.ResultError, time.Since(startTime))
+
+		return fmt.Errorf("error writing chaincode credentials for chaincode ID %s to %s: %w", chaincodeData.ChaincodeID, s.Location(name), err)
+	}
+
+	metrics.FromContext(ctx).SecretApplied(metrics.ResultSuccess, time.Since(startTime))
+
+	logger.Debugf("Wrote credentials for chaincode ID %s: %s", chaincodeData.ChaincodeID, s.Location(name))
+
+	return nil
+}
+
+// SetOwner does nothing, since Vault secrets cannot be owned by Kubernetes
+// objects.
+func (s *VaultCredentialStore) SetOwner(_ context.Context, _ *log.CmdLogger, _ string, _ *batchv1.Job) error {
+	return nil
+}
+
+// RemoveOwners does nothing, since Vault secrets cannot be owned by Kubernetes
+// objects.
+func (s *VaultCredentialStore) RemoveOwners(_ context.Context, _ *log.CmdLogger, _ string) error {
+	return nil
+}
+
+// Delete deletes all versions of the chaincode credentials from Vault.
+func (s *VaultCredentialStore) Delete(ctx context.Context, logger *log.CmdLogger, name string) error {
+	logger.Debugf("Deleting chaincode credentials %s", s.Location(name))
+
+	spanCtx, span := tracing.Start(ctx, "vault.kv.delete", tracing.NamespaceKey.String(s.namespace), tracing.ObjectNameKey.String(name))
+	err := s.request(spanCtx, http.MethodDelete, s.metadataPath(name), nil, nil)
+	tracing.End(span, err)
+
+	if err != nil {
+		return fmt.Errorf("error deleting chaincode credentials %s: %w", s.Location(name), err)
+	}
+
+	return nil
+}
+
+// DeleteOrphaned deletes chaincode credentials for the namespace from Vault
+// which are not used by any chaincode jobs that are still running. Vault
+// secrets are not deleted by Kubernetes garbage collection, so this removes
+// credentials left behind if the k8s builder was unable to delete them, e.g.
+// because the peer was stopped while chaincode was running.
+func (s *VaultCredentialStore) DeleteOrphaned(
+	ctx context.Context,
+	logger *log.CmdLogger,
+	jobsClient typedBatchv1.JobInterface,
+) error {
+	var list vaultListResponse
+
+	spanCtx, span := tracing.Start(ctx, "vault.kv.list", tracing.NamespaceKey.String(s.namespace))
+	err := s.request(spanCtx, vaultListMethod, s.metadataPath(""), nil, &list)
+	tracing.End(span, err)
+
+	if err != nil {
+		return fmt.Errorf("error listing chaincode credentials in vault:%s: %w", s.metadataPath(""), err)
+	}
+
+	if len(list.Data.Keys) == 0 {
+		return nil
+	}
+
+	selector, err := getChaincodeObjectSelector()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := jobsClient.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return fmt.Errorf("error listing chaincode jobs in namespace %s: %w", s.namespace, err)
+	}
+
+	inUse := make(map[string]bool)
+
+	for i := range jobs.Items {
+		if !isJobFinished(&jobs.Items[i]) {
+			inUse[jobs.Items[i].Spec.Template.Annotations[vaultAgentInjectSecretAnnotation+path.Base(TLSClientKeyFile)]] = true
+		}
+	}
+
+	var errs []error
+
+	for _, name := range list.Data.Keys {
+		if strings.HasSuffix(name, "/") || inUse[s.dataPath(name)] {
+			continue
+		}
+
+		var metadata vaultMetadataResponse
+		if err := s.request(ctx, http.MethodGet, s.metadataPath(name), nil, &metadata); err != nil {
+			errs = append(errs, fmt.Errorf("error reading chaincode credentials %s: %w", s.Location(name), err))
+
+			continue
+		}
+
+		if time.Since(metadata.Data.UpdatedTime) < orphanedSecretMinimumAge {
+			logger.Debugf("Skipping recently updated chaincode credentials %s", s.Location(name))
+
+			continue
+		}
+
+		logger.Debugf("Deleting orphaned chaincode credentials %s", s.Location(name))
+
+		if err := s.Delete(ctx, logger, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ConfigurePod adds Vault Agent Injector annotations to the chaincode pod
+// template, so that the credentials are written to the certificate directory
+// in the chaincode container before it starts. Only an init container is
+// injected, since a Vault Agent sidecar would stop chaincode jobs completing.
+func (s *VaultCredentialStore) ConfigurePod(podTemplate *apiv1.PodTemplateSpec, name string) {
+	// The pod template annotations are shared with the job
+	podTemplate.Annotations = maps.Clone(podTemplate.Annotations)
+	if podTemplate.Annotations == nil {
+		podTemplate.Annotations = make(map[string]string)
+	}
+
+	podTemplate.Annotations[vaultAgentInjectAnnotation] = "true"
+	podTemplate.Annotations[vaultAgentPrePopulateOnlyAnnotation] = "true"
+	podTemplate.Annotations[vaultRoleAnnotation] = s.config.Role
+	podTemplate.Annotations[vaultSecretVolumePathAnnotation] = certsMountPath
+
+	dataPath := s.dataPath(name)
+
+	for _, file := range getChaincodeCredentialFiles() {
+		podTemplate.Annotations[vaultAgentInjectSecretAnnotation+file] = dataPath
+		podTemplate.Annotations[vaultAgentInjectTemplateAnnotation+file] = fmt.Sprintf(vaultAgentInjectTemplateFormatString, dataPath, file)
+	}
+}
+
+// Location returns the Vault path of the chaincode credentials.
+func (s *VaultCredentialStore) Location(name string) string {
+	return "vault:" + s.dataPath(name)
+}
+
+// secretPath returns the path of the chaincode credentials in the KV secrets
+// engine, which includes the namespace in case the same Vault server is used
+// by peers in different namespaces.
+func (s *VaultCredentialStore) secretPath(name string) string {
+	return path.Join(vaultPathPrefix, s.namespace, name)
+}
+
+// metadataPath returns the Vault API path used to list and delete chaincode
+// credentials.
+func (s *VaultCredentialStore) metadataPath(name string) string {
+	return path.Join(s.config.KVMount, "metadata", s.secretPath(name))
+}
+
+// dataPath returns the Vault API path used to read and write the chaincode
+// credentials.
+func (s *VaultCredentialStore) dataPath(name string) string {
+	return path.Join(s.config.KVMount, "data", s.secretPath(name))
+}
+
+// request sends a request to the Vault HTTP API, and decodes the response into
+// the result, if there is one. Not found errors are ignored when listing or
+// deleting secrets.
+func (s *VaultCredentialStore) request(ctx context.Context, method, apiPath string, body []byte, result any) error {
+	token, err := os.ReadFile(s.config.TokenFile)
+	if err != nil {
+		return fmt.Errorf("unable to read vault token from %s: %w", s.config.TokenFile, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, vaultTimeout)
+	defer cancel()
+
+	requestURL := strings.TrimSuffix(s.config.Address, "/") + "/v1/" + apiPath
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating vault request: %w", err)
+	}
+
+	request.Header.Set(vaultTokenHeader, strings.TrimSpace(string(token)))
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error sending vault request: %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, maximumVaultResponse))
+
+	if response.StatusCode == http.StatusNotFound && (method == http.MethodDelete || method == vaultListMethod) {
+		return nil
+	}
+
+	if response.StatusCode < http.StatusMultipleChoices {
+		if result == nil || len(responseBody) == 0 {
+			return nil
+		}
+
+		if err := json.Unmarshal(responseBody, result); err != nil {
+			return fmt.Errorf("error decoding vault response: %w", err)
+		}
+
+		return nil
+	}
+
+	var vaultErr vaultErrorResponse
+
+	if err := json.Unmarshal(responseBody, &vaultErr); err != nil || len(vaultErr.Errors) == 0 {
+		return fmt.Errorf("vault returned %s", response.Status)
+	}
+
+	return fmt.Errorf("vault returned %s: %s", response.Status, strings.Join(vaultErr.Errors, ", "))
+}
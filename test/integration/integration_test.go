@@ -28,6 +28,28 @@ func TestRunChaincode(t *testing.T) {
 		}).Feature())
 }
 
+func TestRunChaincodeWithPlainTextPeerConnection(t *testing.T) {
+	testenv.Test(t, features.NewWithDescription(t.Name()+"Feature", "the builder should run chaincode without TLS certificates for a plain text peer connection").
+		Assess(t.Name()+"Assessment", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			t.Helper()
+
+			testscript.Run(t, test.NewTestscriptParams(t, "testdata/plain_text_chaincode.txtar", testenv))
+
+			return ctx
+		}).Feature())
+}
+
+func TestRunChaincodeWithRestrictedSecurityContext(t *testing.T) {
+	testenv.Test(t, features.NewWithDescription(t.Name()+"Feature", "the builder should run chaincode with a restricted security context by default").
+		Assess(t.Name()+"Assessment", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+			t.Helper()
+
+			testscript.Run(t, test.NewTestscriptParams(t, "testdata/restricted_security_context.txtar", testenv))
+
+			return ctx
+		}).Feature())
+}
+
 func TestRunChaincodeWithNamePrefix(t *testing.T) {
 	testenv.Test(t, features.NewWithDescription(t.Name()+"Feature", "the builder should run chaincode using kubernetes object names with the specified prefix").
 		Assess(t.Name()+"Assessment", func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
@@ -15,6 +15,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/klient/wait"
 	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
@@ -146,6 +147,51 @@ func jobInfoCmd(script *testscript.TestScript, _ bool, args []string) {
 		_, err = script.Stdout().Write(fmt.Appendf(nil, "Job annotation: %s=%s\n", k, v))
 		script.Check(err)
 	}
+
+	for _, container := range job.Spec.Template.Spec.Containers {
+		writeContainerEnv(script, container)
+		writeContainerSecurityContext(script, container)
+	}
+}
+
+func writeContainerEnv(script *testscript.TestScript, container v1.Container) {
+	for _, env := range container.Env {
+		_, err := script.Stdout().Write(fmt.Appendf(nil, "Job container %s env: %s=%s\n", container.Name, env.Name, env.Value))
+		script.Check(err)
+	}
+}
+
+func writeContainerSecurityContext(script *testscript.TestScript, container v1.Container) {
+	securityContext := container.SecurityContext
+	if securityContext == nil {
+		return
+	}
+
+	var err error
+
+	_, err = script.Stdout().Write(fmt.Appendf(nil, "Job container %s runAsNonRoot: %t\n", container.Name, ptr.Deref(securityContext.RunAsNonRoot, false)))
+	script.Check(err)
+
+	_, err = script.Stdout().Write(fmt.Appendf(nil, "Job container %s allowPrivilegeEscalation: %t\n", container.Name, ptr.Deref(securityContext.AllowPrivilegeEscalation, true)))
+	script.Check(err)
+
+	_, err = script.Stdout().Write(fmt.Appendf(nil, "Job container %s readOnlyRootFilesystem: %t\n", container.Name, ptr.Deref(securityContext.ReadOnlyRootFilesystem, false)))
+	script.Check(err)
+
+	if securityContext.Capabilities != nil {
+		_, err = script.Stdout().Write(fmt.Appendf(nil, "Job container %s capabilities drop: %v\n", container.Name, securityContext.Capabilities.Drop))
+		script.Check(err)
+	}
+
+	if securityContext.SeccompProfile != nil {
+		_, err = script.Stdout().Write(fmt.Appendf(nil, "Job container %s seccomp profile: %s\n", container.Name, securityContext.SeccompProfile.Type))
+		script.Check(err)
+	}
+
+	for _, mount := range container.VolumeMounts {
+		_, err = script.Stdout().Write(fmt.Appendf(nil, "Job container %s volume mount: %s=%s\n", container.Name, mount.Name, mount.MountPath))
+		script.Check(err)
+	}
 }
 
 func podInfoCmd(script *testscript.TestScript, _ bool, args []string) {